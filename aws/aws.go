@@ -272,6 +272,9 @@ type credentials struct {
 	Expiration      string
 }
 
+// metadataURL is the base URL of the instance metadata service.
+var metadataURL = "http://169.254.169.254/latest/meta-data/"
+
 // GetMetaData retrieves instance metadata about the current machine.
 //
 // See http://docs.aws.amazon.com/AWSEC2/latest/UserGuide/AESDG-chapter-instancedata.html for more details.
@@ -290,7 +293,7 @@ func GetMetaData(path string) (contents []byte, err error) {
 		},
 	}
 
-	url := "http://169.254.169.254/latest/meta-data/" + path
+	url := metadataURL + path
 
 	resp, err := c.Get(url)
 	if err != nil {
@@ -337,6 +340,22 @@ func GetInstanceCredentials() (cred credentials, err error) {
 	return
 }
 
+// InstanceRoleAuth creates an Auth from the credentials of the role of the
+// running instance, fetched from the instance metadata service. The
+// returned Auth carries the session token, and its Token method refreshes
+// the credentials shortly before they expire.
+func InstanceRoleAuth() (auth Auth, err error) {
+	cred, err := GetInstanceCredentials()
+	if err != nil {
+		return auth, err
+	}
+	expiration, err := time.Parse("2006-01-02T15:04:05Z", cred.Expiration)
+	if err != nil {
+		return auth, fmt.Errorf("Error Parsing expiration date: cred.Expiration :%s , error: %s", cred.Expiration, err)
+	}
+	return Auth{cred.AccessKeyId, cred.SecretAccessKey, cred.Token, expiration}, nil
+}
+
 // GetAuth creates an Auth based on either passed in credentials,
 // environment information or instance based role credentials.
 func GetAuth(accessKey string, secretKey, token string, expiration time.Time) (auth Auth, err error) {
@@ -353,18 +372,10 @@ func GetAuth(accessKey string, secretKey, token string, expiration time.Time) (a
 	}
 
 	// Next try getting auth from the instance role
-	cred, err := GetInstanceCredentials()
+	auth, err = InstanceRoleAuth()
 	if err == nil {
 		// Found auth, return
-		auth.AccessKey = cred.AccessKeyId
-		auth.SecretKey = cred.SecretAccessKey
-		auth.token = cred.Token
-		exptdate, err := time.Parse("2006-01-02T15:04:05Z", cred.Expiration)
-		if err != nil {
-			err = fmt.Errorf("Error Parsing expiration date: cred.Expiration :%s , error: %s \n", cred.Expiration, err)
-		}
-		auth.expiration = exptdate
-		return auth, err
+		return
 	}
 
 	// Next try getting auth from the credentials file
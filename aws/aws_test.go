@@ -4,6 +4,8 @@ import (
 	"github.com/AdRoll/goamz/aws"
 	"gopkg.in/check.v1"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -82,6 +84,42 @@ func (s *S) TestGetAuthEnv(c *check.C) {
 	c.Assert(auth, check.Equals, aws.Auth{SecretKey: "secret", AccessKey: "access"})
 }
 
+func (s *S) TestInstanceRoleAuth(c *check.C) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/iam/security-credentials/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("my-role"))
+	})
+	mux.HandleFunc("/iam/security-credentials/my-role", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+  "Code" : "Success",
+  "Type" : "AWS-HMAC",
+  "AccessKeyId" : "access",
+  "SecretAccessKey" : "secret",
+  "Token" : "token",
+  "Expiration" : "2030-01-02T15:04:05Z"
+}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer aws.SetMetadataURL(server.URL + "/")()
+
+	auth, err := aws.InstanceRoleAuth()
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.AccessKey, check.Equals, "access")
+	c.Assert(auth.SecretKey, check.Equals, "secret")
+	c.Assert(auth.Token(), check.Equals, "token")
+	c.Assert(auth.Expiration(), check.Equals, time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC))
+}
+
+func (s *S) TestInstanceRoleAuthNoRole(c *check.C) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	defer aws.SetMetadataURL(server.URL + "/")()
+
+	_, err := aws.InstanceRoleAuth()
+	c.Assert(err, check.ErrorMatches, "Code 404 returned for url .*iam/security-credentials/")
+}
+
 func (s *S) TestEncode(c *check.C) {
 	c.Assert(aws.Encode("foo"), check.Equals, "foo")
 	c.Assert(aws.Encode("/"), check.Equals, "%2F")
@@ -27,3 +27,11 @@ func (s *V4Signer) Signature(t time.Time, sts string) string {
 func (s *V4Signer) Authorization(header http.Header, t time.Time, signature string) string {
 	return s.authorization(header, t, signature)
 }
+
+// SetMetadataURL sets the base URL of the instance metadata service and
+// returns a function restoring the previous one.
+func SetMetadataURL(url string) (restore func()) {
+	old := metadataURL
+	metadataURL = url
+	return func() { metadataURL = old }
+}
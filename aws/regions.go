@@ -194,7 +194,7 @@ var APSoutheast2 = Region{
 
 var APSouth = Region{
 	"ap-south-1",
-	ServiceInfo{"https://ec2.ap-south-1.amazonaws.com", V2Signature},
+	ServiceInfo{"https://ec2.ap-south-1.amazonaws.com", V4Signature},
 	"https://s3-ap-south-1.amazonaws.com",
 	"",
 	true,
@@ -242,7 +242,7 @@ var APNortheast = Region{
 
 var APNortheast2 = Region{
 	"ap-northeast-2",
-	ServiceInfo{"https://ec2.ap-northeast-2.amazonaws.com", V2Signature},
+	ServiceInfo{"https://ec2.ap-northeast-2.amazonaws.com", V4Signature},
 	"https://s3-ap-northeast-2.amazonaws.com",
 	"",
 	true,
@@ -290,7 +290,7 @@ var SAEast = Region{
 
 var CNNorth1 = Region{
 	"cn-north-1",
-	ServiceInfo{"https://ec2.cn-north-1.amazonaws.com.cn", V2Signature},
+	ServiceInfo{"https://ec2.cn-north-1.amazonaws.com.cn", V4Signature},
 	"https://s3.cn-north-1.amazonaws.com.cn",
 	"",
 	true,
@@ -41,6 +41,11 @@ type V2Signer struct {
 	auth    Auth
 	service ServiceInfo
 	host    string
+
+	// OnSign, when set, is called with the sorted, encoded parameters and
+	// the string to sign of every request signed, to help debug
+	// SignatureDoesNotMatch errors.
+	OnSign func(canonicalQuery, stringToSign string)
 }
 
 var b64 = base64.StdEncoding
@@ -74,6 +79,9 @@ func (s *V2Signer) Sign(method, path string, params map[string]string) {
 	}
 	joined := strings.Join(sarray, "&")
 	payload := method + "\n" + s.host + "\n" + path + "\n" + joined
+	if s.OnSign != nil {
+		s.OnSign(joined, payload)
+	}
 	hash := hmac.New(sha256.New, []byte(s.auth.SecretKey))
 	hash.Write([]byte(payload))
 	signature := make([]byte, b64.EncodedLen(hash.Size()))
@@ -91,7 +99,11 @@ func (s *V2Signer) SignRequest(req *http.Request) error {
 		req.Form.Set("SecurityToken", s.auth.Token())
 	}
 
-	payload := req.Method + "\n" + req.URL.Host + "\n" + req.URL.Path + "\n" + EncodeSorted(req.Form)
+	query := EncodeSorted(req.Form)
+	payload := req.Method + "\n" + req.URL.Host + "\n" + req.URL.Path + "\n" + query
+	if s.OnSign != nil {
+		s.OnSign(query, payload)
+	}
 	hash := hmac.New(sha256.New, []byte(s.auth.SecretKey))
 	hash.Write([]byte(payload))
 	signature := make([]byte, b64.EncodedLen(hash.Size()))
@@ -155,6 +167,10 @@ type V4Signer struct {
 	region      Region
 	// Add the x-amz-content-sha256 header
 	IncludeXAmzContentSha256 bool
+	// OnSign, when set, is called with the canonical request and the
+	// string to sign of every request signed, to help debug
+	// SignatureDoesNotMatch errors.
+	OnSign func(canonicalRequest, stringToSign string)
 }
 
 /*
@@ -208,6 +224,9 @@ func (s *V4Signer) Sign(req *http.Request) {
 	sts := s.stringToSign(t, creq)                    // Build string to sign
 	signature := s.signature(t, sts)                  // Calculate the AWS Signature Version 4
 	auth := s.authorization(req.Header, t, signature) // Create Authorization header value
+	if s.OnSign != nil {
+		s.OnSign(creq, sts)
+	}
 
 	if _, ok := req.Form["X-Amz-Expires"]; ok {
 		req.Form["X-Amz-Signature"] = []string{signature}
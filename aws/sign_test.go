@@ -557,6 +557,27 @@ func (s *V4SignerSuite) TestCases(c *check.C) {
 	}
 }
 
+func (s *V4SignerSuite) TestOnSign(c *check.C) {
+	testCase := s.cases[0]
+	req, err := http.NewRequest(testCase.request.method, "http://"+testCase.request.host+testCase.request.url, nil)
+	c.Assert(err, check.IsNil)
+	for _, v := range testCase.request.headers {
+		h := strings.SplitN(v, ":", 2)
+		req.Header.Add(h[0], h[1])
+	}
+
+	var canonicalRequest, stringToSign string
+	signer := aws.NewV4Signer(s.auth, "host", s.region)
+	signer.OnSign = func(creq, sts string) {
+		canonicalRequest, stringToSign = creq, sts
+	}
+	signer.Sign(req)
+
+	c.Check(canonicalRequest, check.Equals, testCase.canonicalRequest)
+	c.Check(stringToSign, check.Equals, testCase.stringToSign)
+	c.Check(req.Header.Get("Authorization"), check.Equals, testCase.authorization)
+}
+
 func ExampleV4Signer() {
 	// Get auth from env vars
 	auth, err := aws.EnvAuth()
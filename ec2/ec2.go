@@ -11,36 +11,222 @@
 package ec2
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha1"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"github.com/AdRoll/goamz/aws"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-const debug = false
+// Debug enables logging of every response received from EC2, headers and
+// body included, through the standard log package. It may be set at any
+// time, for example from a command line flag.
+var Debug = false
 
 // The EC2 type encapsulates operations with a specific EC2 region.
 type EC2 struct {
 	aws.Auth
 	aws.Region
+
+	// Endpoint, when set, overrides Region.EC2Endpoint.Endpoint as the URL
+	// requests are sent to. This is useful to target EC2 emulators such as
+	// localstack or moto, e.g. "http://localhost:4566/".
+	Endpoint string
+
+	// Version, when set, replaces the API version sent with requests for
+	// actions which don't require a newer one. It defaults to
+	// "2014-02-01".
+	Version string
+
+	// OnRequest, when set, is called after every HTTP request with the
+	// action, the time taken until the response headers were received,
+	// and the HTTP status code, which is zero if no response was received.
+	OnRequest func(action string, duration time.Duration, statusCode int)
+
+	// MaxRetries is the number of times a request failing with a server
+	// error or because of throttling is retried. It defaults to zero, so
+	// such failures are returned right away.
+	MaxRetries int
+
+	// RetryBaseDelay is the delay before the first retry, doubled before
+	// each further retry up to RetryMaxDelay. A longer delay requested by
+	// EC2 with a Retry-After header is honored, also up to RetryMaxDelay.
+	// They default to 300 milliseconds and 20 seconds.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// OnRetry, when set, is called before a failed request is retried,
+	// with the action, the number of the upcoming attempt (starting at 2)
+	// and the error that caused the retry.
+	OnRetry func(action string, attempt int, err error)
+
+	// OnResponse, when set, is called with the action and the raw XML body
+	// of every successful response before it is decoded. It gives access
+	// to fields returned by EC2 which the response types don't model yet.
+	// Setting it buffers every response body in full, including those of
+	// DescribeInstancesStream.
+	OnResponse func(action string, body []byte)
+
+	// OnSign, when set, is called as every request is signed with the
+	// action, the canonical request (for V2 signatures, the sorted and
+	// encoded parameters) and the string to sign, which may be compared
+	// with those AWS reports in SignatureDoesNotMatch errors.
+	OnSign func(action, canonicalRequest, stringToSign string)
+
 	private byte // Reserve the right of using private data.
 }
 
 // New creates a new EC2.
 func New(auth aws.Auth, region aws.Region) *EC2 {
-	return &EC2{auth, region, 0}
+	return &EC2{Auth: auth, Region: region}
+}
+
+// Client is the set of operations offered by EC2. Code which depends on
+// Client rather than on *EC2 may be given a fake implementation in tests.
+type Client interface {
+	RunInstances(options *RunInstancesOptions) (*RunInstancesResp, error)
+	TerminateInstances(instIds []string) (*TerminateInstancesResp, error)
+	DescribeAddresses(publicIps []string, allocationIds []string, filter *Filter) (*DescribeAddressesResp, error)
+	AddressesForInterface(networkInterfaceId string) (*DescribeAddressesResp, error)
+	AllocateAddress(domain string) (*AllocateAddressResp, error)
+	ReleaseAddress(publicIp, allocationId string) (*ReleaseAddressResp, error)
+	AssociateAddress(options *AssociateAddressOptions) (*AssociateAddressResp, error)
+	DiassociateAddress(publicIp, associationId string) (*DiassociateAddressResp, error)
+	DescribeInstances(instIds []string, filter *Filter) (*DescribeInstancesResp, error)
+	DescribeInstancesStream(instIds []string, filter *Filter, fn func(Instance) error) error
+	DescribeInstancesPages(opts *DescribeInstancesPagesOptions, fn func(page *DescribeInstancesResp) error) error
+	DescribeInstancesEventuallyConsistent(instIds []string, filter *Filter, opts *ConsistencyOptions) (*DescribeInstancesResp, error)
+	InstancesByTag(key, value string) (*DescribeInstancesResp, error)
+	RunningInstances(filter *Filter) (*DescribeInstancesResp, error)
+	StoppedInstances(filter *Filter) (*DescribeInstancesResp, error)
+	SpotInstances(filter *Filter) (*DescribeInstancesResp, error)
+	WatchInstances(ids []string, interval time.Duration) (<-chan InstanceStateChange, func())
+	Instance(id string) (*Instance, error)
+	Images(ids []string, filter *Filter) (*ImagesResp, error)
+	ImagesWithOptions(opts *ImagesOptions) (*ImagesResp, error)
+	LatestImage(owners []string, namePattern string) (*Image, error)
+	CreateImage(instanceId, name, description string, noReboot bool) (*CreateImageResp, error)
+	CopyImage(sourceRegion aws.Region, imageId, name, description string) (*CreateImageResp, error)
+	CopyImageWithOptions(opts *CopyImageOptions) (*CreateImageResp, error)
+	CreateSnapshot(volumeId, description string) (*CreateSnapshotResp, error)
+	DeleteSnapshots(ssid string) (*SimpleResp, error)
+	Snapshots(ids []string, filter *Filter) (*SnapshotsResp, error)
+	SnapshotsWithOptions(opts *SnapshotsOptions) (*SnapshotsResp, error)
+	WaitUntilSnapshotCompleted(snapshotId string, opts *WaitOptions) error
+	RegisterImage(opts *RegisterImageOptions) (*RegisterImageResp, error)
+	RegisterImageFromSnapshot(name, snapshotId, architecture, rootDeviceName string) (*RegisterImageResp, error)
+	DeregisterImage(imageId string) (*DeregisterImageResponse, error)
+	Subnets(ids []string, filter *Filter) (*SubnetsResp, error)
+	CreateSecurityGroup(name, description string) (*CreateSecurityGroupResp, error)
+	CreateSecurityGroupVPC(name, description, vpcId string) (*CreateSecurityGroupResp, error)
+	SecurityGroups(groups []SecurityGroup, filter *Filter) (*SecurityGroupsResp, error)
+	DeleteSecurityGroup(group SecurityGroup) (*SimpleResp, error)
+	AuthorizeSecurityGroup(group SecurityGroup, perms []IPPerm) (*SimpleResp, error)
+	RevokeSecurityGroup(group SecurityGroup, perms []IPPerm) (*SimpleResp, error)
+	SecurityGroupRules(ids []string, filter *Filter) (*SecurityGroupRulesResp, error)
+	CreateTags(instIds []string, tags []Tag) (*SimpleResp, error)
+	DeleteTags(instIds []string, tags []Tag) (*SimpleResp, error)
+	DescribeTags(filter *Filter) (*DescribeTagsResp, error)
+	StartInstances(ids ...string) (*StartInstanceResp, error)
+	StopInstances(ids ...string) (*StopInstanceResp, error)
+	ModifyInstanceType(instanceId, instanceType string) (*SimpleResp, error)
+	ResizeInstance(instanceId, instanceType string, opts *ResizeOptions) error
+	RebootInstances(ids ...string) (*SimpleResp, error)
+	DescribeReservedInstances(instIds []string, filter *Filter) (*DescribeReservedInstancesResponse, error)
+	ReservedInstances(ids []string, filter *Filter) (*ReservedInstancesResp, error)
+	ReservedInstancesOfferings(filter *Filter) (*ReservedInstancesOfferingsResp, error)
+	ReservedInstancesOfferingsPages(opts *ReservedInstancesOfferingsPagesOptions, fn func(page *ReservedInstancesOfferingsResp) error) error
+	PurchaseReservedInstancesOffering(offeringId string, count int, limitPrice *float64) (*PurchaseReservedInstancesOfferingResp, error)
+	DescribeInstanceStatus(instIds []string, filter *Filter) (*DescribeInstanceStatusResponse, error)
+	DescribeVolumes(volIds []string, filter *Filter) (*DescribeVolumesResp, error)
+	AttachVolume(volId string, instId string, devName string) (*AttachVolumeResp, error)
+	CreateVolume(options CreateVolumeOptions) (*CreateVolumeResp, error)
+	ModifyVolume(volumeId string, opts *ModifyVolumeOptions) (*ModifyVolumeResp, error)
+	VolumeStatus(ids []string, filter *Filter) (*VolumeStatusResp, error)
+	EnableVolumeIO(volumeId string) (*SimpleResp, error)
+	DescribeVpcs(vpcIds []string, filter *Filter) (*DescribeVpcsResp, error)
+	VpcAttribute(vpcId, attribute string) (*VpcAttributeResp, error)
+	ModifyVpcAttribute(vpcId string, opts *VpcAttributeChange) (*SimpleResp, error)
+	DescribeVpnConnections(vpnConnectionIds []string, filter *Filter) (*DescribeVpnConnectionsResp, error)
+	DescribeVpnGateways(vpnGatewayIds []string, filter *Filter) (*DescribeVpnGatewaysResp, error)
+	DescribeInternetGateways(internetGatewayIds []string, filter *Filter) (*DescribeInternetGatewaysResp, error)
+	CreatePlacementGroup(name, strategy string) (*SimpleResp, error)
+	DeletePlacementGroup(name string) (*SimpleResp, error)
+	PlacementGroups(names []string, filter *Filter) (*PlacementGroupsResp, error)
+	InstanceTypeOfferings(locationType string, filter *Filter) (*InstanceTypeOfferingsResp, error)
+	AssociateIamInstanceProfile(instanceId string, profile IamInstanceProfile) (*IamProfileAssociationResp, error)
+	DisassociateIamInstanceProfile(associationId string) (*IamProfileAssociationResp, error)
+	ReplaceIamInstanceProfileAssociation(associationId string, profile IamInstanceProfile) (*IamProfileAssociationResp, error)
+	IamInstanceProfileAssociations(filter *Filter) (*IamInstanceProfileAssociationsResp, error)
+	RequestSpotFleet(config *SpotFleetRequestConfig) (*RequestSpotFleetResp, error)
+	DescribeSpotFleetRequests(ids []string) (*SpotFleetRequestsResp, error)
+	CancelSpotFleetRequests(ids []string, terminateInstances bool) (*CancelSpotFleetRequestsResp, error)
+	KeyPairs(names []string, filter *Filter) (*KeyPairsResp, error)
+	AssignPrivateIpAddresses(networkInterfaceId string, addresses []string, secondaryCount int, allowReassignment bool) (*SimpleResp, error)
+	UnassignPrivateIpAddresses(networkInterfaceId string, addresses []string) (*SimpleResp, error)
+	CreateNatGateway(subnetId, allocationId string) (*CreateNatGatewayResp, error)
+	CreateNatGatewayWithOptions(opts *CreateNatGatewayOptions) (*CreateNatGatewayResp, error)
+	DeleteNatGateway(id string) (*DeleteNatGatewayResp, error)
+	NatGateways(ids []string, filter *Filter) (*NatGatewaysResp, error)
+	CreateDhcpOptions(configs []DhcpConfig) (*CreateDhcpOptionsResp, error)
+	DeleteDhcpOptions(id string) (*SimpleResp, error)
+	AssociateDhcpOptions(dhcpOptionsId, vpcId string) (*SimpleResp, error)
+	DhcpOptions(ids []string, filter *Filter) (*DhcpOptionsResp, error)
+	CreateNetworkAcl(vpcId string) (*CreateNetworkAclResp, error)
+	DeleteNetworkAcl(id string) (*SimpleResp, error)
+	NetworkAcls(ids []string, filter *Filter) (*NetworkAclsResp, error)
+	CreateNetworkAclEntry(networkAclId string, entry *NetworkAclEntry) (*SimpleResp, error)
+	DeleteNetworkAclEntry(networkAclId string, ruleNumber int, egress bool) (*SimpleResp, error)
+	ReplaceNetworkAclAssociation(associationId, networkAclId string) (*ReplaceNetworkAclAssociationResp, error)
+	CreateVpcPeeringConnection(vpcId, peerVpcId, peerOwnerId, peerRegion string) (*VpcPeeringConnectionResp, error)
+	AcceptVpcPeeringConnection(id string) (*VpcPeeringConnectionResp, error)
+	DeleteVpcPeeringConnection(id string) (*SimpleResp, error)
+	VpcPeeringConnections(ids []string, filter *Filter) (*VpcPeeringConnectionsResp, error)
+	CreateVpcEndpoint(opts *CreateVpcEndpointOptions) (*CreateVpcEndpointResp, error)
+	DeleteVpcEndpoints(ids []string) (*DeleteVpcEndpointsResp, error)
+	VpcEndpoints(ids []string, filter *Filter) (*VpcEndpointsResp, error)
+	ModifyVpcEndpoint(id string, opts *ModifyVpcEndpointOptions) (*SimpleResp, error)
+	InstanceTypeInfo(types []string) (*InstanceTypeInfoResp, error)
+	ImportVolume(opts *ImportVolumeOptions) (*ImportVolumeResp, error)
+	DescribeConversionTasks(ids []string) (*DescribeConversionTasksResp, error)
+	CreateInstanceExportTask(instanceId string, opts *ExportTaskOptions) (*CreateInstanceExportTaskResp, error)
+	DescribeExportTasks(ids []string) (*DescribeExportTasksResp, error)
+	CancelExportTask(id string) (*SimpleResp, error)
+	CreateFlowLogs(opts *CreateFlowLogsOptions) (*CreateFlowLogsResp, error)
+	DeleteFlowLogs(ids []string) (*DeleteFlowLogsResp, error)
+	FlowLogs(ids []string, filter *Filter) (*FlowLogsResp, error)
+	BundleInstance(instanceId string, opts *BundleOptions) (*BundleTaskResp, error)
+	DescribeBundleTasks(ids []string, filter *Filter) (*DescribeBundleTasksResp, error)
+	CancelBundleTask(id string) (*BundleTaskResp, error)
+	CreateFleet(config *FleetConfig) (*CreateFleetResp, error)
+	DescribeFleets(ids []string) (*FleetsResp, error)
+	DeleteFleets(ids []string, terminateInstances bool) (*DeleteFleetsResp, error)
+	CreateLaunchTemplate(name string, data *LaunchTemplateData) (*LaunchTemplateResp, error)
+	CreateLaunchTemplateWithOptions(opts *CreateLaunchTemplateOptions) (*LaunchTemplateResp, error)
+	CreateLaunchTemplateVersion(opts *CreateLaunchTemplateVersionOptions) (*CreateLaunchTemplateVersionResp, error)
+	DescribeLaunchTemplates(ids, names []string, filter *Filter) (*LaunchTemplatesResp, error)
+	DeleteLaunchTemplate(id string) (*LaunchTemplateResp, error)
 }
 
+var _ Client = (*EC2)(nil)
+
 // ----------------------------------------------------------------------------
 // Filtering helper.
 
@@ -52,6 +238,8 @@ func New(auth aws.Auth, region aws.Region) *EC2 {
 //     filter.Add("launch-index", "0")
 //     resp, err := ec2.DescribeInstances(nil, filter)
 //
+// All methods taking a *Filter accept nil, which is the same as an empty
+// filter and sends no filtering parameters.
 type Filter struct {
 	m map[string][]string
 }
@@ -63,24 +251,50 @@ func NewFilter() *Filter {
 
 // Add appends a filtering parameter with the given name and value(s).
 func (f *Filter) Add(name string, value ...string) {
+	if f.m == nil {
+		f.m = make(map[string][]string)
+	}
 	f.m[name] = append(f.m[name], value...)
 }
 
+// Merge appends the filtering parameters of other, which may be nil, to f.
+// Values given for a name already in f are added to its values.
+func (f *Filter) Merge(other *Filter) {
+	if other == nil {
+		return
+	}
+	for name, values := range other.m {
+		f.Add(name, values...)
+	}
+}
+
+// Get returns the values of the filtering parameter with the given name.
+func (f *Filter) Get(name string) []string {
+	if f == nil {
+		return nil
+	}
+	return f.m[name]
+}
+
+// Names returns the names of the filtering parameters of f, sorted.
+func (f *Filter) Names() []string {
+	if f == nil {
+		return nil
+	}
+	a := make([]string, 0, len(f.m))
+	for k := range f.m {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
 func (f *Filter) addParams(params map[string]string) {
-	if f != nil {
-		a := make([]string, len(f.m))
-		i := 0
-		for k := range f.m {
-			a[i] = k
-			i++
-		}
-		sort.StringSlice(a).Sort()
-		for i, k := range a {
-			prefix := "Filter." + strconv.Itoa(i+1)
-			params[prefix+".Name"] = k
-			for j, v := range f.m[k] {
-				params[prefix+".Value."+strconv.Itoa(j+1)] = v
-			}
+	for i, k := range f.Names() {
+		prefix := "Filter." + strconv.Itoa(i+1)
+		params[prefix+".Name"] = k
+		for j, v := range f.m[k] {
+			params[prefix+".Value."+strconv.Itoa(j+1)] = v
 		}
 	}
 }
@@ -99,6 +313,9 @@ type Error struct {
 	// The human-oriented error message
 	Message   string
 	RequestId string `xml:"RequestID"`
+	// RetryAfter is the delay requested by the Retry-After header of a
+	// throttled or unavailable response, or zero if none was given.
+	RetryAfter time.Duration `xml:"-"`
 }
 
 func (err *Error) Error() string {
@@ -109,6 +326,22 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("%s (%s)", err.Message, err.Code)
 }
 
+// IsErrorCode reports whether err is an error returned by EC2 with the
+// given code, for example:
+//
+//     if ec2.IsErrorCode(err, "Resource.AlreadyAssociated") {
+//         // Retry with AllowReassociation set.
+//     }
+//
+// An *InstancesNotFoundError is matched by the code of its Err.
+func IsErrorCode(err error, code string) bool {
+	if notFound, ok := err.(*InstancesNotFoundError); ok {
+		err = notFound.Err
+	}
+	ec2err, ok := err.(*Error)
+	return ok && ec2err.Code == code
+}
+
 // For now a single error inst is being exposed. In the future it may be useful
 // to provide access to all of them, but rather than doing it as an array/slice,
 // use a *next pointer, so that it's backward compatible and it continues to be
@@ -118,46 +351,258 @@ type xmlErrors struct {
 	Errors    []Error `xml:"Errors>Error"`
 }
 
+// Response is implemented by the responses of all EC2 requests, allowing
+// generic code such as logging middleware to record the id AWS assigned to
+// each request.
+type Response interface {
+	RequestID() string
+}
+
+// RequestID implementations. Each returns the RequestId field of the response.
+
+func (r *RunInstancesResp) RequestID() string                      { return r.RequestId }
+func (r *TerminateInstancesResp) RequestID() string                { return r.RequestId }
+func (r *DescribeAddressesResp) RequestID() string                 { return r.RequestId }
+func (r *AllocateAddressResp) RequestID() string                   { return r.RequestId }
+func (r *ReleaseAddressResp) RequestID() string                    { return r.RequestId }
+func (r *AssociateAddressResp) RequestID() string                  { return r.RequestId }
+func (r *DiassociateAddressResp) RequestID() string                { return r.RequestId }
+func (r *DescribeInstancesResp) RequestID() string                 { return r.RequestId }
+func (r *ImagesResp) RequestID() string                            { return r.RequestId }
+func (r *CreateImageResp) RequestID() string                       { return r.RequestId }
+func (r *CreateSnapshotResp) RequestID() string                    { return r.RequestId }
+func (r *SnapshotsResp) RequestID() string                         { return r.RequestId }
+func (r *DeregisterImageResponse) RequestID() string               { return r.RequestId }
+func (r *RegisterImageResp) RequestID() string                     { return r.RequestId }
+func (r *SubnetsResp) RequestID() string                           { return r.RequestId }
+func (r *SimpleResp) RequestID() string                            { return r.RequestId }
+func (r *CreateSecurityGroupResp) RequestID() string               { return r.RequestId }
+func (r *SecurityGroupsResp) RequestID() string                    { return r.RequestId }
+func (r *SecurityGroupRulesResp) RequestID() string                { return r.RequestId }
+func (r *DescribeTagsResp) RequestID() string                      { return r.RequestId }
+func (r *StartInstanceResp) RequestID() string                     { return r.RequestId }
+func (r *StopInstanceResp) RequestID() string                      { return r.RequestId }
+func (r *DescribeReservedInstancesResponse) RequestID() string     { return r.RequestId }
+func (r *ReservedInstancesResp) RequestID() string                 { return r.RequestId }
+func (r *ReservedInstancesOfferingsResp) RequestID() string        { return r.RequestId }
+func (r *PurchaseReservedInstancesOfferingResp) RequestID() string { return r.RequestId }
+func (r *DescribeInstanceStatusResponse) RequestID() string        { return r.RequestId }
+func (r *DescribeVolumesResp) RequestID() string                   { return r.RequestId }
+func (r *AttachVolumeResp) RequestID() string                      { return r.RequestId }
+func (r *CreateVolumeResp) RequestID() string                      { return r.RequestId }
+func (r *ModifyVolumeResp) RequestID() string                      { return r.RequestId }
+func (r *VolumeStatusResp) RequestID() string                      { return r.RequestId }
+func (r *DescribeVpcsResp) RequestID() string                      { return r.RequestId }
+func (r *VpcAttributeResp) RequestID() string                      { return r.RequestId }
+func (r *DescribeVpnConnectionsResp) RequestID() string            { return r.RequestId }
+func (r *DescribeVpnGatewaysResp) RequestID() string               { return r.RequestId }
+func (r *DescribeInternetGatewaysResp) RequestID() string          { return r.RequestId }
+func (r *PlacementGroupsResp) RequestID() string                   { return r.RequestId }
+func (r *InstanceTypeOfferingsResp) RequestID() string             { return r.RequestId }
+func (r *InstanceTypeInfoResp) RequestID() string                  { return r.RequestId }
+func (r *IamProfileAssociationResp) RequestID() string             { return r.RequestId }
+func (r *IamInstanceProfileAssociationsResp) RequestID() string    { return r.RequestId }
+func (r *RequestSpotFleetResp) RequestID() string                  { return r.RequestId }
+func (r *SpotFleetRequestsResp) RequestID() string                 { return r.RequestId }
+func (r *CancelSpotFleetRequestsResp) RequestID() string           { return r.RequestId }
+func (r *KeyPairsResp) RequestID() string                          { return r.RequestId }
+func (r *CreateNatGatewayResp) RequestID() string                  { return r.RequestId }
+func (r *DeleteNatGatewayResp) RequestID() string                  { return r.RequestId }
+func (r *NatGatewaysResp) RequestID() string                       { return r.RequestId }
+func (r *CreateDhcpOptionsResp) RequestID() string                 { return r.RequestId }
+func (r *DhcpOptionsResp) RequestID() string                       { return r.RequestId }
+func (r *CreateNetworkAclResp) RequestID() string                  { return r.RequestId }
+func (r *NetworkAclsResp) RequestID() string                       { return r.RequestId }
+func (r *ReplaceNetworkAclAssociationResp) RequestID() string      { return r.RequestId }
+func (r *VpcPeeringConnectionResp) RequestID() string              { return r.RequestId }
+func (r *VpcPeeringConnectionsResp) RequestID() string             { return r.RequestId }
+func (r *CreateVpcEndpointResp) RequestID() string                 { return r.RequestId }
+func (r *DeleteVpcEndpointsResp) RequestID() string                { return r.RequestId }
+func (r *VpcEndpointsResp) RequestID() string                      { return r.RequestId }
+func (r *ImportVolumeResp) RequestID() string                      { return r.RequestId }
+func (r *DescribeConversionTasksResp) RequestID() string           { return r.RequestId }
+func (r *CreateInstanceExportTaskResp) RequestID() string          { return r.RequestId }
+func (r *DescribeExportTasksResp) RequestID() string               { return r.RequestId }
+func (r *CreateFlowLogsResp) RequestID() string                    { return r.RequestId }
+func (r *DeleteFlowLogsResp) RequestID() string                    { return r.RequestId }
+func (r *FlowLogsResp) RequestID() string                          { return r.RequestId }
+func (r *BundleTaskResp) RequestID() string                        { return r.RequestId }
+func (r *DescribeBundleTasksResp) RequestID() string               { return r.RequestId }
+func (r *CreateFleetResp) RequestID() string                       { return r.RequestId }
+func (r *FleetsResp) RequestID() string                            { return r.RequestId }
+func (r *DeleteFleetsResp) RequestID() string                      { return r.RequestId }
+func (r *LaunchTemplateResp) RequestID() string                    { return r.RequestId }
+func (r *CreateLaunchTemplateVersionResp) RequestID() string       { return r.RequestId }
+func (r *LaunchTemplatesResp) RequestID() string                   { return r.RequestId }
+
+// API versions sent with requests. Most actions use defaultAPIVersion;
+// actions introduced later set the Version parameter to newAPIVersion.
+const (
+	defaultAPIVersion = "2014-02-01"
+	newAPIVersion     = "2016-11-15"
+)
+
 var timeNow = time.Now
 
+// ErrMissingRegionEndpoint is returned by requests made with an EC2 whose
+// region has no EC2 endpoint, or one that is not an absolute URL, as
+// happens when the zero aws.Region is used.
+var ErrMissingRegionEndpoint = errors.New("region has no valid EC2 endpoint URL")
+
 func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
+	return ec2.send("GET", params, resp)
+}
+
+// post is like query but sends the parameters in a form-encoded request
+// body, which avoids overflowing URL length limits on large requests.
+func (ec2 *EC2) post(params map[string]string, resp interface{}) error {
+	return ec2.send("POST", params, resp)
+}
+
+// Default delays between retries of failed requests.
+const (
+	defaultRetryBaseDelay = 300 * time.Millisecond
+	defaultRetryMaxDelay  = 20 * time.Second
+)
+
+var sleep = time.Sleep
+
+// send issues the request. If it was rejected as expired, which happens
+// when the local clock is off by more than five minutes, it is retried
+// once with the timestamp corrected by the clock offset to the server.
+// Server errors and throttled requests are retried up to MaxRetries times
+// with an exponential backoff.
+func (ec2 *EC2) send(method string, params map[string]string, resp interface{}) error {
+	var skew time.Duration
+	skewFixed := false
+	retries := 0
+	for attempt := 1; ; attempt++ {
+		serverTime, err := ec2.sendAt(method, params, resp, timeNow().Add(skew))
+		var delay time.Duration
+		switch {
+		case err == nil:
+			return nil
+		case IsErrorCode(err, "RequestExpired") && !skewFixed && !serverTime.IsZero():
+			skewFixed = true
+			skew = serverTime.Sub(timeNow())
+		case isRetryable(err) && retries < ec2.MaxRetries:
+			retries++
+			delay = ec2.retryDelay(retries, err)
+		default:
+			return err
+		}
+		if ec2.OnRetry != nil {
+			ec2.OnRetry(params["Action"], attempt+1, err)
+		}
+		if delay > 0 {
+			sleep(delay)
+		}
+	}
+}
+
+// retryDelay returns how long to wait before the given retry, counting
+// from 1, of a request which failed with err.
+func (ec2 *EC2) retryDelay(retry int, err error) time.Duration {
+	base, max := ec2.RetryBaseDelay, ec2.RetryMaxDelay
+	if base == 0 {
+		base = defaultRetryBaseDelay
+	}
+	if max == 0 {
+		max = defaultRetryMaxDelay
+	}
+	delay := base
+	for i := 1; i < retry && delay < max; i++ {
+		delay *= 2
+	}
+	if ec2err, ok := err.(*Error); ok && ec2err.RetryAfter > delay {
+		delay = ec2err.RetryAfter
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// maxQueryLength is the longest query string sent in a GET request URL.
+// Requests with longer query strings are sent as POST requests.
+const maxQueryLength = 4096
+
+// throttlingCodes lists the error codes EC2 uses for throttled requests.
+var throttlingCodes = map[string]bool{
+	"RequestLimitExceeded": true,
+	"Throttling":           true,
+	"ThrottlingException":  true,
+}
+
+// isRetryable reports whether err is a server error or a throttled
+// request, which may succeed if retried later.
+func isRetryable(err error) bool {
+	ec2err, ok := err.(*Error)
+	return ok && (ec2err.StatusCode >= 500 || throttlingCodes[ec2err.Code])
+}
+
+// sendAt sends a request timestamped with now and decodes the response
+// into resp. It returns the time given by the Date header of the response,
+// or the zero time if there is none.
+func (ec2 *EC2) sendAt(method string, params map[string]string, resp interface{}, now time.Time) (time.Time, error) {
 	values := multimap(params)
-	values.Set("Version", "2014-02-01")
-	values.Set("Timestamp", timeNow().In(time.UTC).Format(time.RFC3339))
+	if _, ok := params["Version"]; !ok {
+		if ec2.Version != "" {
+			values.Set("Version", ec2.Version)
+		} else {
+			values.Set("Version", defaultAPIVersion)
+		}
+	}
+	values.Set("Timestamp", now.In(time.UTC).Format(time.RFC3339))
+	query := values.Encode()
+	if method == "GET" && len(query) > maxQueryLength {
+		// Long parameter lists, such as many ImageId.N entries, would
+		// overflow the URL, so send them in the request body instead.
+		method = "POST"
+	}
 
 	client := http.Client{}
 
-	req, err := http.NewRequest("GET", ec2.Region.EC2Endpoint.Endpoint, nil)
+	endpoint := ec2.Region.EC2Endpoint.Endpoint
+	if ec2.Endpoint != "" {
+		endpoint = ec2.Endpoint
+	}
+	req, err := http.NewRequest(method, endpoint, nil)
 	if err != nil {
-		return err
+		return time.Time{}, err
+	}
+	if req.URL.Scheme == "" || req.URL.Host == "" {
+		return time.Time{}, ErrMissingRegionEndpoint
 	}
 
 	if req.URL.Path == "" {
 		req.URL.Path = "/"
 	}
 
-	req.URL.RawQuery = values.Encode()
+	req.URL.RawQuery = query
 
-	if ec2.Region.EC2Endpoint.Signer == aws.V2Signature {
-		sgnr, err := aws.NewV2Signer(ec2.Auth, ec2.Region.EC2Endpoint)
-		sgnr.SignRequest(req)
-		if err != nil {
-			return err
-		}
-	} else if ec2.Region.EC2Endpoint.Signer == aws.V4Signature {
-		sgnr := aws.NewV4Signer(ec2.Auth, "ec2", ec2.Region)
-		sgnr.SignRequest(req)
-	} else {
-		str := fmt.Sprintf("Unknown signature type specified for region '%v'", ec2.Region.Name)
-		return errors.New(str)
+	if err := ec2.sign(req, now); err != nil {
+		return time.Time{}, err
+	}
+
+	if Debug {
+		logRequest(req)
 	}
 
+	start := time.Now()
 	r, err := client.Do(req)
+	if ec2.OnRequest != nil {
+		statusCode := 0
+		if err == nil {
+			statusCode = r.StatusCode
+		}
+		ec2.OnRequest(params["Action"], time.Since(start), statusCode)
+	}
 	if err != nil {
-		return err
+		return time.Time{}, err
 	}
 
-	if debug {
+	if Debug {
 		dump, _ := httputil.DumpResponse(r, true)
 		log.Printf("response:\n")
 		log.Printf("%v\n}\n", string(dump))
@@ -165,13 +610,127 @@ func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
 
 	defer r.Body.Close()
 
+	serverTime, _ := http.ParseTime(r.Header.Get("Date"))
+
 	if r.StatusCode != 200 {
-		return buildError(r)
+		return serverTime, buildError(r)
+	}
+
+	if ec2.OnResponse != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return serverTime, err
+		}
+		ec2.OnResponse(params["Action"], body)
+		return serverTime, xml.Unmarshal(body, resp)
 	}
 
 	err = xml.NewDecoder(r.Body).Decode(resp)
 
-	return err
+	return serverTime, err
+}
+
+// sign signs req with the signature version selected by the Signer of the
+// region's EC2 endpoint. Regions opened after 2014, such as eu-central-1,
+// only accept Signature Version 4. The version may be forced by setting
+// EC2Endpoint.Signer before issuing requests.
+//
+// Temporary credentials carry a session token which is sent as the
+// SecurityToken parameter (V2) or the X-Amz-Security-Token header (V4).
+// Fetching the token first refreshes expiring instance role credentials,
+// so the signers below are given the current keys.
+func (ec2 *EC2) sign(req *http.Request, now time.Time) error {
+	token := ec2.Auth.Token()
+	switch ec2.Region.EC2Endpoint.Signer {
+	case aws.V2Signature:
+		signer, err := aws.NewV2Signer(ec2.Auth, ec2.Region.EC2Endpoint)
+		if err != nil {
+			return err
+		}
+		signer.OnSign = ec2.onSign(req)
+		if err := signer.SignRequest(req); err != nil {
+			return err
+		}
+		if req.Method == "POST" {
+			setFormBody(req)
+		}
+		return nil
+	case aws.V4Signature:
+		if req.Method == "POST" {
+			setFormBody(req)
+		}
+		if token != "" {
+			req.Header.Set("X-Amz-Security-Token", token)
+		}
+		req.Header.Set("x-amz-date", now.In(time.UTC).Format(aws.ISO8601BasicFormat))
+		signer := aws.NewV4Signer(ec2.Auth, "ec2", ec2.Region)
+		signer.OnSign = ec2.onSign(req)
+		return signer.SignRequest(req)
+	}
+	return fmt.Errorf("Unknown signature type specified for region '%v'", ec2.Region.Name)
+}
+
+// onSign returns the signer hook forwarding to ec2.OnSign for req, or nil
+// if OnSign isn't set.
+func (ec2 *EC2) onSign(req *http.Request) func(canonical, stringToSign string) {
+	if ec2.OnSign == nil {
+		return nil
+	}
+	action := req.URL.Query().Get("Action")
+	return func(canonical, stringToSign string) {
+		ec2.OnSign(action, canonical, stringToSign)
+	}
+}
+
+// setFormBody moves the query parameters of req into a form-encoded body.
+func setFormBody(req *http.Request) {
+	body := req.URL.RawQuery
+	req.URL.RawQuery = ""
+	req.Body = ioutil.NopCloser(strings.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+}
+
+// sensitiveParams lists the request parameters that carry credentials and
+// must not be written to the debug log.
+var sensitiveParams = []string{
+	"AWSAccessKeyId",
+	"Signature",
+	"SecurityToken",
+	"X-Amz-Credential",
+	"X-Amz-Signature",
+	"X-Amz-Security-Token",
+}
+
+// logRequest logs the method, URL and form body of a signed request with
+// any credentials masked.
+func logRequest(req *http.Request) {
+	u := *req.URL
+	u.RawQuery = redact(u.RawQuery)
+	log.Printf("request: %s %s\n", req.Method, u.String())
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			log.Printf("body: %s\n", redact(string(b)))
+		}
+	}
+}
+
+// redact returns query with the values of sensitiveParams masked.
+func redact(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "<unparseable query>"
+	}
+	for _, k := range sensitiveParams {
+		if _, ok := values[k]; ok {
+			values.Set(k, "REDACTED")
+		}
+	}
+	return values.Encode()
 }
 
 func multimap(p map[string]string) url.Values {
@@ -190,13 +749,67 @@ func buildError(r *http.Response) error {
 		err = errors.Errors[0]
 	}
 	err.RequestId = errors.RequestId
+	if err.RequestId == "" {
+		err.RequestId = r.Header.Get("x-amzn-RequestId")
+	}
+	if err.RequestId == "" {
+		err.RequestId = r.Header.Get("x-amz-request-id")
+	}
 	err.StatusCode = r.StatusCode
+	err.RetryAfter = retryAfter(r.Header.Get("Retry-After"))
 	if err.Message == "" {
 		err.Message = r.Status
 	}
 	return &err
 }
 
+// retryAfter parses the value of a Retry-After header, given either as a
+// number of seconds or as an HTTP date. It returns zero if the value is
+// empty, invalid or in the past.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(timeNow()); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// CheckDryRun interprets the error returned by a request made with DryRun
+// set. It reports whether the caller has the permissions required for the
+// action: a DryRunOperation error means it would have succeeded and an
+// UnauthorizedOperation error means it would have been denied. Any other
+// error is returned unchanged.
+func CheckDryRun(err error) (permitted bool, rerr error) {
+	if ec2err, ok := err.(*Error); ok {
+		switch ec2err.Code {
+		case "DryRunOperation":
+			return true, nil
+		case "UnauthorizedOperation":
+			return false, nil
+		}
+	}
+	if err == nil {
+		err = errors.New("request was not a dry run")
+	}
+	return false, err
+}
+
+func addDryRun(params map[string]string, dryRun bool) {
+	if dryRun {
+		params["DryRun"] = "true"
+	}
+}
+
 func makeParams(action string) map[string]string {
 	params := make(map[string]string)
 	params["Action"] = action
@@ -237,17 +850,80 @@ type RunInstancesOptions struct {
 	BlockDeviceMappings   []BlockDeviceMapping
 	EbsOptimized          bool
 	NetworkInterfaces     []NetworkInterface
+
+	// InstanceMarketOptions requests spot instances directly, without
+	// going through RequestSpotInstances. Optional.
+	InstanceMarketOptions *MarketOptions
+
+	// TagSpecifications tags the instances and volumes created by the
+	// launch, so that they are never visible untagged. Optional.
+	TagSpecifications []TagSpecification
+
+	// LaunchTemplate launches the instances from a launch template.
+	// Fields set in these options override the values of the template.
+	// Optional.
+	LaunchTemplate *LaunchTemplateSpec
+
+	// ClientToken ensures the idempotency of the request. Retrying with the
+	// same token will not launch duplicate instances. If empty, a random
+	// token is generated and returned in RunInstancesResp; callers that
+	// need to retry after a failed request should set it themselves.
+	ClientToken string
+
+	// DryRun checks for the required permissions without launching any
+	// instances. See CheckDryRun.
+	DryRun bool
 }
 
-// NetworkInterface is for creating and attaching to ec2 instances on launch
+// NetworkInterface is for creating and attaching to ec2 instances on launch.
+// Setting NetworkInterfaces in RunInstancesOptions allows launching with
+// several interfaces, or with a public IP address in a subnet that does not
+// assign one by default; SubnetId and PrivateIPAddress in the options must
+// then be left empty and given per interface instead.
 type NetworkInterface struct {
-	AssociatePublicIpAddress bool
-	SubnetId                 string
-	Description              string
-	SecurityGroups           []SecurityGroup
-	DeleteOnTermination      bool
-	PrivateIpAddress         string // primary private ip
-	PrivateIpAddresses       []InstancePrivateIpAddress
+	NetworkInterfaceId             string // attach an existing interface
+	DeviceIndex                    int    // defaults to the position in NetworkInterfaces
+	AssociatePublicIpAddress       bool
+	SubnetId                       string
+	Description                    string
+	SecurityGroups                 []SecurityGroup
+	DeleteOnTermination            bool
+	PrivateIpAddress               string // primary private ip
+	PrivateIpAddresses             []InstancePrivateIpAddress
+	SecondaryPrivateIpAddressCount int
+}
+
+// MarketOptions describes the market (purchasing) option for instances
+// launched by RunInstances. Only the spot market is supported by EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InstanceMarketOptionsRequest.html for more details.
+type MarketOptions struct {
+	MarketType                   string // Defaults to "spot"
+	MaxPrice                     string // Maximum hourly price; defaults to the on-demand price
+	SpotInstanceType             string // "one-time" or "persistent"
+	ValidUntil                   string // End date of a persistent request, in ISO 8601 format
+	InstanceInterruptionBehavior string // "terminate", "stop" or "hibernate"
+}
+
+func (o *MarketOptions) addParams(params map[string]string, prefix string) {
+	prefix += "InstanceMarketOptions."
+	if o.MarketType != "" {
+		params[prefix+"MarketType"] = o.MarketType
+	} else {
+		params[prefix+"MarketType"] = "spot"
+	}
+	if o.MaxPrice != "" {
+		params[prefix+"SpotOptions.MaxPrice"] = o.MaxPrice
+	}
+	if o.SpotInstanceType != "" {
+		params[prefix+"SpotOptions.SpotInstanceType"] = o.SpotInstanceType
+	}
+	if o.ValidUntil != "" {
+		params[prefix+"SpotOptions.ValidUntil"] = o.ValidUntil
+	}
+	if o.InstanceInterruptionBehavior != "" {
+		params[prefix+"SpotOptions.InstanceInterruptionBehavior"] = o.InstanceInterruptionBehavior
+	}
 }
 
 // Response to a RunInstances request.
@@ -259,6 +935,10 @@ type RunInstancesResp struct {
 	OwnerId        string          `xml:"ownerId"`
 	SecurityGroups []SecurityGroup `xml:"groupSet>item"`
 	Instances      []Instance      `xml:"instancesSet>item"`
+
+	// ClientToken is the idempotency token sent with the request, either
+	// the one given in RunInstancesOptions or the one generated for it.
+	ClientToken string `xml:"-"`
 }
 
 // Instance encapsulates a running instance in EC2.
@@ -267,57 +947,58 @@ type RunInstancesResp struct {
 type Instance struct {
 
 	// General instance information
-	InstanceId         string              `xml:"instanceId"`                 // The ID of the instance launched
-	InstanceType       string              `xml:"instanceType"`               // The instance type eg. m1.small | m1.medium | m1.large etc
-	AvailabilityZone   string              `xml:"placement>availabilityZone"` // The Availability Zone the instance is located in
-	Tags               []Tag               `xml:"tagSet>item"`                // Any tags assigned to the resource
-	State              InstanceState       `xml:"instanceState"`              // The current state of the instance
-	Reason             string              `xml:"reason"`                     // The reason for the most recent state transition. This might be an empty string
-	StateReason        InstanceStateReason `xml:"stateReason"`                // The reason for the most recent state transition
-	ImageId            string              `xml:"imageId"`                    // The ID of the AMI used to launch the instance
-	KeyName            string              `xml:"keyName"`                    // The key pair name, if this instance was launched with an associated key pair
-	Monitoring         string              `xml:"monitoring>state"`           // Valid values: disabled | enabled | pending
-	IamInstanceProfile IamInstanceProfile  `xml:"iamInstanceProfile"`         // The IAM instance profile associated with the instance
-	LaunchTime         string              `xml:"launchTime"`                 // The time the instance was launched
-	OwnerId            string              // This isn't currently returned in the response, and is taken from the parent reservation
+	InstanceId         string              `xml:"instanceId" json:"instanceId"`                       // The ID of the instance launched
+	InstanceType       string              `xml:"instanceType" json:"instanceType"`                   // The instance type eg. m1.small | m1.medium | m1.large etc
+	AvailabilityZone   string              `xml:"placement>availabilityZone" json:"availabilityZone"` // The Availability Zone the instance is located in
+	Tags               []Tag               `xml:"tagSet>item" json:"tags"`                            // Any tags assigned to the resource
+	State              InstanceState       `xml:"instanceState" json:"state"`                         // The current state of the instance
+	Reason             string              `xml:"reason" json:"reason"`                               // The reason for the most recent state transition. This might be an empty string
+	StateReason        InstanceStateReason `xml:"stateReason" json:"stateReason"`                     // The reason for the most recent state transition
+	ImageId            string              `xml:"imageId" json:"imageId"`                             // The ID of the AMI used to launch the instance
+	KeyName            string              `xml:"keyName" json:"keyName"`                             // The key pair name, if this instance was launched with an associated key pair
+	Monitoring         string              `xml:"monitoring>state" json:"monitoring"`                 // Valid values: disabled | enabled | pending
+	IamInstanceProfile IamInstanceProfile  `xml:"iamInstanceProfile" json:"iamInstanceProfile"`       // The IAM instance profile associated with the instance
+	LaunchTime         string              `xml:"launchTime" json:"launchTime"`                       // The time the instance was launched
+	OwnerId            string              `json:"ownerId"`                                           // This isn't currently returned in the response, and is taken from the parent reservation
+	RequesterId        string              `json:"requesterId"`                                       // Likewise taken from the parent reservation
 
 	// More specific information
-	Architecture          string        `xml:"architecture"`          // Valid values: i386 | x86_64
-	Hypervisor            string        `xml:"hypervisor"`            // Valid values: ovm | xen
-	KernelId              string        `xml:"kernelId"`              // The kernel associated with this instance
-	RamDiskId             string        `xml:"ramdiskId"`             // The RAM disk associated with this instance
-	Platform              string        `xml:"platform"`              // The value is Windows for Windows AMIs; otherwise blank
-	VirtualizationType    string        `xml:"virtualizationType"`    // Valid values: paravirtual | hvm
-	AMILaunchIndex        int           `xml:"amiLaunchIndex"`        // The AMI launch index, which can be used to find this instance in the launch group
-	PlacementGroupName    string        `xml:"placement>groupName"`   // The name of the placement group the instance is in (for cluster compute instances)
-	Tenancy               string        `xml:"placement>tenancy"`     // (VPC only) Valid values: default | dedicated
-	InstanceLifecycle     string        `xml:"instanceLifecycle"`     // Spot instance? Valid values: "spot" or blank
-	SpotInstanceRequestId string        `xml:"spotInstanceRequestId"` // The ID of the Spot Instance request
-	ClientToken           string        `xml:"clientToken"`           // The idempotency token you provided when you launched the instance
-	ProductCodes          []ProductCode `xml:"productCodes>item"`     // The product codes attached to this instance
+	Architecture          string        `xml:"architecture" json:"architecture"`                   // Valid values: i386 | x86_64
+	Hypervisor            string        `xml:"hypervisor" json:"hypervisor"`                       // Valid values: ovm | xen
+	KernelId              string        `xml:"kernelId" json:"kernelId"`                           // The kernel associated with this instance
+	RamDiskId             string        `xml:"ramdiskId" json:"ramdiskId"`                         // The RAM disk associated with this instance
+	Platform              string        `xml:"platform" json:"platform"`                           // The value is Windows for Windows AMIs; otherwise blank
+	VirtualizationType    string        `xml:"virtualizationType" json:"virtualizationType"`       // Valid values: paravirtual | hvm
+	AMILaunchIndex        int           `xml:"amiLaunchIndex" json:"amiLaunchIndex"`               // The AMI launch index, which can be used to find this instance in the launch group
+	PlacementGroupName    string        `xml:"placement>groupName" json:"placementGroupName"`      // The name of the placement group the instance is in (for cluster compute instances)
+	Tenancy               string        `xml:"placement>tenancy" json:"tenancy"`                   // (VPC only) Valid values: default | dedicated
+	InstanceLifecycle     string        `xml:"instanceLifecycle" json:"instanceLifecycle"`         // Spot instance? Valid values: "spot" or blank
+	SpotInstanceRequestId string        `xml:"spotInstanceRequestId" json:"spotInstanceRequestId"` // The ID of the Spot Instance request
+	ClientToken           string        `xml:"clientToken" json:"clientToken"`                     // The idempotency token you provided when you launched the instance
+	ProductCodes          []ProductCode `xml:"productCodes>item" json:"productCodes"`              // The product codes attached to this instance
 
 	// Storage
-	RootDeviceType string        `xml:"rootDeviceType"`          // Valid values: ebs | instance-store
-	RootDeviceName string        `xml:"rootDeviceName"`          // The root device name (for example, /dev/sda1)
-	BlockDevices   []BlockDevice `xml:"blockDeviceMapping>item"` // Any block device mapping entries for the instance
-	EbsOptimized   bool          `xml:"ebsOptimized"`            // Indicates whether the instance is optimized for Amazon EBS I/O
+	RootDeviceType string        `xml:"rootDeviceType" json:"rootDeviceType"`        // Valid values: ebs | instance-store
+	RootDeviceName string        `xml:"rootDeviceName" json:"rootDeviceName"`        // The root device name (for example, /dev/sda1)
+	BlockDevices   []BlockDevice `xml:"blockDeviceMapping>item" json:"blockDevices"` // Any block device mapping entries for the instance
+	EbsOptimized   bool          `xml:"ebsOptimized" json:"ebsOptimized"`            // Indicates whether the instance is optimized for Amazon EBS I/O
 
 	// Network
-	DNSName          string          `xml:"dnsName"`          // The public DNS name assigned to the instance. This element remains empty until the instance enters the running state
-	PrivateDNSName   string          `xml:"privateDnsName"`   // The private DNS name assigned to the instance. This DNS name can only be used inside the Amazon EC2 network. This element remains empty until the instance enters the running state
-	IPAddress        string          `xml:"ipAddress"`        // The public IP address assigned to the instance
-	PrivateIPAddress string          `xml:"privateIpAddress"` // The private IP address assigned to the instance
-	SubnetId         string          `xml:"subnetId"`         // The ID of the subnet in which the instance is running
-	VpcId            string          `xml:"vpcId"`            // The ID of the VPC in which the instance is running
-	SecurityGroups   []SecurityGroup `xml:"groupSet>item"`    // A list of the security groups for the instance
+	DNSName          string          `xml:"dnsName" json:"dnsName"`                   // The public DNS name assigned to the instance. This element remains empty until the instance enters the running state
+	PrivateDNSName   string          `xml:"privateDnsName" json:"privateDnsName"`     // The private DNS name assigned to the instance. This DNS name can only be used inside the Amazon EC2 network. This element remains empty until the instance enters the running state
+	IPAddress        string          `xml:"ipAddress" json:"ipAddress"`               // The public IP address assigned to the instance
+	PrivateIPAddress string          `xml:"privateIpAddress" json:"privateIpAddress"` // The private IP address assigned to the instance
+	SubnetId         string          `xml:"subnetId" json:"subnetId"`                 // The ID of the subnet in which the instance is running
+	VpcId            string          `xml:"vpcId" json:"vpcId"`                       // The ID of the VPC in which the instance is running
+	SecurityGroups   []SecurityGroup `xml:"groupSet>item" json:"securityGroups"`      // A list of the security groups for the instance
 
 	// Advanced Networking
-	NetworkInterfaces []InstanceNetworkInterface `xml:"networkInterfaceSet>item"` // (VPC) One or more network interfaces for the instance
-	SourceDestCheck   bool                       `xml:"sourceDestCheck"`          // Controls whether source/destination checking is enabled on the instance
-	SriovNetSupport   string                     `xml:"sriovNetSupport"`          // Specifies whether enhanced networking is enabled. Valid values: simple
+	NetworkInterfaces []InstanceNetworkInterface `xml:"networkInterfaceSet>item" json:"networkInterfaces"` // (VPC) One or more network interfaces for the instance
+	SourceDestCheck   bool                       `xml:"sourceDestCheck" json:"sourceDestCheck"`            // Controls whether source/destination checking is enabled on the instance
+	SriovNetSupport   string                     `xml:"sriovNetSupport" json:"sriovNetSupport"`            // Specifies whether enhanced networking is enabled. Valid values: simple
 }
 
-// isSpotInstance returns if the instance is a spot instance
+// IsSpotInstance returns whether the instance is a spot instance.
 func (i Instance) IsSpotInstance() bool {
 	if i.InstanceLifecycle == "spot" {
 		return true
@@ -325,77 +1006,239 @@ func (i Instance) IsSpotInstance() bool {
 	return false
 }
 
+// RunOptions returns options for launching a new instance configured like
+// i: same image, type, key pair, placement, subnet, security groups, IAM
+// instance profile and block device names. EBS volume ids and snapshots
+// are not copied, so the new instance gets fresh volumes from its image.
+func (i *Instance) RunOptions() *RunInstancesOptions {
+	options := &RunInstancesOptions{
+		ImageId:            i.ImageId,
+		InstanceType:       i.InstanceType,
+		KeyName:            i.KeyName,
+		AvailabilityZone:   i.AvailabilityZone,
+		PlacementGroupName: i.PlacementGroupName,
+		Tenancy:            i.Tenancy,
+		SubnetId:           i.SubnetId,
+		Monitoring:         i.Monitoring == "enabled",
+		IamInstanceProfile: IamInstanceProfile{ARN: i.IamInstanceProfile.ARN},
+		EbsOptimized:       i.EbsOptimized,
+	}
+	if len(i.SecurityGroups) > 0 {
+		options.SecurityGroups = make([]SecurityGroup, len(i.SecurityGroups))
+		copy(options.SecurityGroups, i.SecurityGroups)
+	}
+	for _, d := range i.BlockDevices {
+		options.BlockDeviceMappings = append(options.BlockDeviceMappings, BlockDeviceMapping{
+			DeviceName:          d.DeviceName,
+			DeleteOnTermination: d.EBS.DeleteOnTermination,
+		})
+	}
+	return options
+}
+
 type BlockDevice struct {
-	DeviceName string `xml:"deviceName"`
-	EBS        EBS    `xml:"ebs"`
+	DeviceName string `xml:"deviceName" json:"deviceName"`
+	EBS        EBS    `xml:"ebs" json:"ebs"`
 }
 
 type EBS struct {
-	VolumeId            string `xml:"volumeId"`
-	Status              string `xml:"status"`
-	AttachTime          string `xml:"attachTime"`
-	DeleteOnTermination bool   `xml:"deleteOnTermination"`
+	VolumeId            string `xml:"volumeId" json:"volumeId"`
+	Status              string `xml:"status" json:"status"`
+	AttachTime          string `xml:"attachTime" json:"attachTime"`
+	DeleteOnTermination bool   `xml:"deleteOnTermination" json:"deleteOnTermination"`
 }
 
 // ProductCode represents a product code
 // See http://goo.gl/hswmQm for more details.
 type ProductCode struct {
-	ProductCode string `xml:"productCode"` // The product code
-	Type        string `xml:"type"`        // Valid values: devpay | marketplace
+	ProductCode string `xml:"productCode" json:"productCode"` // The product code
+	Type        string `xml:"type" json:"type"`               // Valid values: devpay | marketplace
 }
 
 // InstanceNetworkInterface represents a network interface attached to an instance
 // See http://goo.gl/9eW02N for more details.
 type InstanceNetworkInterface struct {
-	Id                 string                              `xml:"networkInterfaceId"`
-	Description        string                              `xml:"description"`
-	SubnetId           string                              `xml:"subnetId"`
-	VpcId              string                              `xml:"vpcId"`
-	OwnerId            string                              `xml:"ownerId"` // The ID of the AWS account that created the network interface.
-	Status             string                              `xml:"status"`  // Valid values: available | attaching | in-use | detaching
-	MacAddress         string                              `xml:"macAddress"`
-	PrivateIPAddress   string                              `xml:"privateIpAddress"`
-	PrivateDNSName     string                              `xml:"privateDnsName"`
-	SourceDestCheck    bool                                `xml:"sourceDestCheck"`
-	SecurityGroups     []SecurityGroup                     `xml:"groupSet>item"`
-	Attachment         InstanceNetworkInterfaceAttachment  `xml:"attachment"`
-	Association        InstanceNetworkInterfaceAssociation `xml:"association"`
-	PrivateIPAddresses []InstancePrivateIpAddress          `xml:"privateIpAddressesSet>item"`
+	Id                 string                              `xml:"networkInterfaceId" json:"id"`
+	Description        string                              `xml:"description" json:"description"`
+	SubnetId           string                              `xml:"subnetId" json:"subnetId"`
+	VpcId              string                              `xml:"vpcId" json:"vpcId"`
+	OwnerId            string                              `xml:"ownerId" json:"ownerId"` // The ID of the AWS account that created the network interface.
+	Status             string                              `xml:"status" json:"status"`   // Valid values: available | attaching | in-use | detaching
+	MacAddress         string                              `xml:"macAddress" json:"macAddress"`
+	PrivateIPAddress   string                              `xml:"privateIpAddress" json:"privateIpAddress"`
+	PrivateDNSName     string                              `xml:"privateDnsName" json:"privateDnsName"`
+	SourceDestCheck    bool                                `xml:"sourceDestCheck" json:"sourceDestCheck"`
+	SecurityGroups     []SecurityGroup                     `xml:"groupSet>item" json:"securityGroups"`
+	Attachment         InstanceNetworkInterfaceAttachment  `xml:"attachment" json:"attachment"`
+	Association        InstanceNetworkInterfaceAssociation `xml:"association" json:"association"`
+	PrivateIPAddresses []InstancePrivateIpAddress          `xml:"privateIpAddressesSet>item" json:"privateIpAddresses"`
 }
 
 // InstanceNetworkInterfaceAttachment describes a network interface attachment to an instance
 // See http://goo.gl/0ql0Cg for more details
 type InstanceNetworkInterfaceAttachment struct {
-	AttachmentID        string `xml:"attachmentID"`        // The ID of the network interface attachment.
-	DeviceIndex         int32  `xml:"deviceIndex"`         // The index of the device on the instance for the network interface attachment.
-	Status              string `xml:"status"`              // Valid values: attaching | attached | detaching | detached
-	AttachTime          string `xml:"attachTime"`          // Time attached, as a Datetime
-	DeleteOnTermination bool   `xml:"deleteOnTermination"` // Indicates whether the network interface is deleted when the instance is terminated.
+	AttachmentID        string `xml:"attachmentID" json:"attachmentId"`               // The ID of the network interface attachment.
+	DeviceIndex         int32  `xml:"deviceIndex" json:"deviceIndex"`                 // The index of the device on the instance for the network interface attachment.
+	Status              string `xml:"status" json:"status"`                           // Valid values: attaching | attached | detaching | detached
+	AttachTime          string `xml:"attachTime" json:"attachTime"`                   // Time attached, as a Datetime
+	DeleteOnTermination bool   `xml:"deleteOnTermination" json:"deleteOnTermination"` // Indicates whether the network interface is deleted when the instance is terminated.
 }
 
 // Describes association information for an Elastic IP address.
 // See http://goo.gl/YCDdMe for more details
 type InstanceNetworkInterfaceAssociation struct {
-	PublicIP      string `xml:"publicIp"`      // The address of the Elastic IP address bound to the network interface
-	PublicDNSName string `xml:"publicDnsName"` // The public DNS name
-	IPOwnerId     string `xml:"ipOwnerId"`     // The ID of the owner of the Elastic IP address
+	PublicIP      string `xml:"publicIp" json:"publicIp"`           // The address of the Elastic IP address bound to the network interface
+	PublicDNSName string `xml:"publicDnsName" json:"publicDnsName"` // The public DNS name
+	IPOwnerId     string `xml:"ipOwnerId" json:"ipOwnerId"`         // The ID of the owner of the Elastic IP address
 }
 
 // InstancePrivateIpAddress describes a private IP address
 // See http://goo.gl/irN646 for more details
 type InstancePrivateIpAddress struct {
-	PrivateIPAddress string                              `xml:"privateIpAddress"` // The private IP address of the network interface
-	PrivateDNSName   string                              `xml:"privateDnsName"`   // The private DNS name
-	Primary          bool                                `xml:"primary"`          // Indicates whether this IP address is the primary private IP address of the network interface
-	Association      InstanceNetworkInterfaceAssociation `xml:"association"`      // The association information for an Elastic IP address for the network interface
+	PrivateIPAddress string                              `xml:"privateIpAddress" json:"privateIpAddress"` // The private IP address of the network interface
+	PrivateDNSName   string                              `xml:"privateDnsName" json:"privateDnsName"`     // The private DNS name
+	Primary          bool                                `xml:"primary" json:"primary"`                   // Indicates whether this IP address is the primary private IP address of the network interface
+	Association      InstanceNetworkInterfaceAssociation `xml:"association" json:"association"`           // The association information for an Elastic IP address for the network interface
 }
 
 // IamInstanceProfile
 // See http://goo.gl/PjyijL for more details
 type IamInstanceProfile struct {
-	ARN  string `xml:"arn"`
-	Id   string `xml:"id"`
-	Name string `xml:"name"`
+	ARN  string `xml:"arn" json:"arn"`
+	Id   string `xml:"id" json:"id"`
+	Name string `xml:"name" json:"name"`
+}
+
+// addBlockDeviceMappings adds the BlockDeviceMapping.N parameters
+// describing mappings to params, with each name preceded by prefix.
+func addBlockDeviceMappings(params map[string]string, prefix string, mappings []BlockDeviceMapping) {
+	for i, d := range mappings {
+		bdm := prefix + "BlockDeviceMapping." + strconv.Itoa(i+1) + "."
+		if d.DeviceName != "" {
+			params[bdm+"DeviceName"] = d.DeviceName
+		}
+		if d.VirtualName != "" {
+			params[bdm+"VirtualName"] = d.VirtualName
+		}
+		if d.SnapshotId != "" {
+			params[bdm+"Ebs.SnapshotId"] = d.SnapshotId
+		}
+		if d.VolumeType != "" {
+			params[bdm+"Ebs.VolumeType"] = d.VolumeType
+		}
+		if d.VolumeSize != 0 {
+			params[bdm+"Ebs.VolumeSize"] = strconv.FormatInt(d.VolumeSize, 10)
+		}
+		if d.DeleteOnTermination {
+			params[bdm+"Ebs.DeleteOnTermination"] = "true"
+		}
+		if d.IOPS != 0 {
+			params[bdm+"Ebs.Iops"] = strconv.FormatInt(d.IOPS, 10)
+		}
+		if d.Throughput != 0 {
+			// Throughput is unknown to the default API version.
+			params["Version"] = newAPIVersion
+			params[bdm+"Ebs.Throughput"] = strconv.FormatInt(d.Throughput, 10)
+		}
+		if d.Encrypted {
+			params[bdm+"Ebs.Encrypted"] = "true"
+		}
+		if d.KmsKeyId != "" {
+			params[bdm+"Ebs.KmsKeyId"] = d.KmsKeyId
+		}
+		if d.NoDevice {
+			params[bdm+"NoDevice"] = ""
+		}
+	}
+}
+
+// addSecurityGroups adds the parameters selecting groups, by id if set
+// or else by name, to params, with each name preceded by prefix.
+func addSecurityGroups(params map[string]string, prefix string, groups []SecurityGroup) {
+	i, j := 1, 1
+	for _, g := range groups {
+		if g.Id != "" {
+			params[prefix+"SecurityGroupId."+strconv.Itoa(i)] = g.Id
+			i++
+		} else {
+			params[prefix+"SecurityGroup."+strconv.Itoa(j)] = g.Name
+			j++
+		}
+	}
+}
+
+// addNetworkInterfaces adds the NetworkInterface.N parameters describing
+// interfaces to params, with each name preceded by prefix.
+func addNetworkInterfaces(params map[string]string, prefix string, interfaces []NetworkInterface) {
+	for i, ni := range interfaces {
+		nip := prefix + "NetworkInterface." + strconv.Itoa(i+1) + "."
+		if ni.DeviceIndex != 0 {
+			params[nip+"DeviceIndex"] = strconv.Itoa(ni.DeviceIndex)
+		} else {
+			params[nip+"DeviceIndex"] = strconv.Itoa(i)
+		}
+		if ni.NetworkInterfaceId != "" {
+			params[nip+"NetworkInterfaceId"] = ni.NetworkInterfaceId
+		}
+		if ni.SubnetId != "" {
+			params[nip+"SubnetId"] = ni.SubnetId
+		}
+		if ni.Description != "" {
+			params[nip+"Description"] = ni.Description
+		}
+		if ni.AssociatePublicIpAddress {
+			params[nip+"AssociatePublicIpAddress"] = "true"
+		}
+		if ni.PrivateIpAddress != "" {
+			params[nip+"PrivateIpAddress"] = ni.PrivateIpAddress
+		}
+		if ni.SecurityGroups != nil {
+			for secId, g := range ni.SecurityGroups {
+				params[nip+"SecurityGroupId."+strconv.Itoa(secId+1)] = g.Id
+			}
+		}
+		if ni.DeleteOnTermination {
+			params[nip+"DeleteOnTermination"] = "true"
+		}
+		if ni.PrivateIpAddresses != nil {
+			for pId, addy := range ni.PrivateIpAddresses {
+				params[nip+"PrivateIpAddresses."+strconv.Itoa(pId+1)+".PrivateIpAddress"] = addy.PrivateIPAddress
+				if addy.Primary {
+					params[nip+"PrivateIpAddresses."+strconv.Itoa(pId+1)+".Primary"] = "true"
+				}
+			}
+		}
+		if ni.SecondaryPrivateIpAddressCount != 0 {
+			params[nip+"SecondaryPrivateIpAddressCount"] = strconv.Itoa(ni.SecondaryPrivateIpAddressCount)
+		}
+	}
+}
+
+// MaxUserDataSize is the largest UserData, before base64 encoding, that
+// EC2 accepts when launching instances.
+const MaxUserDataSize = 16 * 1024
+
+// ErrUserDataTooLarge is returned by RunInstances when options.UserData is
+// larger than MaxUserDataSize.
+var ErrUserDataTooLarge = errors.New("user data is larger than 16 KB")
+
+// GzipUserData compresses data with gzip. cloud-init transparently
+// decompresses gzipped user data, so compressing it lets larger
+// configurations fit within MaxUserDataSize. The result is meant to be
+// used as RunInstancesOptions.UserData.
+func GzipUserData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // RunInstances starts new instances in EC2.
@@ -403,11 +1246,33 @@ type IamInstanceProfile struct {
 // will be started; otherwise if options.MaxCount is zero, options.MinCount
 // will be used insteead.
 //
+// The request is sent in the body of a POST, as base64-encoded user data
+// may not fit in a URL.
+//
+// A request that failed or timed out may still have launched instances.
+// Callers that retry should set RunInstancesOptions.ClientToken
+// themselves, as a generated token is only returned on success.
+//
 // See http://goo.gl/Mcm3b for more details.
 func (ec2 *EC2) RunInstances(options *RunInstancesOptions) (resp *RunInstancesResp, err error) {
+	if len(options.UserData) > MaxUserDataSize {
+		return nil, ErrUserDataTooLarge
+	}
+	if err := validateBlockDeviceMappings(options.BlockDeviceMappings); err != nil {
+		return nil, err
+	}
 	params := makeParams("RunInstances")
-	params["ImageId"] = options.ImageId
-	params["InstanceType"] = options.InstanceType
+	if options.LaunchTemplate != nil {
+		params["Version"] = newAPIVersion
+		options.LaunchTemplate.addParams(params, "LaunchTemplate.")
+	}
+	// The image and instance type may be left to the launch template.
+	if options.ImageId != "" || options.LaunchTemplate == nil {
+		params["ImageId"] = options.ImageId
+	}
+	if options.InstanceType != "" || options.LaunchTemplate == nil {
+		params["InstanceType"] = options.InstanceType
+	}
 	var min, max int
 	if options.MinCount == 0 && options.MaxCount == 0 {
 		min = 1
@@ -421,46 +1286,16 @@ func (ec2 *EC2) RunInstances(options *RunInstancesOptions) (resp *RunInstancesRe
 	}
 	params["MinCount"] = strconv.Itoa(min)
 	params["MaxCount"] = strconv.Itoa(max)
-	i, j := 1, 1
-	for _, g := range options.SecurityGroups {
-		if g.Id != "" {
-			params["SecurityGroupId."+strconv.Itoa(i)] = g.Id
-			i++
-		} else {
-			params["SecurityGroup."+strconv.Itoa(j)] = g.Name
-			j++
-		}
-	}
+	addSecurityGroups(params, "", options.SecurityGroups)
 
-	for i, d := range options.BlockDeviceMappings {
-		if d.DeviceName != "" {
-			params["BlockDeviceMapping."+strconv.Itoa(i)+".DeviceName"] = d.DeviceName
-		}
-		if d.VirtualName != "" {
-			params["BlockDeviceMapping."+strconv.Itoa(i)+".VirtualName"] = d.VirtualName
-		}
-		if d.SnapshotId != "" {
-			params["BlockDeviceMapping."+strconv.Itoa(i)+".Ebs.SnapshotId"] = d.SnapshotId
-		}
-		if d.VolumeType != "" {
-			params["BlockDeviceMapping."+strconv.Itoa(i)+".Ebs.VolumeType"] = d.VolumeType
-		}
-		if d.VolumeSize != 0 {
-			params["BlockDeviceMapping."+strconv.Itoa(i)+".Ebs.VolumeSize"] = strconv.FormatInt(d.VolumeSize, 10)
-		}
-		if d.DeleteOnTermination {
-			params["BlockDeviceMapping."+strconv.Itoa(i)+".Ebs.DeleteOnTermination"] = "true"
-		}
-		if d.IOPS != 0 {
-			params["BlockDeviceMapping."+strconv.Itoa(i)+".Ebs.Iops"] = strconv.FormatInt(d.IOPS, 10)
-		}
-	}
+	addBlockDeviceMappings(params, "", options.BlockDeviceMappings)
 
-	token, err := clientToken()
+	token, err := orClientToken(options.ClientToken)
 	if err != nil {
 		return nil, err
 	}
 	params["ClientToken"] = token
+	addDryRun(params, options.DryRun)
 
 	if options.KeyName != "" {
 		params["KeyName"] = options.KeyName
@@ -510,45 +1345,21 @@ func (ec2 *EC2) RunInstances(options *RunInstancesOptions) (resp *RunInstancesRe
 		params["EbsOptimized"] = "true"
 	}
 
-	if options.NetworkInterfaces != nil {
-		for i, ni := range options.NetworkInterfaces {
-			prefix := fmt.Sprintf("NetworkInterface.%d.", i+1)
-			params[prefix+"DeviceIndex"] = strconv.Itoa(i)
-			if ni.SubnetId != "" {
-				params[prefix+"SubnetId"] = ni.SubnetId
-			}
-			if ni.Description != "" {
-				params[prefix+"Description"] = ni.Description
-			}
-			if ni.AssociatePublicIpAddress {
-				params[prefix+"AssociatePublicIpAddress"] = "true"
-			}
-			if ni.PrivateIpAddress != "" {
-				params[prefix+"PrivateIpAddress"] = ni.PrivateIpAddress
-			}
-			if ni.SecurityGroups != nil {
-				for secId, g := range ni.SecurityGroups {
-					params[prefix+"SecurityGroupId."+strconv.Itoa(secId+1)] = g.Id
-				}
-			}
-			if ni.DeleteOnTermination {
-				params[prefix+"DeleteOnTermination"] = "true"
-			}
-			if ni.PrivateIpAddresses != nil {
-				for pId, addy := range ni.PrivateIpAddresses {
-					params[prefix+"PrivateIpAddresses."+strconv.Itoa(pId+1)+".PrivateIpAddress"] = addy.PrivateIPAddress
-					if addy.Primary {
-						params[prefix+"PrivateIpAddresses."+strconv.Itoa(pId+1)+".Primary"] = "true"
-					}
-				}
-			}
-		}
+	addNetworkInterfaces(params, "", options.NetworkInterfaces)
+	if options.InstanceMarketOptions != nil {
+		params["Version"] = newAPIVersion
+		options.InstanceMarketOptions.addParams(params, "")
+	}
+	if len(options.TagSpecifications) > 0 {
+		params["Version"] = newAPIVersion
+		addTagSpecifications(params, "", options.TagSpecifications)
 	}
 	resp = &RunInstancesResp{}
-	err = ec2.query(params, resp)
+	err = ec2.post(params, resp)
 	if err != nil {
 		return nil, err
 	}
+	resp.ClientToken = token
 	return
 }
 
@@ -563,6 +1374,18 @@ func clientToken() (string, error) {
 	return hex.EncodeToString(buf), nil
 }
 
+// orClientToken returns token, or a newly generated client token if token
+// is empty. Mutating calls that accept a ClientToken use it so that every
+// request is idempotent. A generated token is only returned in the
+// response on success, so callers that want to retry a failed request
+// safely should set the token themselves.
+func orClientToken(token string) (string, error) {
+	if token != "" {
+		return token, nil
+	}
+	return clientToken()
+}
+
 // Response to a TerminateInstances request.
 //
 // See http://goo.gl/3BKHj for more details.
@@ -575,64 +1398,162 @@ type TerminateInstancesResp struct {
 //
 // See http://goo.gl/y3ZBq for more details.
 type InstanceState struct {
-	Code int    `xml:"code"` // Watch out, bits 15-8 have unpublished meaning.
-	Name string `xml:"name"`
+	Code int    `xml:"code" json:"code"` // Watch out, bits 15-8 have unpublished meaning.
+	Name string `xml:"name" json:"name"`
+}
+
+// Instance state codes, as returned by InstanceState.Normalized.
+const (
+	InstanceStatePending      = 0
+	InstanceStateRunning      = 16
+	InstanceStateShuttingDown = 32
+	InstanceStateTerminated   = 48
+	InstanceStateStopping     = 64
+	InstanceStateStopped      = 80
+)
+
+// Normalized returns the state code with the unpublished high bits masked
+// off, so that it may be compared with the InstanceState constants.
+func (s InstanceState) Normalized() int {
+	return s.Code & 0xff
 }
 
 // InstanceStateChange informs of the previous and current states
-// for an instance when a state change is requested.
+// for an instance when a state change is requested. StateReason
+// explains why a transition did not happen, if EC2 reports it.
 type InstanceStateChange struct {
-	InstanceId    string        `xml:"instanceId"`
-	CurrentState  InstanceState `xml:"currentState"`
-	PreviousState InstanceState `xml:"previousState"`
+	InstanceId    string              `xml:"instanceId"`
+	CurrentState  InstanceState       `xml:"currentState"`
+	PreviousState InstanceState       `xml:"previousState"`
+	StateReason   InstanceStateReason `xml:"stateReason"`
 }
 
 // InstanceStateReason describes a state change for an instance in EC2
 //
 // See http://goo.gl/KZkbXi for more details
 type InstanceStateReason struct {
-	Code    string `xml:"code"`
-	Message string `xml:"message"`
+	Code    string `xml:"code" json:"code"`
+	Message string `xml:"message" json:"message"`
 }
 
-// TerminateInstances requests the termination of instances when the given ids.
-//
-// See http://goo.gl/3BKHj for more details.
+// InstancesNotFoundError is returned by TerminateInstances, along with the
+// state changes of the other instances, when some of the given instances
+// don't exist.
+type InstancesNotFoundError struct {
+	InstanceIds []string // The ids of the instances which don't exist
+	Err         *Error   // The InvalidInstanceID.NotFound error from EC2
+}
+
+func (err *InstancesNotFoundError) Error() string {
+	return err.Err.Error()
+}
+
+// TerminateInstances requests the termination of instances when the given ids.
+//
+// EC2 rejects the whole request if any of the instances doesn't exist. In
+// that case the request is made again without those instances, and the
+// response is returned along with an *InstancesNotFoundError listing them.
+// If none of the instances exist, the *Error from EC2 is returned as is.
+//
+// See http://goo.gl/3BKHj for more details.
 func (ec2 *EC2) TerminateInstances(instIds []string) (resp *TerminateInstancesResp, err error) {
-	params := makeParams("TerminateInstances")
-	addParamsList(params, "InstanceId", instIds)
-	resp = &TerminateInstancesResp{}
-	err = ec2.query(params, resp)
-	if err != nil {
-		return nil, err
+	var notFound *InstancesNotFoundError
+	for {
+		params := makeParams("TerminateInstances")
+		addParamsList(params, "InstanceId", instIds)
+		resp = &TerminateInstancesResp{}
+		err = ec2.query(params, resp)
+		if err == nil {
+			break
+		}
+		ec2err, ok := err.(*Error)
+		if !ok || ec2err.Code != "InvalidInstanceID.NotFound" {
+			return nil, err
+		}
+		missing := notFoundInstanceIds(ec2err.Message)
+		remaining := removeIds(instIds, missing)
+		if len(remaining) == len(instIds) {
+			return nil, err
+		}
+		if notFound == nil {
+			notFound = &InstancesNotFoundError{Err: ec2err}
+		}
+		notFound.InstanceIds = append(notFound.InstanceIds, missing...)
+		instIds = remaining
+		if len(instIds) == 0 {
+			return nil, notFound.Err
+		}
 	}
-	return
+	if notFound != nil {
+		return resp, notFound
+	}
+	return resp, nil
+}
+
+// notFoundInstanceIds returns the instance ids quoted in the message of
+// an InvalidInstanceID.NotFound error, such as
+// "The instance IDs 'i-1, i-2' do not exist".
+func notFoundInstanceIds(message string) []string {
+	i := strings.Index(message, "'")
+	j := strings.LastIndex(message, "'")
+	if i < 0 || j <= i {
+		return nil
+	}
+	var ids []string
+	for _, id := range strings.Split(message[i+1:j], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// removeIds returns the ids which aren't in removed.
+func removeIds(ids, removed []string) []string {
+	var kept []string
+	for _, id := range ids {
+		found := false
+		for _, r := range removed {
+			if id == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, id)
+		}
+	}
+	return kept
 }
 
 // Response to a DescribeAddresses request.
 //
 // See http://goo.gl/zW7J4p for more details.
 type DescribeAddressesResp struct {
-	RequestId string    `xml:"requestId"`
-	Addresses []Address `xml:"addressesSet>item"`
+	RequestId string    `xml:"requestId" json:"requestId"`
+	Addresses []Address `xml:"addressesSet>item" json:"addresses"`
 }
 
 // Address represents an Elastic IP Address
 // See http://goo.gl/uxCjp7 for more details
 type Address struct {
-	PublicIp                string `xml:"publicIp"`
-	AllocationId            string `xml:"allocationId"`
-	Domain                  string `xml:"domain"`
-	InstanceId              string `xml:"instanceId"`
-	AssociationId           string `xml:"associationId"`
-	NetworkInterfaceId      string `xml:"networkInterfaceId"`
-	NetworkInterfaceOwnerId string `xml:"networkInterfaceOwnerId"`
-	PrivateIpAddress        string `xml:"privateIpAddress"`
+	PublicIp                string `xml:"publicIp" json:"publicIp"`
+	AllocationId            string `xml:"allocationId" json:"allocationId"`
+	Domain                  string `xml:"domain" json:"domain"`
+	InstanceId              string `xml:"instanceId" json:"instanceId"`
+	AssociationId           string `xml:"associationId" json:"associationId"`
+	NetworkInterfaceId      string `xml:"networkInterfaceId" json:"networkInterfaceId"`
+	NetworkInterfaceOwnerId string `xml:"networkInterfaceOwnerId" json:"networkInterfaceOwnerId"`
+	PrivateIpAddress        string `xml:"privateIpAddress" json:"privateIpAddress"`
 }
 
 // DescribeAddresses returns details about one or more
 // Elastic IP Addresses. Returned addresses can be
-// filtered by Public IP, Allocation ID or multiple filters
+// filtered by Public IP, Allocation ID or multiple filters.
+// VPC addresses are best looked up by allocation id, and
+// their association and network interface details are
+// included in the response. To find the addresses of an
+// instance, use the "instance-id" filter.
 //
 // See http://goo.gl/zW7J4p for more details.
 func (ec2 *EC2) DescribeAddresses(publicIps []string, allocationIds []string, filter *Filter) (resp *DescribeAddressesResp, err error) {
@@ -648,6 +1569,16 @@ func (ec2 *EC2) DescribeAddresses(publicIps []string, allocationIds []string, fi
 	return
 }
 
+// AddressesForInterface returns the Elastic IP addresses associated with
+// the network interface with the given id.
+//
+// See http://goo.gl/zW7J4p for more details.
+func (ec2 *EC2) AddressesForInterface(networkInterfaceId string) (resp *DescribeAddressesResp, err error) {
+	filter := NewFilter()
+	filter.Add("network-interface-id", networkInterfaceId)
+	return ec2.DescribeAddresses(nil, nil, filter)
+}
+
 //Response to an AllocateAddress request
 //
 // See  http://goo.gl/aLPmbm for more details
@@ -717,6 +1648,7 @@ type AssociateAddressOptions struct {
 	NetworkInterfaceId string
 	PrivateIpAddress   string
 	AllowReassociation bool
+	DryRun             bool
 }
 
 // Response to an AssociateAddress request
@@ -749,6 +1681,7 @@ func (ec2 *EC2) AssociateAddress(options *AssociateAddressOptions) (resp *Associ
 	if options.AllowReassociation {
 		params["AllowReassociation"] = "true"
 	}
+	addDryRun(params, options.DryRun)
 
 	resp = &AssociateAddressResp{}
 	err = ec2.query(params, resp)
@@ -791,19 +1724,38 @@ func (ec2 *EC2) DiassociateAddress(publicIp, associationId string) (resp *Diasso
 //
 // See http://goo.gl/mLbmw for more details.
 type DescribeInstancesResp struct {
-	RequestId    string        `xml:"requestId"`
-	Reservations []Reservation `xml:"reservationSet>item"`
+	RequestId    string        `xml:"requestId" json:"requestId"`
+	Reservations []Reservation `xml:"reservationSet>item" json:"reservations"`
+	NextToken    string        `xml:"nextToken" json:"nextToken,omitempty"`
 }
 
 // Reservation represents details about a reservation in EC2.
 //
 // See http://goo.gl/0ItPT for more details.
 type Reservation struct {
-	ReservationId  string          `xml:"reservationId"`
-	OwnerId        string          `xml:"ownerId"`
-	RequesterId    string          `xml:"requesterId"`
-	SecurityGroups []SecurityGroup `xml:"groupSet>item"`
-	Instances      []Instance      `xml:"instancesSet>item"`
+	ReservationId  string          `xml:"reservationId" json:"reservationId"`
+	OwnerId        string          `xml:"ownerId" json:"ownerId"`
+	RequesterId    string          `xml:"requesterId" json:"requesterId"`
+	SecurityGroups []SecurityGroup `xml:"groupSet>item" json:"securityGroups"`
+	Instances      []Instance      `xml:"instancesSet>item" json:"instances"`
+}
+
+// AllInstances returns the instances of all reservations in r as a single
+// list. The reservation's OwnerId and RequesterId are copied to each
+// instance, as are its security groups unless the instance lists its own.
+func (r *DescribeInstancesResp) AllInstances() []Instance {
+	var instances []Instance
+	for _, rsv := range r.Reservations {
+		for _, inst := range rsv.Instances {
+			inst.OwnerId = rsv.OwnerId
+			inst.RequesterId = rsv.RequesterId
+			if len(inst.SecurityGroups) == 0 {
+				inst.SecurityGroups = rsv.SecurityGroups
+			}
+			instances = append(instances, inst)
+		}
+	}
+	return instances
 }
 
 // Instances returns details about instances in EC2.  Both parameters
@@ -821,16 +1773,346 @@ func (ec2 *EC2) DescribeInstances(instIds []string, filter *Filter) (resp *Descr
 		return nil, err
 	}
 
-	// Add additional parameters to instances which aren't available in the response
-	for i, rsv := range resp.Reservations {
-		ownerId := rsv.OwnerId
-		for j, inst := range rsv.Instances {
-			inst.OwnerId = ownerId
-			resp.Reservations[i].Instances[j] = inst
+	resp.setReservationIds()
+	return
+}
+
+// setReservationIds copies the OwnerId and RequesterId of each reservation,
+// which aren't given per instance in the response, to its instances.
+func (r *DescribeInstancesResp) setReservationIds() {
+	for i, rsv := range r.Reservations {
+		for j := range rsv.Instances {
+			r.Reservations[i].Instances[j].OwnerId = rsv.OwnerId
+			r.Reservations[i].Instances[j].RequesterId = rsv.RequesterId
+		}
+	}
+}
+
+// DescribeInstancesPagesOptions encapsulates options for the
+// DescribeInstancesPages call. All fields are optional.
+type DescribeInstancesPagesOptions struct {
+	InstanceIds []string
+	Filter      *Filter
+
+	// MaxResults is the number of instances EC2 returns per page, between
+	// 5 and 1000. It can't be combined with InstanceIds.
+	MaxResults int
+
+	// Limit stops the iteration once Limit instances have been passed on,
+	// even if more pages exist. The last page is cut short if needed.
+	Limit int
+}
+
+// DescribeInstancesPages is like DescribeInstances, but requests the
+// instances a page at a time and calls fn with each page. If fn returns
+// an error, no further pages are requested and that error is returned.
+// opts may be nil.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstances.html for more details.
+func (ec2 *EC2) DescribeInstancesPages(opts *DescribeInstancesPagesOptions, fn func(page *DescribeInstancesResp) error) error {
+	if opts == nil {
+		opts = &DescribeInstancesPagesOptions{}
+	}
+	maxResults := opts.MaxResults
+	if maxResults == 0 && opts.Limit > 0 && len(opts.InstanceIds) == 0 {
+		// Don't fetch pages much larger than the limit.
+		maxResults = opts.Limit
+		if maxResults < 5 {
+			maxResults = 5
+		} else if maxResults > 1000 {
+			maxResults = 1000
 		}
 	}
+	remaining := opts.Limit
+	nextToken := ""
+	for {
+		params := makeParams("DescribeInstances")
+		params["Version"] = newAPIVersion
+		addParamsList(params, "InstanceId", opts.InstanceIds)
+		opts.Filter.addParams(params)
+		if maxResults > 0 {
+			params["MaxResults"] = strconv.Itoa(maxResults)
+		}
+		if nextToken != "" {
+			params["NextToken"] = nextToken
+		}
 
-	return
+		page := &DescribeInstancesResp{}
+		if err := ec2.query(params, page); err != nil {
+			return err
+		}
+		page.setReservationIds()
+		if opts.Limit > 0 {
+			remaining = page.truncate(remaining)
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if page.NextToken == "" || (opts.Limit > 0 && remaining == 0) {
+			return nil
+		}
+		nextToken = page.NextToken
+	}
+}
+
+// truncate drops the instances of r beyond the first n, along with any
+// reservations left empty. It returns how many more instances may follow.
+func (r *DescribeInstancesResp) truncate(n int) int {
+	for i, rsv := range r.Reservations {
+		if len(rsv.Instances) >= n {
+			r.Reservations[i].Instances = rsv.Instances[:n]
+			if n == 0 {
+				r.Reservations = r.Reservations[:i]
+			} else {
+				r.Reservations = r.Reservations[:i+1]
+			}
+			return 0
+		}
+		n -= len(rsv.Instances)
+	}
+	return n
+}
+
+// DescribeInstancesStream is like DescribeInstances, but calls fn with
+// each instance as the response is decoded instead of holding every
+// instance in memory. Instances are passed on once their reservation has
+// been decoded, so that their OwnerId and RequesterId can be set from it.
+// If fn returns an error, decoding stops and that error is returned.
+//
+// If OnResponse is set, the raw response is read into memory in full
+// before it is decoded, though instances are still decoded and passed to
+// fn one at a time.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstances.html for more details.
+func (ec2 *EC2) DescribeInstancesStream(instIds []string, filter *Filter, fn func(Instance) error) error {
+	params := makeParams("DescribeInstances")
+	addParamsList(params, "InstanceId", instIds)
+	filter.addParams(params)
+	return ec2.query(params, &instanceStream{fn: fn})
+}
+
+// instanceStream decodes a DescribeInstances response one reservation at
+// a time, passing its instances to fn.
+type instanceStream struct {
+	fn func(Instance) error
+}
+
+func (s *instanceStream) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var path []string
+	var rsv Reservation
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var field interface{}
+			switch strings.Join(append(path, t.Name.Local), ">") {
+			case "reservationSet>item":
+				rsv = Reservation{}
+			case "reservationSet>item>ownerId":
+				field = &rsv.OwnerId
+			case "reservationSet>item>requesterId":
+				field = &rsv.RequesterId
+			case "reservationSet>item>instancesSet>item":
+				rsv.Instances = append(rsv.Instances, Instance{})
+				field = &rsv.Instances[len(rsv.Instances)-1]
+			}
+			if field != nil {
+				if err := d.DecodeElement(field, &t); err != nil {
+					return err
+				}
+				continue
+			}
+			path = append(path, t.Name.Local)
+		case xml.EndElement:
+			if len(path) == 0 {
+				return nil
+			}
+			if strings.Join(path, ">") == "reservationSet>item" {
+				for _, inst := range rsv.Instances {
+					inst.OwnerId = rsv.OwnerId
+					inst.RequesterId = rsv.RequesterId
+					if err := s.fn(inst); err != nil {
+						return err
+					}
+				}
+			}
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+// ConsistencyOptions encapsulates options for the
+// DescribeInstancesEventuallyConsistent call.
+type ConsistencyOptions struct {
+	// Timeout bounds the total time spent waiting between attempts. It
+	// defaults to one minute.
+	Timeout time.Duration
+
+	// BaseDelay is the delay before the first retry, doubled before each
+	// further retry up to MaxDelay. They default to 500 milliseconds and
+	// 5 seconds.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DescribeInstancesEventuallyConsistent is like DescribeInstances, but
+// retries the request while EC2 reports any of the instances as not
+// found, as it may for a little while after they were launched with
+// RunInstances. opts may be nil.
+func (ec2 *EC2) DescribeInstancesEventuallyConsistent(instIds []string, filter *Filter, opts *ConsistencyOptions) (resp *DescribeInstancesResp, err error) {
+	timeout, delay, max := time.Minute, 500*time.Millisecond, 5*time.Second
+	if opts != nil {
+		if opts.Timeout > 0 {
+			timeout = opts.Timeout
+		}
+		if opts.BaseDelay > 0 {
+			delay = opts.BaseDelay
+		}
+		if opts.MaxDelay > 0 {
+			max = opts.MaxDelay
+		}
+	}
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		resp, err = ec2.DescribeInstances(instIds, filter)
+		if !IsErrorCode(err, "InvalidInstanceID.NotFound") {
+			return resp, err
+		}
+		if delay > max {
+			delay = max
+		}
+		if waited+delay > timeout {
+			return nil, err
+		}
+		if ec2.OnRetry != nil {
+			ec2.OnRetry("DescribeInstances", attempt+1, err)
+		}
+		sleep(delay)
+		waited += delay
+		delay *= 2
+	}
+}
+
+// InstancesByTag returns details about the instances tagged with the given
+// key and value, for example InstancesByTag("Name", "web-1").
+func (ec2 *EC2) InstancesByTag(key, value string) (resp *DescribeInstancesResp, err error) {
+	filter := NewFilter()
+	filter.Add("tag:"+key, value)
+	return ec2.DescribeInstances(nil, filter)
+}
+
+// RunningInstances returns details about the running instances which match
+// filter, which may be nil.
+func (ec2 *EC2) RunningInstances(filter *Filter) (resp *DescribeInstancesResp, err error) {
+	return ec2.DescribeInstances(nil, withFilterValue(filter, "instance-state-name", "running"))
+}
+
+// StoppedInstances returns details about the stopped instances which match
+// filter, which may be nil.
+func (ec2 *EC2) StoppedInstances(filter *Filter) (resp *DescribeInstancesResp, err error) {
+	return ec2.DescribeInstances(nil, withFilterValue(filter, "instance-state-name", "stopped"))
+}
+
+// SpotInstances returns details about the spot instances which match
+// filter, which may be nil. Terminated spot instances are only returned
+// for a short while after their termination.
+func (ec2 *EC2) SpotInstances(filter *Filter) (resp *DescribeInstancesResp, err error) {
+	return ec2.DescribeInstances(nil, withFilterValue(filter, "instance-lifecycle", "spot"))
+}
+
+// WatchInstances polls the instances with the given ids every interval
+// and sends a state change on the returned channel whenever the state of
+// one of them differs from the previous poll. If ids is empty, every
+// instance is watched. Failed polls are skipped. Calling the returned
+// function stops polling, after which the channel is closed.
+//
+// The instances are selected with an instance-id filter rather than by
+// id, so that an instance which no longer exists, such as one terminated
+// a while ago, doesn't make every poll fail.
+func (ec2 *EC2) WatchInstances(ids []string, interval time.Duration) (<-chan InstanceStateChange, func()) {
+	var filter *Filter
+	if len(ids) > 0 {
+		filter = NewFilter()
+		filter.Add("instance-id", ids...)
+	}
+	changes := make(chan InstanceStateChange)
+	stop := make(chan struct{})
+	var once sync.Once
+	go func() {
+		defer close(changes)
+		states := make(map[string]InstanceState)
+		for {
+			if resp, err := ec2.DescribeInstances(nil, filter); err == nil {
+				for _, rsv := range resp.Reservations {
+					for _, inst := range rsv.Instances {
+						previous, seen := states[inst.InstanceId]
+						states[inst.InstanceId] = inst.State
+						if !seen || previous.Name == inst.State.Name {
+							continue
+						}
+						change := InstanceStateChange{
+							InstanceId:    inst.InstanceId,
+							CurrentState:  inst.State,
+							PreviousState: previous,
+							StateReason:   inst.StateReason,
+						}
+						select {
+						case changes <- change:
+						case <-stop:
+							return
+						}
+					}
+				}
+			}
+			select {
+			case <-time.After(interval):
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return changes, func() { once.Do(func() { close(stop) }) }
+}
+
+// withFilterValue returns a copy of filter, which is left unchanged, with
+// its entry for name, if any, replaced by value.
+func withFilterValue(filter *Filter, name, value string) *Filter {
+	f := NewFilter()
+	if filter != nil {
+		for k, values := range filter.m {
+			f.m[k] = append([]string(nil), values...)
+		}
+	}
+	f.m[name] = []string{value}
+	return f
+}
+
+// ErrInstanceNotFound is returned by Instance when no instance has the
+// requested id.
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// Instance returns the instance with the given id. ErrInstanceNotFound is
+// returned if there is no such instance, including when EC2 reports the
+// id as unknown with an InvalidInstanceID.NotFound error.
+func (ec2 *EC2) Instance(id string) (*Instance, error) {
+	resp, err := ec2.DescribeInstances([]string{id}, nil)
+	if err != nil {
+		if IsErrorCode(err, "InvalidInstanceID.NotFound") {
+			return nil, ErrInstanceNotFound
+		}
+		return nil, err
+	}
+	instances := resp.AllInstances()
+	switch len(instances) {
+	case 0:
+		return nil, ErrInstanceNotFound
+	case 1:
+		return &instances[0], nil
+	}
+	return nil, fmt.Errorf("%d instances found with id %q", len(instances), id)
 }
 
 // ----------------------------------------------------------------------------
@@ -840,50 +2122,104 @@ func (ec2 *EC2) DescribeInstances(instIds []string, filter *Filter) (resp *Descr
 //
 // See http://goo.gl/hLnyg for more details.
 type ImagesResp struct {
-	RequestId string  `xml:"requestId"`
-	Images    []Image `xml:"imagesSet>item"`
+	RequestId string  `xml:"requestId" json:"requestId"`
+	Images    []Image `xml:"imagesSet>item" json:"images"`
 }
 
 // BlockDeviceMapping represents the association of a block device with an image.
 //
 // See http://goo.gl/wnDBf for more details.
 type BlockDeviceMapping struct {
-	DeviceName          string `xml:"deviceName"`
-	VirtualName         string `xml:"virtualName"`
-	SnapshotId          string `xml:"ebs>snapshotId"`
-	VolumeType          string `xml:"ebs>volumeType"`
-	VolumeSize          int64  `xml:"ebs>volumeSize"`
-	DeleteOnTermination bool   `xml:"ebs>deleteOnTermination"`
+	DeviceName          string `xml:"deviceName" json:"deviceName"`
+	VirtualName         string `xml:"virtualName" json:"virtualName"`
+	SnapshotId          string `xml:"ebs>snapshotId" json:"snapshotId"`
+	VolumeType          string `xml:"ebs>volumeType" json:"volumeType"`
+	VolumeSize          int64  `xml:"ebs>volumeSize" json:"volumeSize"`
+	DeleteOnTermination bool   `xml:"ebs>deleteOnTermination" json:"deleteOnTermination"`
 
 	// The number of I/O operations per second (IOPS) that the volume supports.
-	IOPS int64 `xml:"ebs>iops"`
+	IOPS int64 `xml:"ebs>iops" json:"iops"`
+
+	// The throughput, in MiB/s, of a gp3 volume.
+	Throughput int64 `xml:"ebs>throughput" json:"throughput"`
+
+	// Encrypted requests an encrypted volume, using the KMS key with the
+	// ARN or id KmsKeyId or, if that is empty, the default EBS key.
+	Encrypted bool   `xml:"ebs>encrypted" json:"encrypted"`
+	KmsKeyId  string `xml:"ebs>kmsKeyId" json:"kmsKeyId"`
+
+	// NoDevice suppresses the mapping of DeviceName from the image when
+	// launching an instance. It is not decoded from responses.
+	NoDevice bool `xml:"-" json:"noDevice,omitempty"`
+}
+
+// EBS volume types.
+const (
+	VolumeTypeStandard = "standard"
+	VolumeTypeGp2      = "gp2"
+	VolumeTypeGp3      = "gp3"
+	VolumeTypeIo1      = "io1"
+	VolumeTypeIo2      = "io2"
+	VolumeTypeSt1      = "st1"
+	VolumeTypeSc1      = "sc1"
+)
+
+// validateVolumeType checks that volumeType, if set, is one of the
+// VolumeType constants and that IOPS are only given for volume types
+// which support provisioning them.
+func validateVolumeType(volumeType string, iops int64) error {
+	switch volumeType {
+	case "":
+		return nil
+	case VolumeTypeGp3, VolumeTypeIo1, VolumeTypeIo2:
+		return nil
+	case VolumeTypeStandard, VolumeTypeGp2, VolumeTypeSt1, VolumeTypeSc1:
+		if iops > 0 {
+			return fmt.Errorf("IOPS can't be set for %s volumes", volumeType)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid volume type %q", volumeType)
+}
+
+func validateBlockDeviceMappings(mappings []BlockDeviceMapping) error {
+	for _, d := range mappings {
+		if err := validateVolumeType(d.VolumeType, d.IOPS); err != nil {
+			return fmt.Errorf("block device %s: %v", d.DeviceName, err)
+		}
+	}
+	return nil
 }
 
 // Image represents details about an image.
 //
 // See http://goo.gl/iSqJG for more details.
 type Image struct {
-	Id                 string               `xml:"imageId"`
-	Name               string               `xml:"name"`
-	Description        string               `xml:"description"`
-	Type               string               `xml:"imageType"`
-	State              string               `xml:"imageState"`
-	Location           string               `xml:"imageLocation"`
-	Public             bool                 `xml:"isPublic"`
-	Architecture       string               `xml:"architecture"`
-	Platform           string               `xml:"platform"`
-	ProductCodes       []string             `xml:"productCode>item>productCode"`
-	KernelId           string               `xml:"kernelId"`
-	RamdiskId          string               `xml:"ramdiskId"`
-	StateReason        string               `xml:"stateReason"`
-	OwnerId            string               `xml:"imageOwnerId"`
-	OwnerAlias         string               `xml:"imageOwnerAlias"`
-	RootDeviceType     string               `xml:"rootDeviceType"`
-	RootDeviceName     string               `xml:"rootDeviceName"`
-	VirtualizationType string               `xml:"virtualizationType"`
-	Tags               []Tag                `xml:"tagSet>item"`
-	Hypervisor         string               `xml:"hypervisor"`
-	BlockDevices       []BlockDeviceMapping `xml:"blockDeviceMapping>item"`
+	Id                 string               `xml:"imageId" json:"id"`
+	Name               string               `xml:"name" json:"name"`
+	Description        string               `xml:"description" json:"description"`
+	Type               string               `xml:"imageType" json:"type"`
+	State              string               `xml:"imageState" json:"state"`
+	Location           string               `xml:"imageLocation" json:"location"`
+	Public             bool                 `xml:"isPublic" json:"public"`
+	Architecture       string               `xml:"architecture" json:"architecture"`
+	Platform           string               `xml:"platform" json:"platform"`
+	ProductCodes       []string             `xml:"productCode>item>productCode" json:"productCodes"`
+	KernelId           string               `xml:"kernelId" json:"kernelId"`
+	RamdiskId          string               `xml:"ramdiskId" json:"ramdiskId"`
+	StateReason        string               `xml:"stateReason" json:"stateReason"`
+	OwnerId            string               `xml:"imageOwnerId" json:"ownerId"`
+	OwnerAlias         string               `xml:"imageOwnerAlias" json:"ownerAlias"`
+	RootDeviceType     string               `xml:"rootDeviceType" json:"rootDeviceType"`
+	RootDeviceName     string               `xml:"rootDeviceName" json:"rootDeviceName"`
+	VirtualizationType string               `xml:"virtualizationType" json:"virtualizationType"`
+	Tags               []Tag                `xml:"tagSet>item" json:"tags"`
+	Hypervisor         string               `xml:"hypervisor" json:"hypervisor"`
+	BlockDevices       []BlockDeviceMapping `xml:"blockDeviceMapping>item" json:"blockDevices"`
+	CreationDate       string               `xml:"creationDate" json:"creationDate"`
+	EnaSupport         bool                 `xml:"enaSupport" json:"enaSupport"`
+	SriovNetSupport    string               `xml:"sriovNetSupport" json:"sriovNetSupport"`
+	DeprecationTime    string               `xml:"deprecationTime" json:"deprecationTime"`
 }
 
 // Images returns details about available images.
@@ -892,15 +2228,46 @@ type Image struct {
 // the boolean filter "is-private" to true.
 //
 // Note: calling this function with nil ids and filter parameters will result in
-// a very large number of images being returned.
+// a very large number of images being returned. Use ImagesWithOptions to
+// restrict the images to those of given owners.
 //
 // See http://goo.gl/SRBhW for more details.
 func (ec2 *EC2) Images(ids []string, filter *Filter) (resp *ImagesResp, err error) {
+	return ec2.ImagesWithOptions(&ImagesOptions{ImageIds: ids, Filter: filter})
+}
+
+// ImagesOptions encapsulates options for the ImagesWithOptions call.
+// All fields are optional.
+type ImagesOptions struct {
+	ImageIds []string
+
+	// Owners limits the images to those owned by the given account ids,
+	// or by "self", "amazon" or "aws-marketplace".
+	Owners []string
+
+	// ExecutableBy limits the images to those with launch permissions
+	// for the given account ids, "self" or "all".
+	ExecutableBy []string
+
+	Filter *Filter
+}
+
+// ImagesWithOptions returns details about available images, like Images,
+// but also supports the Owner and ExecutableBy parameters. opts may be
+// nil.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeImages.html for more details.
+func (ec2 *EC2) ImagesWithOptions(opts *ImagesOptions) (resp *ImagesResp, err error) {
+	if opts == nil {
+		opts = &ImagesOptions{}
+	}
 	params := makeParams("DescribeImages")
-	for i, id := range ids {
+	for i, id := range opts.ImageIds {
 		params["ImageId."+strconv.Itoa(i+1)] = id
 	}
-	filter.addParams(params)
+	addParamsList(params, "Owner", opts.Owners)
+	addParamsList(params, "ExecutableBy", opts.ExecutableBy)
+	opts.Filter.addParams(params)
 
 	resp = &ImagesResp{}
 	err = ec2.query(params, resp)
@@ -910,6 +2277,37 @@ func (ec2 *EC2) Images(ids []string, filter *Filter) (resp *ImagesResp, err erro
 	return
 }
 
+// ErrImageNotFound is returned by LatestImage when no image matches.
+var ErrImageNotFound = errors.New("image not found")
+
+// LatestImage returns the most recently created of the images owned by
+// owners whose name matches namePattern, which may contain the * and ?
+// wildcards. For example, LatestImage([]string{"self"}, "myapp-*").
+// ErrImageNotFound is returned if no image matches.
+func (ec2 *EC2) LatestImage(owners []string, namePattern string) (*Image, error) {
+	filter := NewFilter()
+	filter.Add("name", namePattern)
+	resp, err := ec2.ImagesWithOptions(&ImagesOptions{Owners: owners, Filter: filter})
+	if err != nil {
+		return nil, err
+	}
+	var latest *Image
+	var latestDate time.Time
+	for i := range resp.Images {
+		date, err := time.Parse(time.RFC3339, resp.Images[i].CreationDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creation date of image %s: %v", resp.Images[i].Id, err)
+		}
+		if latest == nil || date.After(latestDate) {
+			latest, latestDate = &resp.Images[i], date
+		}
+	}
+	if latest == nil {
+		return nil, ErrImageNotFound
+	}
+	return latest, nil
+}
+
 type CreateImageResp struct {
 	RequestId string `xml:"requestId"`
 	ImageId   string `xml:"imageId"`
@@ -940,11 +2338,38 @@ func (ec2 *EC2) CreateImage(instanceId, name, description string, noReboot bool)
 //
 // see http://docs.aws.amazon.com/AWSEC2/latest/APIReference/ApiReference-query-CopyImage.html for more details.
 func (ec2 *EC2) CopyImage(sourceRegion aws.Region, imageId, name, description string) (resp *CreateImageResp, err error) {
+	return ec2.CopyImageWithOptions(&CopyImageOptions{
+		SourceRegion:  sourceRegion,
+		SourceImageId: imageId,
+		Name:          name,
+		Description:   description,
+	})
+}
+
+// CopyImageOptions encapsulates options for the CopyImageWithOptions call.
+type CopyImageOptions struct {
+	SourceRegion  aws.Region
+	SourceImageId string
+	Name          string
+	Description   string
+	ClientToken   string // Generated if empty
+}
+
+// CopyImageWithOptions initiates the copy of an AMI, like CopyImage, but
+// also supports the ClientToken parameter.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopyImage.html for more details.
+func (ec2 *EC2) CopyImageWithOptions(opts *CopyImageOptions) (resp *CreateImageResp, err error) {
 	params := makeParams("CopyImage")
-	params["SourceRegion"] = sourceRegion.Name
-	params["SourceImageId"] = imageId
-	params["Name"] = name
-	params["Description"] = description
+	params["SourceRegion"] = opts.SourceRegion.Name
+	params["SourceImageId"] = opts.SourceImageId
+	params["Name"] = opts.Name
+	params["Description"] = opts.Description
+	token, err := orClientToken(opts.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
 
 	resp = &CreateImageResp{}
 	err = ec2.query(params, resp)
@@ -1005,36 +2430,76 @@ func (ec2 *EC2) DeleteSnapshots(ssid string) (resp *SimpleResp, err error) {
 //
 // See http://goo.gl/nClDT for more details.
 type SnapshotsResp struct {
-	RequestId string     `xml:"requestId"`
-	Snapshots []Snapshot `xml:"snapshotSet>item"`
+	RequestId string     `xml:"requestId" json:"requestId"`
+	Snapshots []Snapshot `xml:"snapshotSet>item" json:"snapshots"`
 }
 
 // Snapshot represents details about a volume snapshot.
 //
 // See http://goo.gl/nkovs for more details.
 type Snapshot struct {
-	Id          string `xml:"snapshotId"`
-	VolumeId    string `xml:"volumeId"`
-	VolumeSize  string `xml:"volumeSize"`
-	Status      string `xml:"status"`
-	StartTime   string `xml:"startTime"`
-	Description string `xml:"description"`
-	Progress    string `xml:"progress"`
-	OwnerId     string `xml:"ownerId"`
-	OwnerAlias  string `xml:"ownerAlias"`
-	Tags        []Tag  `xml:"tagSet>item"`
+	Id            string `xml:"snapshotId" json:"id"`
+	VolumeId      string `xml:"volumeId" json:"volumeId"`
+	VolumeSize    string `xml:"volumeSize" json:"volumeSize"`
+	Status        string `xml:"status" json:"status"`
+	StartTime     string `xml:"startTime" json:"startTime"`
+	Description   string `xml:"description" json:"description"`
+	Progress      string `xml:"progress" json:"progress"`
+	StatusMessage string `xml:"statusMessage" json:"statusMessage"`
+	OwnerId       string `xml:"ownerId" json:"ownerId"`
+	OwnerAlias    string `xml:"ownerAlias" json:"ownerAlias"`
+	Tags          []Tag  `xml:"tagSet>item" json:"tags"`
+}
+
+// VolumeSizeGiB returns the size of the snapshot's volume in GiB.
+func (s *Snapshot) VolumeSizeGiB() (int64, error) {
+	return strconv.ParseInt(s.VolumeSize, 10, 64)
 }
 
 // Snapshots returns details about volume snapshots available to the user.
 // The ids and filter parameters, if provided, limit the snapshots returned.
 //
+// Note: calling this function with nil ids and filter parameters will
+// return every public snapshot. Use SnapshotsWithOptions to restrict the
+// snapshots to those of given owners.
+//
 // See http://goo.gl/ogJL4 for more details.
 func (ec2 *EC2) Snapshots(ids []string, filter *Filter) (resp *SnapshotsResp, err error) {
+	return ec2.SnapshotsWithOptions(&SnapshotsOptions{SnapshotIds: ids, Filter: filter})
+}
+
+// SnapshotsOptions encapsulates options for the SnapshotsWithOptions call.
+// All fields are optional.
+type SnapshotsOptions struct {
+	SnapshotIds []string
+
+	// Owners limits the snapshots to those owned by the given account ids,
+	// or by "self" or "amazon".
+	Owners []string
+
+	// RestorableBy limits the snapshots to those with create volume
+	// permissions for the given account ids, "self" or "all".
+	RestorableBy []string
+
+	Filter *Filter
+}
+
+// SnapshotsWithOptions returns details about volume snapshots, like
+// Snapshots, but also supports the Owner and RestorableBy parameters.
+// opts may be nil.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSnapshots.html for more details.
+func (ec2 *EC2) SnapshotsWithOptions(opts *SnapshotsOptions) (resp *SnapshotsResp, err error) {
+	if opts == nil {
+		opts = &SnapshotsOptions{}
+	}
 	params := makeParams("DescribeSnapshots")
-	for i, id := range ids {
+	for i, id := range opts.SnapshotIds {
 		params["SnapshotId."+strconv.Itoa(i+1)] = id
 	}
-	filter.addParams(params)
+	addParamsList(params, "Owner", opts.Owners)
+	addParamsList(params, "RestorableBy", opts.RestorableBy)
+	opts.Filter.addParams(params)
 
 	resp = &SnapshotsResp{}
 	err = ec2.query(params, resp)
@@ -1044,6 +2509,147 @@ func (ec2 *EC2) Snapshots(ids []string, filter *Filter) (resp *SnapshotsResp, er
 	return
 }
 
+// WaitOptions encapsulates options for the WaitUntilSnapshotCompleted call.
+type WaitOptions struct {
+	// Attempts sets how the snapshot is polled. It defaults to polling
+	// every 15 seconds for up to an hour.
+	Attempts *aws.AttemptStrategy
+
+	// Progress, if set, is called with the progress percentage reported
+	// by each poll.
+	Progress func(percent int)
+}
+
+var snapshotAttempts = aws.AttemptStrategy{
+	Total: time.Hour,
+	Delay: 15 * time.Second,
+}
+
+// WaitUntilSnapshotCompleted polls the snapshot with the given id until
+// its status is completed. It returns an error if the snapshot fails or
+// attempts run out. opts may be nil.
+func (ec2 *EC2) WaitUntilSnapshotCompleted(snapshotId string, opts *WaitOptions) error {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+	attempts := snapshotAttempts
+	if opts.Attempts != nil {
+		attempts = *opts.Attempts
+	}
+	var current Snapshot
+	for a := attempts.Start(); a.Next(); {
+		resp, err := ec2.Snapshots([]string{snapshotId}, nil)
+		if err != nil {
+			return err
+		}
+		if len(resp.Snapshots) == 0 {
+			return fmt.Errorf("snapshot %s not found", snapshotId)
+		}
+		current = resp.Snapshots[0]
+		if opts.Progress != nil {
+			if percent, err := strconv.Atoi(strings.TrimSuffix(current.Progress, "%")); err == nil {
+				opts.Progress(percent)
+			}
+		}
+		switch current.Status {
+		case "completed":
+			return nil
+		case "error":
+			return fmt.Errorf("snapshot %s failed: %s", snapshotId, current.StatusMessage)
+		}
+	}
+	return fmt.Errorf("timed out waiting for snapshot %s, which is %s", snapshotId, current.Status)
+}
+
+// RegisterImageOptions encapsulates options for the RegisterImage call.
+// Name is required; an EBS-backed image is described by its
+// RootDeviceName and BlockDeviceMappings.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RegisterImage.html for more details.
+type RegisterImageOptions struct {
+	Name                string
+	Description         string
+	Architecture        string // i386 | x86_64 | arm64
+	ImageLocation       string // S3 manifest of an instance store-backed image
+	KernelId            string
+	RamdiskId           string
+	RootDeviceName      string
+	VirtualizationType  string // paravirtual | hvm
+	SriovNetSupport     string // "simple" enables enhanced networking
+	EnaSupport          bool
+	BlockDeviceMappings []BlockDeviceMapping
+}
+
+// RegisterImageResp represents a response to a RegisterImage request.
+type RegisterImageResp struct {
+	RequestId string `xml:"requestId"`
+	ImageId   string `xml:"imageId"`
+}
+
+// RegisterImage registers a new AMI.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RegisterImage.html for more details.
+func (ec2 *EC2) RegisterImage(opts *RegisterImageOptions) (resp *RegisterImageResp, err error) {
+	if err := validateBlockDeviceMappings(opts.BlockDeviceMappings); err != nil {
+		return nil, err
+	}
+	params := makeParams("RegisterImage")
+	params["Name"] = opts.Name
+	if opts.Description != "" {
+		params["Description"] = opts.Description
+	}
+	if opts.Architecture != "" {
+		params["Architecture"] = opts.Architecture
+	}
+	if opts.ImageLocation != "" {
+		params["ImageLocation"] = opts.ImageLocation
+	}
+	if opts.KernelId != "" {
+		params["KernelId"] = opts.KernelId
+	}
+	if opts.RamdiskId != "" {
+		params["RamdiskId"] = opts.RamdiskId
+	}
+	if opts.RootDeviceName != "" {
+		params["RootDeviceName"] = opts.RootDeviceName
+	}
+	if opts.VirtualizationType != "" {
+		params["VirtualizationType"] = opts.VirtualizationType
+	}
+	if opts.SriovNetSupport != "" {
+		params["SriovNetSupport"] = opts.SriovNetSupport
+	}
+	if opts.EnaSupport {
+		params["Version"] = newAPIVersion
+		params["EnaSupport"] = "true"
+	}
+	addBlockDeviceMappings(params, "", opts.BlockDeviceMappings)
+
+	resp = &RegisterImageResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterImageFromSnapshot registers an HVM image booting from a volume
+// created from the given EBS snapshot, attached as rootDeviceName (for
+// example "/dev/xvda") and deleted when the instance terminates.
+func (ec2 *EC2) RegisterImageFromSnapshot(name, snapshotId, architecture, rootDeviceName string) (resp *RegisterImageResp, err error) {
+	return ec2.RegisterImage(&RegisterImageOptions{
+		Name:               name,
+		Architecture:       architecture,
+		RootDeviceName:     rootDeviceName,
+		VirtualizationType: "hvm",
+		BlockDeviceMappings: []BlockDeviceMapping{{
+			DeviceName:          rootDeviceName,
+			SnapshotId:          snapshotId,
+			DeleteOnTermination: true,
+		}},
+	})
+}
+
 // DeregisterImage
 //
 type DeregisterImageResponse struct {
@@ -1069,21 +2675,21 @@ func (ec2 *EC2) DeregisterImage(imageId string) (resp *DeregisterImageResponse,
 // Subnets
 
 type SubnetsResp struct {
-	RequestId string   `xml:"requestId"`
-	Subnets   []Subnet `xml:"subnetSet>item"`
+	RequestId string   `xml:"requestId" json:"requestId"`
+	Subnets   []Subnet `xml:"subnetSet>item" json:"subnets"`
 }
 
 // Subnet represents details about a given VPC subnet
 type Subnet struct {
-	Id                      string `xml:"subnetId"`
-	State                   string `xml:"state"`
-	VpcId                   string `xml:"vpcId"`
-	CidrBlock               string `xml:"cidrBlock"`
-	AvailableIpAddressCount int    `xml:"availableIpAddressCount"`
-	AvailabilityZone        string `xml:"availabilityZone"`
-	DefaultForAz            bool   `xml:"defaultForAz"`
-	MapPublicIpOnLaunch     bool   `xml:"mapPublicIpOnLaunch"`
-	Tags                    []Tag  `xml:"tagSet>item"`
+	Id                      string `xml:"subnetId" json:"id"`
+	State                   string `xml:"state" json:"state"`
+	VpcId                   string `xml:"vpcId" json:"vpcId"`
+	CidrBlock               string `xml:"cidrBlock" json:"cidrBlock"`
+	AvailableIpAddressCount int    `xml:"availableIpAddressCount" json:"availableIpAddressCount"`
+	AvailabilityZone        string `xml:"availabilityZone" json:"availabilityZone"`
+	DefaultForAz            bool   `xml:"defaultForAz" json:"defaultForAz"`
+	MapPublicIpOnLaunch     bool   `xml:"mapPublicIpOnLaunch" json:"mapPublicIpOnLaunch"`
+	Tags                    []Tag  `xml:"tagSet>item" json:"tags"`
 }
 
 // Subnets returns details about VPC subnets.
@@ -1124,9 +2730,21 @@ type CreateSecurityGroupResp struct {
 //
 // See http://goo.gl/Eo7Yl for more details.
 func (ec2 *EC2) CreateSecurityGroup(name, description string) (resp *CreateSecurityGroupResp, err error) {
+	return ec2.CreateSecurityGroupVPC(name, description, "")
+}
+
+// CreateSecurityGroupVPC is like CreateSecurityGroup but creates the group
+// in the VPC with the given id. VPC security groups must be referred to by
+// the Id of the returned group in later requests.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateSecurityGroup.html for more details.
+func (ec2 *EC2) CreateSecurityGroupVPC(name, description, vpcId string) (resp *CreateSecurityGroupResp, err error) {
 	params := makeParams("CreateSecurityGroup")
 	params["GroupName"] = name
 	params["GroupDescription"] = description
+	if vpcId != "" {
+		params["VpcId"] = vpcId
+	}
 
 	resp = &CreateSecurityGroupResp{}
 	err = ec2.query(params, resp)
@@ -1142,8 +2760,8 @@ func (ec2 *EC2) CreateSecurityGroup(name, description string) (resp *CreateSecur
 //
 // See http://goo.gl/k12Uy for more details.
 type SecurityGroupsResp struct {
-	RequestId string              `xml:"requestId"`
-	Groups    []SecurityGroupInfo `xml:"securityGroupInfo>item"`
+	RequestId string              `xml:"requestId" json:"requestId"`
+	Groups    []SecurityGroupInfo `xml:"securityGroupInfo>item" json:"groups"`
 }
 
 // SecurityGroup encapsulates details for a security group in EC2.
@@ -1151,39 +2769,111 @@ type SecurityGroupsResp struct {
 // See http://goo.gl/CIdyP for more details.
 type SecurityGroupInfo struct {
 	SecurityGroup
-	OwnerId       string   `xml:"ownerId"`
-	Description   string   `xml:"groupDescription"`
-	IPPerms       []IPPerm `xml:"ipPermissions>item"`
-	IPPermsEgress []IPPerm `xml:"ipPermissionsEgress>item"`
-	VpcId         string   `xml:"vpcId"`
-	Tags          []Tag    `xml:"tagSet>item"`
+	OwnerId       string   `xml:"ownerId" json:"ownerId"`
+	Description   string   `xml:"groupDescription" json:"description"`
+	IPPerms       []IPPerm `xml:"ipPermissions>item" json:"ipPerms"`
+	IPPermsEgress []IPPerm `xml:"ipPermissionsEgress>item" json:"ipPermsEgress"`
+	VpcId         string   `xml:"vpcId" json:"vpcId"`
+	Tags          []Tag    `xml:"tagSet>item" json:"tags"`
 }
 
 // IPPerm represents an allowance within an EC2 security group.
 //
+// SourceIPs is kept for backward compatibility and holds the plain CIDR
+// blocks of the permission. IPRanges carries the same entries along with
+// their optional descriptions. When sending a permission both are used,
+// and blocks found in both are sent once, so that permissions read with
+// SecurityGroups may be passed back to AuthorizeSecurityGroup unchanged.
+// Source groups are identified by Id when it is set, and by Name
+// otherwise.
+//
+// For ICMP permissions FromPort and ToPort hold the ICMP type and code
+// instead, where -1 means any type or code; see IcmpPerm.
+//
 // See http://goo.gl/4oTxv for more details.
 type IPPerm struct {
-	Protocol     string              `xml:"ipProtocol"`
-	FromPort     int                 `xml:"fromPort"`
-	ToPort       int                 `xml:"toPort"`
-	SourceIPs    []string            `xml:"ipRanges>item>cidrIp"`
-	SourceGroups []UserSecurityGroup `xml:"groups>item"`
+	Protocol     string              `xml:"ipProtocol" json:"protocol"`
+	FromPort     int                 `xml:"fromPort" json:"fromPort"`
+	ToPort       int                 `xml:"toPort" json:"toPort"`
+	SourceIPs    []string            `xml:"-" json:"sourceIps,omitempty"`
+	IPRanges     []IPRange           `xml:"ipRanges>item" json:"ipRanges"`
+	SourceGroups []UserSecurityGroup `xml:"groups>item" json:"sourceGroups"`
 }
 
-// UserSecurityGroup holds a security group and the owner
-// of that group.
+// IP protocols of security group permissions. Other protocols may be given
+// by their number.
+const (
+	ProtocolTCP    = "tcp"
+	ProtocolUDP    = "udp"
+	ProtocolICMP   = "icmp"
+	ProtocolICMPv6 = "icmpv6"
+	ProtocolAll    = "-1"
+)
+
+// normalizeProtocol lowercases protocol, as some endpoints reject names
+// such as "TCP", and checks that it is a known protocol name, a protocol
+// number or ProtocolAll.
+func normalizeProtocol(protocol string) (string, error) {
+	lower := strings.ToLower(protocol)
+	switch lower {
+	case ProtocolTCP, ProtocolUDP, ProtocolICMP, ProtocolICMPv6, ProtocolAll:
+		return lower, nil
+	}
+	if n, err := strconv.Atoi(protocol); err == nil && n >= 0 && n <= 255 {
+		return protocol, nil
+	}
+	return "", fmt.Errorf("invalid IP protocol %q", protocol)
+}
+
+// IcmpPerm returns a permission allowing ICMP messages of the given type
+// and code from the given CIDR blocks. A negative typ allows all ICMP
+// messages, and a negative code allows all codes of typ; both are sent
+// to EC2 as -1.
+func IcmpPerm(typ, code int, sources ...string) IPPerm {
+	if typ < 0 {
+		typ, code = -1, -1
+	} else if code < 0 {
+		code = -1
+	}
+	return IPPerm{
+		Protocol:  ProtocolICMP,
+		FromPort:  typ,
+		ToPort:    code,
+		SourceIPs: sources,
+	}
+}
+
+// IPRange represents a CIDR block within an IPPerm along with an optional
+// free-text description of the rule.
+type IPRange struct {
+	CIDR        string `xml:"cidrIp" json:"cidr"`
+	Description string `xml:"description,omitempty" json:"description,omitempty"`
+}
+
+// setSourceIPs fills in SourceIPs from the decoded IPRanges.
+func setSourceIPs(perms []IPPerm) {
+	for i := range perms {
+		perms[i].SourceIPs = nil
+		for _, r := range perms[i].IPRanges {
+			perms[i].SourceIPs = append(perms[i].SourceIPs, r.CIDR)
+		}
+	}
+}
+
+// UserSecurityGroup holds a security group and the owner
+// of that group.
 type UserSecurityGroup struct {
-	Id      string `xml:"groupId"`
-	Name    string `xml:"groupName"`
-	OwnerId string `xml:"userId"`
+	Id      string `xml:"groupId" json:"id"`
+	Name    string `xml:"groupName" json:"name"`
+	OwnerId string `xml:"userId" json:"ownerId"`
 }
 
 // SecurityGroup represents an EC2 security group.
 // If SecurityGroup is used as a parameter, then one of Id or Name
 // may be empty. If both are set, then Id is used.
 type SecurityGroup struct {
-	Id   string `xml:"groupId"`
-	Name string `xml:"groupName"`
+	Id   string `xml:"groupId" json:"id"`
+	Name string `xml:"groupName" json:"name"`
 }
 
 // SecurityGroupNames is a convenience function that
@@ -1230,6 +2920,10 @@ func (ec2 *EC2) SecurityGroups(groups []SecurityGroup, filter *Filter) (resp *Se
 	if err != nil {
 		return nil, err
 	}
+	for i := range resp.Groups {
+		setSourceIPs(resp.Groups[i].IPPerms)
+		setSourceIPs(resp.Groups[i].IPPermsEgress)
+	}
 	return resp, nil
 }
 
@@ -1276,12 +2970,33 @@ func (ec2 *EC2) authOrRevoke(op string, group SecurityGroup, perms []IPPerm) (re
 	}
 
 	for i, perm := range perms {
+		protocol, err := normalizeProtocol(perm.Protocol)
+		if err != nil {
+			return nil, err
+		}
 		prefix := "IpPermissions." + strconv.Itoa(i+1)
-		params[prefix+".IpProtocol"] = perm.Protocol
+		params[prefix+".IpProtocol"] = protocol
 		params[prefix+".FromPort"] = strconv.Itoa(perm.FromPort)
 		params[prefix+".ToPort"] = strconv.Itoa(perm.ToPort)
-		for j, ip := range perm.SourceIPs {
-			params[prefix+".IpRanges."+strconv.Itoa(j+1)+".CidrIp"] = ip
+		described := make(map[string]bool, len(perm.IPRanges))
+		for _, r := range perm.IPRanges {
+			described[r.CIDR] = true
+		}
+		j := 1
+		for _, ip := range perm.SourceIPs {
+			if described[ip] {
+				continue
+			}
+			params[prefix+".IpRanges."+strconv.Itoa(j)+".CidrIp"] = ip
+			j++
+		}
+		for _, r := range perm.IPRanges {
+			subprefix := prefix + ".IpRanges." + strconv.Itoa(j)
+			params[subprefix+".CidrIp"] = r.CIDR
+			if r.Description != "" {
+				params[subprefix+".Description"] = r.Description
+			}
+			j++
 		}
 		for j, g := range perm.SourceGroups {
 			subprefix := prefix + ".Groups." + strconv.Itoa(j+1)
@@ -1304,28 +3019,49 @@ func (ec2 *EC2) authOrRevoke(op string, group SecurityGroup, perms []IPPerm) (re
 	return resp, nil
 }
 
-// ResourceTag represents key-value metadata used to classify and organize
-// EC2 instances.
+// SecurityGroupRule describes a single inbound or outbound rule of a
+// security group.
 //
-// See http://goo.gl/bncl3 for more details
-type Tag struct {
-	Key   string `xml:"key"`
-	Value string `xml:"value"`
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_SecurityGroupRule.html for more details.
+type SecurityGroupRule struct {
+	SecurityGroupRuleId string `xml:"securityGroupRuleId"`
+	GroupId             string `xml:"groupId"`
+	GroupOwnerId        string `xml:"groupOwnerId"`
+	IsEgress            bool   `xml:"isEgress"`
+	IpProtocol          string `xml:"ipProtocol"` // -1 means all protocols
+	FromPort            int    `xml:"fromPort"`
+	ToPort              int    `xml:"toPort"`
+	CidrIpv4            string `xml:"cidrIpv4"`
+	CidrIpv6            string `xml:"cidrIpv6"`
+	PrefixListId        string `xml:"prefixListId"`
+	ReferencedGroupId   string `xml:"referencedGroupInfo>groupId"`
+	Description         string `xml:"description"`
+	Tags                []Tag  `xml:"tagSet>item"`
 }
 
-// CreateTags adds or overwrites one or more tags for the specified instance ids.
+// SecurityGroupRulesResp represents a response to a
+// DescribeSecurityGroupRules request.
 //
-// See http://goo.gl/Vmkqc for more details
-func (ec2 *EC2) CreateTags(instIds []string, tags []Tag) (resp *SimpleResp, err error) {
-	params := makeParams("CreateTags")
-	addParamsList(params, "ResourceId", instIds)
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSecurityGroupRules.html for more details.
+type SecurityGroupRulesResp struct {
+	RequestId string              `xml:"requestId"`
+	Rules     []SecurityGroupRule `xml:"securityGroupRuleSet>item"`
+	NextToken string              `xml:"nextToken"`
+}
 
-	for j, tag := range tags {
-		params["Tag."+strconv.Itoa(j+1)+".Key"] = tag.Key
-		params["Tag."+strconv.Itoa(j+1)+".Value"] = tag.Value
-	}
+// SecurityGroupRules returns the rules of security groups. Both parameters
+// are optional, and if provided will limit the rules returned to those
+// with the given rule ids or matching the filtering rules, such as
+// "group-id".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSecurityGroupRules.html for more details.
+func (ec2 *EC2) SecurityGroupRules(ids []string, filter *Filter) (resp *SecurityGroupRulesResp, err error) {
+	params := makeParams("DescribeSecurityGroupRules")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "SecurityGroupRuleId", ids)
+	filter.addParams(params)
 
-	resp = &SimpleResp{}
+	resp = &SecurityGroupRulesResp{}
 	err = ec2.query(params, resp)
 	if err != nil {
 		return nil, err
@@ -1333,23 +3069,95 @@ func (ec2 *EC2) CreateTags(instIds []string, tags []Tag) (resp *SimpleResp, err
 	return resp, nil
 }
 
+// ResourceTag represents key-value metadata used to classify and organize
+// EC2 instances.
+//
+// See http://goo.gl/bncl3 for more details
+type Tag struct {
+	Key   string `xml:"key" json:"key"`
+	Value string `xml:"value" json:"value"`
+}
+
+// TagValue returns the value of the tag with the given key in tags, and
+// whether such a tag was present. For example, TagValue(inst.Tags, "Name").
+func TagValue(tags []Tag, key string) (string, bool) {
+	for _, tag := range tags {
+		if tag.Key == key {
+			return tag.Value, true
+		}
+	}
+	return "", false
+}
+
+// TagSpecification holds the tags to apply to resources of one type when
+// they are created. ResourceType is e.g. "instance" or "volume".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_TagSpecification.html for more details.
+type TagSpecification struct {
+	ResourceType string
+	Tags         []Tag
+}
+
+func addTagSpecifications(params map[string]string, prefix string, specs []TagSpecification) {
+	for i, spec := range specs {
+		ts := prefix + "TagSpecification." + strconv.Itoa(i+1) + "."
+		params[ts+"ResourceType"] = spec.ResourceType
+		for j, tag := range spec.Tags {
+			params[ts+"Tag."+strconv.Itoa(j+1)+".Key"] = tag.Key
+			params[ts+"Tag."+strconv.Itoa(j+1)+".Value"] = tag.Value
+		}
+	}
+}
+
+// maxTagResources is the maximum number of resources EC2 accepts in a
+// single CreateTags or DeleteTags request.
+const maxTagResources = 1000
+
+// CreateTags adds or overwrites one or more tags for the specified resource ids.
+// The request is sent in the body of a POST so that tagging many resources
+// does not overflow URL length limits, and resource ids beyond the EC2 limit
+// of 1000 per call are split over several requests. The response of the
+// last request is returned; if one of them fails the resources of earlier
+// requests will already have been tagged.
+//
+// See http://goo.gl/Vmkqc for more details
+func (ec2 *EC2) CreateTags(instIds []string, tags []Tag) (resp *SimpleResp, err error) {
+	return ec2.tagResources("CreateTags", instIds, tags)
+}
+
 // DeleteTags deletes the specified set of tags from the specified set of resources.
+// Like CreateTags, large sets of resources are split over several requests.
 //
 // See http://goo.gl/t6XvYh for more details
 func (ec2 *EC2) DeleteTags(instIds []string, tags []Tag) (resp *SimpleResp, err error) {
-	params := makeParams("DeleteTags")
-	addParamsList(params, "ResourceId", instIds)
+	return ec2.tagResources("DeleteTags", instIds, tags)
+}
 
-	for j, tag := range tags {
-		params["Tag."+strconv.Itoa(j+1)+".Key"] = tag.Key
-	}
+func (ec2 *EC2) tagResources(action string, ids []string, tags []Tag) (resp *SimpleResp, err error) {
+	for start := 0; ; start += maxTagResources {
+		end := start + maxTagResources
+		if end > len(ids) {
+			end = len(ids)
+		}
+		params := makeParams(action)
+		addParamsList(params, "ResourceId", ids[start:end])
 
-	resp = &SimpleResp{}
-	err = ec2.query(params, resp)
-	if err != nil {
-		return nil, err
+		for j, tag := range tags {
+			params["Tag."+strconv.Itoa(j+1)+".Key"] = tag.Key
+			if action == "CreateTags" {
+				params["Tag."+strconv.Itoa(j+1)+".Value"] = tag.Value
+			}
+		}
+
+		resp = &SimpleResp{}
+		err = ec2.post(params, resp)
+		if err != nil {
+			return nil, err
+		}
+		if end == len(ids) {
+			return resp, nil
+		}
 	}
-	return resp, nil
 }
 
 // DescribedTag represents key-value metadata used to classify and organize EC2
@@ -1430,6 +3238,84 @@ func (ec2 *EC2) StopInstances(ids ...string) (resp *StopInstanceResp, err error)
 	return resp, nil
 }
 
+// ModifyInstanceType changes the type of a stopped instance.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyInstanceAttribute.html for more details.
+func (ec2 *EC2) ModifyInstanceType(instanceId, instanceType string) (resp *SimpleResp, err error) {
+	params := makeParams("ModifyInstanceAttribute")
+	params["InstanceId"] = instanceId
+	params["InstanceType.Value"] = instanceType
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ResizeOptions encapsulates options for the ResizeInstance call.
+type ResizeOptions struct {
+	// Restart starts the instance again once its type has been changed,
+	// and waits until it is running.
+	Restart bool
+
+	// Attempts sets how the instance state is polled while waiting for it
+	// to stop or start. It defaults to polling every 5 seconds for up to
+	// 10 minutes.
+	Attempts *aws.AttemptStrategy
+}
+
+var resizeAttempts = aws.AttemptStrategy{
+	Total: 10 * time.Minute,
+	Delay: 5 * time.Second,
+}
+
+// ResizeInstance changes the type of an EBS-backed instance. The instance
+// is stopped and, once it is, its type is changed to instanceType. If
+// opts.Restart is set the instance is then started again. opts may be nil.
+func (ec2 *EC2) ResizeInstance(instanceId, instanceType string, opts *ResizeOptions) error {
+	if opts == nil {
+		opts = &ResizeOptions{}
+	}
+	attempts := resizeAttempts
+	if opts.Attempts != nil {
+		attempts = *opts.Attempts
+	}
+	if _, err := ec2.StopInstances(instanceId); err != nil {
+		return err
+	}
+	if err := ec2.waitForInstanceState(instanceId, InstanceStateStopped, attempts); err != nil {
+		return err
+	}
+	if _, err := ec2.ModifyInstanceType(instanceId, instanceType); err != nil {
+		return err
+	}
+	if !opts.Restart {
+		return nil
+	}
+	if _, err := ec2.StartInstances(instanceId); err != nil {
+		return err
+	}
+	return ec2.waitForInstanceState(instanceId, InstanceStateRunning, attempts)
+}
+
+// waitForInstanceState polls the instance with the given id until its
+// state is state, one of the InstanceState constants, or attempts run out.
+func (ec2 *EC2) waitForInstanceState(instanceId string, state int, attempts aws.AttemptStrategy) error {
+	var current InstanceState
+	for a := attempts.Start(); a.Next(); {
+		inst, err := ec2.Instance(instanceId)
+		if err != nil {
+			return err
+		}
+		current = inst.State
+		if current.Normalized() == state {
+			return nil
+		}
+	}
+	return fmt.Errorf("timed out waiting for instance %s, which is %s", instanceId, current.Name)
+}
+
 // RebootInstance requests a reboot of one or more instances. This operation is asynchronous;
 // it only queues a request to reboot the specified instance(s). The operation will succeed
 // if the instances are valid and belong to you.
@@ -1510,6 +3396,186 @@ func (ec2 *EC2) DescribeReservedInstances(instIds []string, filter *Filter) (res
 	return resp, nil
 }
 
+// ReservedInstancesResp represents a response to a DescribeReservedInstances
+// request in EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeReservedInstances.html for more details.
+type ReservedInstancesResp struct {
+	RequestId         string             `xml:"requestId"`
+	ReservedInstances []ReservedInstance `xml:"reservedInstancesSet>item"`
+}
+
+// ReservedInstance describes a purchased reservation of instance capacity.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReservedInstances.html for more details.
+type ReservedInstance struct {
+	ReservedInstancesId string            `xml:"reservedInstancesId"`
+	InstanceType        string            `xml:"instanceType"`
+	AvailabilityZone    string            `xml:"availabilityZone"` // Empty for regional reservations
+	Start               string            `xml:"start"`
+	End                 string            `xml:"end"`
+	Duration            int64             `xml:"duration"` // In seconds
+	FixedPrice          float64           `xml:"fixedPrice"`
+	UsagePrice          float64           `xml:"usagePrice"`
+	InstanceCount       int               `xml:"instanceCount"`
+	ProductDescription  string            `xml:"productDescription"`
+	State               string            `xml:"state"`        // Valid values: payment-pending | active | payment-failed | retired
+	OfferingType        string            `xml:"offeringType"` // For example: No Upfront | Partial Upfront | All Upfront
+	OfferingClass       string            `xml:"offeringClass"`
+	Scope               string            `xml:"scope"` // Valid values: Availability Zone | Region
+	InstanceTenancy     string            `xml:"instanceTenancy"`
+	CurrencyCode        string            `xml:"currencyCode"`
+	RecurringCharges    []RecurringCharge `xml:"recurringCharges>item"`
+	Tags                []Tag             `xml:"tagSet>item"`
+}
+
+// ReservedInstances returns details about the Reserved Instances purchased
+// by the account. Both parameters are optional, and if provided will limit
+// the reservations returned to those matching the given ids or filtering
+// rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeReservedInstances.html for more details.
+func (ec2 *EC2) ReservedInstances(ids []string, filter *Filter) (resp *ReservedInstancesResp, err error) {
+	params := makeParams("DescribeReservedInstances")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "ReservedInstancesId", ids)
+	filter.addParams(params)
+
+	resp = &ReservedInstancesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReservedInstancesOfferingsResp represents a response to a
+// DescribeReservedInstancesOfferings request in EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeReservedInstancesOfferings.html for more details.
+type ReservedInstancesOfferingsResp struct {
+	RequestId string                      `xml:"requestId"`
+	Offerings []ReservedInstancesOffering `xml:"reservedInstancesOfferingsSet>item"`
+	NextToken string                      `xml:"nextToken"`
+}
+
+// ReservedInstancesOffering describes a Reserved Instance offering that
+// may be purchased with PurchaseReservedInstancesOffering.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReservedInstancesOffering.html for more details.
+type ReservedInstancesOffering struct {
+	ReservedInstancesOfferingId string            `xml:"reservedInstancesOfferingId"`
+	InstanceType                string            `xml:"instanceType"`
+	AvailabilityZone            string            `xml:"availabilityZone"`
+	Duration                    int64             `xml:"duration"` // In seconds
+	FixedPrice                  float64           `xml:"fixedPrice"`
+	UsagePrice                  float64           `xml:"usagePrice"`
+	ProductDescription          string            `xml:"productDescription"`
+	InstanceTenancy             string            `xml:"instanceTenancy"`
+	CurrencyCode                string            `xml:"currencyCode"`
+	OfferingType                string            `xml:"offeringType"`
+	OfferingClass               string            `xml:"offeringClass"`
+	Scope                       string            `xml:"scope"`
+	Marketplace                 bool              `xml:"marketplace"`
+	RecurringCharges            []RecurringCharge `xml:"recurringCharges>item"`
+}
+
+// ReservedInstancesOfferings returns the Reserved Instance offerings
+// available for purchase. The filter is optional. All pages of results
+// are fetched and returned together in a single response; use
+// ReservedInstancesOfferingsPages to process them a page at a time.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeReservedInstancesOfferings.html for more details.
+func (ec2 *EC2) ReservedInstancesOfferings(filter *Filter) (resp *ReservedInstancesOfferingsResp, err error) {
+	resp = &ReservedInstancesOfferingsResp{}
+	err = ec2.ReservedInstancesOfferingsPages(&ReservedInstancesOfferingsPagesOptions{Filter: filter}, func(page *ReservedInstancesOfferingsResp) error {
+		resp.RequestId = page.RequestId
+		resp.Offerings = append(resp.Offerings, page.Offerings...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReservedInstancesOfferingsPagesOptions encapsulates options for the
+// ReservedInstancesOfferingsPages call. All fields are optional.
+type ReservedInstancesOfferingsPagesOptions struct {
+	Filter *Filter
+
+	// MaxResults is the number of offerings EC2 returns per page, at most
+	// 100.
+	MaxResults int
+}
+
+// ReservedInstancesOfferingsPages is like ReservedInstancesOfferings, but
+// calls fn with each page of offerings as it is received instead of
+// holding all of them in memory. If fn returns an error, no further pages
+// are requested and that error is returned. opts may be nil.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeReservedInstancesOfferings.html for more details.
+func (ec2 *EC2) ReservedInstancesOfferingsPages(opts *ReservedInstancesOfferingsPagesOptions, fn func(page *ReservedInstancesOfferingsResp) error) error {
+	if opts == nil {
+		opts = &ReservedInstancesOfferingsPagesOptions{}
+	}
+	nextToken := ""
+	for {
+		params := makeParams("DescribeReservedInstancesOfferings")
+		params["Version"] = newAPIVersion
+		opts.Filter.addParams(params)
+		if opts.MaxResults > 0 {
+			params["MaxResults"] = strconv.Itoa(opts.MaxResults)
+		}
+		if nextToken != "" {
+			params["NextToken"] = nextToken
+		}
+
+		page := &ReservedInstancesOfferingsResp{}
+		if err := ec2.query(params, page); err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if page.NextToken == "" {
+			return nil
+		}
+		nextToken = page.NextToken
+	}
+}
+
+// PurchaseReservedInstancesOfferingResp represents a response to a
+// PurchaseReservedInstancesOffering request in EC2.
+type PurchaseReservedInstancesOfferingResp struct {
+	RequestId           string `xml:"requestId"`
+	ReservedInstancesId string `xml:"reservedInstancesId"`
+}
+
+// PurchaseReservedInstancesOffering purchases count instances of the
+// Reserved Instance offering with the given id. If limitPrice is not nil,
+// the purchase fails rather than paying a total upfront price (in USD)
+// above it.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_PurchaseReservedInstancesOffering.html for more details.
+func (ec2 *EC2) PurchaseReservedInstancesOffering(offeringId string, count int, limitPrice *float64) (resp *PurchaseReservedInstancesOfferingResp, err error) {
+	params := makeParams("PurchaseReservedInstancesOffering")
+	params["Version"] = newAPIVersion
+	params["ReservedInstancesOfferingId"] = offeringId
+	params["InstanceCount"] = strconv.Itoa(count)
+	if limitPrice != nil {
+		params["LimitPrice.Amount"] = strconv.FormatFloat(*limitPrice, 'f', -1, 64)
+		params["LimitPrice.CurrencyCode"] = "USD"
+	}
+
+	resp = &PurchaseReservedInstancesOfferingResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 type SystemStateStruct struct {
 	StatusName string `xml:"status"`
 	Name       string `xml:"details>item>name"`
@@ -1548,29 +3614,32 @@ func (ec2 *EC2) DescribeInstanceStatus(instIds []string, filter *Filter) (resp *
 }
 
 type AttachmentSetStruct struct {
-	VolumeId            string `xml:"volumeId"`
-	InstanceId          string `xml:"instanceId"`
-	Device              string `xml:"device"`
-	Status              string `xml:"status"`
-	AttachTime          string `xml:"attachTime"`
-	DeleteOnTermination bool   `xml:"deleteOnTermination"`
+	VolumeId            string `xml:"volumeId" json:"volumeId"`
+	InstanceId          string `xml:"instanceId" json:"instanceId"`
+	Device              string `xml:"device" json:"device"`
+	Status              string `xml:"status" json:"status"`
+	AttachTime          string `xml:"attachTime" json:"attachTime"`
+	DeleteOnTermination bool   `xml:"deleteOnTermination" json:"deleteOnTermination"`
 }
 
 type VolumeStruct struct {
-	VolumeId         string              `xml:"volumeId"`
-	Size             int                 `xml:"size"`
-	SnapShotId       string              `xml:"snapshotId"`
-	AvailabilityZone string              `xml:"availabilityZone"`
-	Status           string              `xml:"status"`
-	CreateTime       string              `xml:"createTime"`
-	AttachmentSet    AttachmentSetStruct `xml:"attachmentSet>item"`
-	VolumeType       string              `xml:"volumeType"`
-	Encrypted        string              `xml:"encrypted"`
+	VolumeId         string              `xml:"volumeId" json:"volumeId"`
+	Size             int                 `xml:"size" json:"size"`
+	SnapShotId       string              `xml:"snapshotId" json:"snapshotId"`
+	AvailabilityZone string              `xml:"availabilityZone" json:"availabilityZone"`
+	Status           string              `xml:"status" json:"status"`
+	CreateTime       string              `xml:"createTime" json:"createTime"`
+	AttachmentSet    AttachmentSetStruct `xml:"attachmentSet>item" json:"attachmentSet"`
+	VolumeType       string              `xml:"volumeType" json:"volumeType"`
+	Encrypted        string              `xml:"encrypted" json:"encrypted"`
+	IOPS             int                 `xml:"iops" json:"iops"`
+	Throughput       int64               `xml:"throughput" json:"throughput"`
+	Tags             []Tag               `xml:"tagSet>item" json:"tags"`
 }
 
 type DescribeVolumesResp struct {
-	RequestId string         `xml:"requestId"`
-	Volumes   []VolumeStruct `xml:"volumeSet>item"`
+	RequestId string         `xml:"requestId" json:"requestId"`
+	Volumes   []VolumeStruct `xml:"volumeSet>item" json:"volumes"`
 }
 
 func (ec2 *EC2) DescribeVolumes(volIds []string, filter *Filter) (resp *DescribeVolumesResp, err error) {
@@ -1614,8 +3683,14 @@ type CreateVolumeOptions struct {
 	AvailabilityZone string
 	VolumeType       string
 	IOPS             int
+	Throughput       int64 // MiB/s, gp3 volumes only
 	Encrypted        bool
 	KmsKeyId         string
+	DryRun           bool
+
+	// ClientToken ensures the idempotency of the request. If empty, a
+	// random token is generated and returned in CreateVolumeResp.
+	ClientToken string
 }
 
 type CreateVolumeResp struct {
@@ -1628,14 +3703,21 @@ type CreateVolumeResp struct {
 	CreateTime       string `xml:"createTime"`
 	VolumeType       string `xml:"volumeType"`
 	IOPS             int    `xml:"iops"`
+	Throughput       int64  `xml:"throughput"`
 	Encrypted        bool   `xml:"encrypted"`
 	KmsKeyId         string `xml:"kmsKeyId"`
+
+	// ClientToken is the idempotency token sent with the request.
+	ClientToken string `xml:"-"`
 }
 
 // CreateVolume creates an Amazon EBS volume that can be attached to an instance in the same Availability Zone.
 //
 // See http://goo.gl/DERo1w for more details.
 func (ec2 *EC2) CreateVolume(options CreateVolumeOptions) (resp *CreateVolumeResp, err error) {
+	if err := validateVolumeType(options.VolumeType, int64(options.IOPS)); err != nil {
+		return nil, err
+	}
 	params := makeParams("CreateVolume")
 	params["AvailabilityZone"] = options.AvailabilityZone
 
@@ -1651,105 +3733,338 @@ func (ec2 *EC2) CreateVolume(options CreateVolumeOptions) (resp *CreateVolumeRes
 	if options.IOPS > 0 {
 		params["Iops"] = strconv.Itoa(options.IOPS)
 	}
+	if options.Throughput > 0 {
+		params["Version"] = newAPIVersion
+		params["Throughput"] = strconv.FormatInt(options.Throughput, 10)
+	}
 	if options.Encrypted {
 		params["Encrypted"] = "true"
 	}
 	if options.KmsKeyId != "" {
 		params["KmsKeyId"] = options.KmsKeyId
 	}
+	token, err := orClientToken(options.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	// ClientToken is unknown to the default API version.
+	params["Version"] = newAPIVersion
+	params["ClientToken"] = token
+	addDryRun(params, options.DryRun)
 
 	resp = &CreateVolumeResp{}
 	err = ec2.query(params, resp)
 	if err != nil {
 		return nil, err
 	}
+	resp.ClientToken = token
 	return resp, err
 }
 
-type VpcStruct struct {
-	VpcId           string `xml:"vpcId"`
-	State           string `xml:"state"`
-	CidrBlock       string `xml:"cidrBlock"`
-	DhcpOptionsId   string `xml:"dhcpOptionsId"`
-	InstanceTenancy string `xml:"instanceTenancy"`
-	IsDefault       bool   `xml:"isDefault"`
+// ModifyVolumeOptions encapsulates options for the ModifyVolume request.
+// Fields left at their zero value are not changed.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVolume.html for more details.
+type ModifyVolumeOptions struct {
+	Size       int // Target size in GiB
+	VolumeType string
+	IOPS       int
+	DryRun     bool
 }
 
-type DescribeVpcsResp struct {
-	RequestId string      `xml:"requestId"`
-	Vpcs      []VpcStruct `xml:"vpcSet>item"`
+// VolumeModification describes the progress of a volume modification.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VolumeModification.html for more details.
+type VolumeModification struct {
+	VolumeId           string `xml:"volumeId"`
+	State              string `xml:"modificationState"` // Valid values: modifying | optimizing | completed | failed
+	StatusMessage      string `xml:"statusMessage"`
+	Progress           int    `xml:"progress"` // Percentage complete
+	TargetSize         int    `xml:"targetSize"`
+	TargetVolumeType   string `xml:"targetVolumeType"`
+	TargetIOPS         int    `xml:"targetIops"`
+	OriginalSize       int    `xml:"originalSize"`
+	OriginalVolumeType string `xml:"originalVolumeType"`
+	OriginalIOPS       int    `xml:"originalIops"`
+	StartTime          string `xml:"startTime"`
+	EndTime            string `xml:"endTime"`
 }
 
-func (ec2 *EC2) DescribeVpcs(vpcIds []string, filter *Filter) (resp *DescribeVpcsResp, err error) {
-	params := makeParams("DescribeVpcs")
-	addParamsList(params, "vpcId", vpcIds)
-	filter.addParams(params)
-	resp = &DescribeVpcsResp{}
+// ModifyVolumeResp represents a response to a ModifyVolume request.
+type ModifyVolumeResp struct {
+	RequestId          string             `xml:"requestId"`
+	VolumeModification VolumeModification `xml:"volumeModification"`
+}
+
+// ModifyVolume changes the size, type or IOPS of an EBS volume while it
+// remains attached and in use.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVolume.html for more details.
+func (ec2 *EC2) ModifyVolume(volumeId string, opts *ModifyVolumeOptions) (resp *ModifyVolumeResp, err error) {
+	if opts == nil {
+		return nil, errors.New("no volume modification given")
+	}
+	if err := validateVolumeType(opts.VolumeType, int64(opts.IOPS)); err != nil {
+		return nil, err
+	}
+	params := makeParams("ModifyVolume")
+	params["Version"] = newAPIVersion
+	params["VolumeId"] = volumeId
+	if opts.Size > 0 {
+		params["Size"] = strconv.Itoa(opts.Size)
+	}
+	if opts.VolumeType != "" {
+		params["VolumeType"] = opts.VolumeType
+	}
+	if opts.IOPS > 0 {
+		params["Iops"] = strconv.Itoa(opts.IOPS)
+	}
+	addDryRun(params, opts.DryRun)
+
+	resp = &ModifyVolumeResp{}
 	err = ec2.query(params, resp)
 	if err != nil {
 		return nil, err
 	}
-	return resp, err
+	return resp, nil
 }
 
-type VpnConnectionStruct struct {
-	VpnConnectionId   string `xml:"vpnConnectionId"`
-	State             string `xml:"state"`
-	Type              string `xml:"type"`
-	CustomerGatewayId string `xml:"customerGatewayId"`
-	VpnGatewayId      string `xml:"vpnGatewayId"`
+// VolumeStatusResp represents a response to a DescribeVolumeStatus request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumeStatus.html for more details.
+type VolumeStatusResp struct {
+	RequestId string             `xml:"requestId"`
+	Volumes   []VolumeStatusInfo `xml:"volumeStatusSet>item"`
+	NextToken string             `xml:"nextToken"`
 }
 
-type DescribeVpnConnectionsResp struct {
-	RequestId      string                `xml:"requestId"`
-	VpnConnections []VpnConnectionStruct `xml:"vpnConnectionSet>item"`
+// VolumeStatusInfo describes the status of a volume.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VolumeStatusItem.html for more details.
+type VolumeStatusInfo struct {
+	VolumeId         string               `xml:"volumeId"`
+	AvailabilityZone string               `xml:"availabilityZone"`
+	Status           string               `xml:"volumeStatus>status"` // Valid values: ok | impaired | insufficient-data
+	Details          []VolumeStatusDetail `xml:"volumeStatus>details>item"`
+	Actions          []VolumeStatusAction `xml:"actionsSet>item"`
+	Events           []VolumeStatusEvent  `xml:"eventsSet>item"`
 }
 
-func (ec2 *EC2) DescribeVpnConnections(VpnConnectionIds []string, filter *Filter) (resp *DescribeVpnConnectionsResp, err error) {
-	params := makeParams("DescribeVpnConnections")
-	addParamsList(params, "VpnConnectionId", VpnConnectionIds)
-	filter.addParams(params)
-	resp = &DescribeVpnConnectionsResp{}
-	err = ec2.query(params, resp)
-	if err != nil {
-		return nil, err
-	}
-	return resp, err
+// VolumeStatusDetail describes a single check of a volume's status,
+// such as io-enabled.
+type VolumeStatusDetail struct {
+	Name   string `xml:"name"`   // Valid values: io-enabled | io-performance
+	Status string `xml:"status"` // Valid values: passed | failed | insufficient-data ...
 }
 
-type VpnGatewayStruct struct {
-	VpnGatewayId     string `xml:"vpnGatewayId"`
-	State            string `xml:"state"`
-	Type             string `xml:"type"`
-	AvailabilityZone string `xml:"availabilityZone"`
-	AttachedVpcId    string `xml:"attachments>item>vpcId"`
-	AttachState      string `xml:"attachments>item>state"`
+// VolumeStatusAction describes an action that may be taken in response
+// to a volume status event.
+type VolumeStatusAction struct {
+	Code        string `xml:"code"` // For example: enable-volume-io
+	EventId     string `xml:"eventId"`
+	EventType   string `xml:"eventType"`
+	Description string `xml:"description"`
 }
 
-type DescribeVpnGatewaysResp struct {
-	RequestId  string             `xml:"requestId"`
-	VpnGateway []VpnGatewayStruct `xml:"vpnGatewaySet>item"`
+// VolumeStatusEvent describes an event affecting a volume.
+type VolumeStatusEvent struct {
+	EventId     string `xml:"eventId"`
+	EventType   string `xml:"eventType"`
+	Description string `xml:"description"`
+	NotBefore   string `xml:"notBefore"`
+	NotAfter    string `xml:"notAfter"`
 }
 
-func (ec2 *EC2) DescribeVpnGateways(VpnGatewayIds []string, filter *Filter) (resp *DescribeVpnGatewaysResp, err error) {
-	params := makeParams("DescribeVpnGateways")
-	addParamsList(params, "VpnGatewayIds", VpnGatewayIds)
+// VolumeStatus returns the status of EBS volumes. Both parameters are
+// optional, and if provided will limit the volumes returned to those
+// matching the given ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumeStatus.html for more details.
+func (ec2 *EC2) VolumeStatus(ids []string, filter *Filter) (resp *VolumeStatusResp, err error) {
+	params := makeParams("DescribeVolumeStatus")
+	addParamsList(params, "VolumeId", ids)
 	filter.addParams(params)
-	resp = &DescribeVpnGatewaysResp{}
-	if err = ec2.query(params, resp); err != nil {
+
+	resp = &VolumeStatusResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
 		return nil, err
 	}
-	return resp, err
+	return resp, nil
 }
 
-type InternetGatewayStruct struct {
-	InternetGatewayId string `xml:"internetGatewayId"`
-	AttachedVpcId     string `xml:"attachmentSet>item>vpcId"`
-	AttachState       string `xml:"attachmentSet>item>state"`
-}
+// EnableVolumeIO re-enables I/O on a volume for which EC2 disabled it
+// after detecting potential data inconsistency.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_EnableVolumeIO.html for more details.
+func (ec2 *EC2) EnableVolumeIO(volumeId string) (resp *SimpleResp, err error) {
+	params := makeParams("EnableVolumeIO")
+	params["VolumeId"] = volumeId
 
-type DescribeInternetGatewaysResp struct {
-	RequestId       string                  `xml:"requestId"`
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type VpcStruct struct {
+	VpcId           string `xml:"vpcId" json:"vpcId"`
+	State           string `xml:"state" json:"state"`
+	CidrBlock       string `xml:"cidrBlock" json:"cidrBlock"`
+	DhcpOptionsId   string `xml:"dhcpOptionsId" json:"dhcpOptionsId"`
+	InstanceTenancy string `xml:"instanceTenancy" json:"instanceTenancy"`
+	IsDefault       bool   `xml:"isDefault" json:"isDefault"`
+	Tags            []Tag  `xml:"tagSet>item" json:"tags"`
+}
+
+type DescribeVpcsResp struct {
+	RequestId string      `xml:"requestId" json:"requestId"`
+	Vpcs      []VpcStruct `xml:"vpcSet>item" json:"vpcs"`
+}
+
+func (ec2 *EC2) DescribeVpcs(vpcIds []string, filter *Filter) (resp *DescribeVpcsResp, err error) {
+	params := makeParams("DescribeVpcs")
+	addParamsList(params, "vpcId", vpcIds)
+	filter.addParams(params)
+	resp = &DescribeVpcsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, err
+}
+
+// VpcAttributeResp represents a response to a DescribeVpcAttribute request.
+// Only the attribute which was asked for is set.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcAttribute.html for more details.
+type VpcAttributeResp struct {
+	RequestId          string `xml:"requestId"`
+	VpcId              string `xml:"vpcId"`
+	EnableDnsSupport   bool   `xml:"enableDnsSupport>value"`
+	EnableDnsHostnames bool   `xml:"enableDnsHostnames>value"`
+}
+
+// VpcAttribute describes the given attribute of a VPC. Valid attributes
+// are "enableDnsSupport" and "enableDnsHostnames".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcAttribute.html for more details.
+func (ec2 *EC2) VpcAttribute(vpcId, attribute string) (resp *VpcAttributeResp, err error) {
+	params := makeParams("DescribeVpcAttribute")
+	params["VpcId"] = vpcId
+	params["Attribute"] = attribute
+
+	resp = &VpcAttributeResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// VpcAttributeChange holds the VPC attributes to change with
+// ModifyVpcAttribute. Attributes left nil are not changed.
+type VpcAttributeChange struct {
+	EnableDnsSupport   *bool
+	EnableDnsHostnames *bool
+}
+
+// ModifyVpcAttribute changes the attributes of a VPC which are set in opts.
+// EC2 accepts a single attribute per request, so one request is issued
+// for each of them; the response of the last one is returned.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVpcAttribute.html for more details.
+func (ec2 *EC2) ModifyVpcAttribute(vpcId string, opts *VpcAttributeChange) (resp *SimpleResp, err error) {
+	if opts == nil {
+		opts = &VpcAttributeChange{}
+	}
+	changes := []struct {
+		name  string
+		value *bool
+	}{
+		{"EnableDnsSupport", opts.EnableDnsSupport},
+		{"EnableDnsHostnames", opts.EnableDnsHostnames},
+	}
+	for _, change := range changes {
+		if change.value == nil {
+			continue
+		}
+		params := makeParams("ModifyVpcAttribute")
+		params["VpcId"] = vpcId
+		params[change.name+".Value"] = strconv.FormatBool(*change.value)
+
+		resp = &SimpleResp{}
+		err = ec2.query(params, resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp == nil {
+		return nil, errors.New("no VPC attribute to change")
+	}
+	return resp, nil
+}
+
+type VpnConnectionStruct struct {
+	VpnConnectionId   string `xml:"vpnConnectionId"`
+	State             string `xml:"state"`
+	Type              string `xml:"type"`
+	CustomerGatewayId string `xml:"customerGatewayId"`
+	VpnGatewayId      string `xml:"vpnGatewayId"`
+}
+
+type DescribeVpnConnectionsResp struct {
+	RequestId      string                `xml:"requestId"`
+	VpnConnections []VpnConnectionStruct `xml:"vpnConnectionSet>item"`
+}
+
+func (ec2 *EC2) DescribeVpnConnections(VpnConnectionIds []string, filter *Filter) (resp *DescribeVpnConnectionsResp, err error) {
+	params := makeParams("DescribeVpnConnections")
+	addParamsList(params, "VpnConnectionId", VpnConnectionIds)
+	filter.addParams(params)
+	resp = &DescribeVpnConnectionsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, err
+}
+
+type VpnGatewayStruct struct {
+	VpnGatewayId     string `xml:"vpnGatewayId"`
+	State            string `xml:"state"`
+	Type             string `xml:"type"`
+	AvailabilityZone string `xml:"availabilityZone"`
+	AttachedVpcId    string `xml:"attachments>item>vpcId"`
+	AttachState      string `xml:"attachments>item>state"`
+}
+
+type DescribeVpnGatewaysResp struct {
+	RequestId  string             `xml:"requestId"`
+	VpnGateway []VpnGatewayStruct `xml:"vpnGatewaySet>item"`
+}
+
+func (ec2 *EC2) DescribeVpnGateways(VpnGatewayIds []string, filter *Filter) (resp *DescribeVpnGatewaysResp, err error) {
+	params := makeParams("DescribeVpnGateways")
+	addParamsList(params, "VpnGatewayIds", VpnGatewayIds)
+	filter.addParams(params)
+	resp = &DescribeVpnGatewaysResp{}
+	if err = ec2.query(params, resp); err != nil {
+		return nil, err
+	}
+	return resp, err
+}
+
+type InternetGatewayStruct struct {
+	InternetGatewayId string `xml:"internetGatewayId"`
+	AttachedVpcId     string `xml:"attachmentSet>item>vpcId"`
+	AttachState       string `xml:"attachmentSet>item>state"`
+}
+
+type DescribeInternetGatewaysResp struct {
+	RequestId       string                  `xml:"requestId"`
 	InternetGateway []InternetGatewayStruct `xml:"internetGatewaySet>item"`
 }
 
@@ -1763,3 +4078,2348 @@ func (ec2 *EC2) DescribeInternetGateways(InternetGatewayIds []string, filter *Fi
 	}
 	return resp, err
 }
+
+// ----------------------------------------------------------------------------
+// Placement group management functions and types.
+
+// PlacementGroup represents a placement group in EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribePlacementGroups.html for more details.
+type PlacementGroup struct {
+	Name     string `xml:"groupName"`
+	State    string `xml:"state"`    // Valid values: pending | available | deleting | deleted
+	Strategy string `xml:"strategy"` // Valid values: cluster | spread | partition
+}
+
+// PlacementGroupsResp represents a response to a DescribePlacementGroups
+// request in EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribePlacementGroups.html for more details.
+type PlacementGroupsResp struct {
+	RequestId       string           `xml:"requestId"`
+	PlacementGroups []PlacementGroup `xml:"placementGroupSet>item"`
+}
+
+// CreatePlacementGroup creates a placement group with the given name into
+// which instances can later be launched. Strategy must be one of cluster,
+// spread or partition.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreatePlacementGroup.html for more details.
+func (ec2 *EC2) CreatePlacementGroup(name, strategy string) (resp *SimpleResp, err error) {
+	params := makeParams("CreatePlacementGroup")
+	// The spread and partition strategies are unknown to the default API
+	// version.
+	params["Version"] = newAPIVersion
+	params["GroupName"] = name
+	params["Strategy"] = strategy
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeletePlacementGroup deletes the placement group with the given name.
+// All instances in the group must be terminated first.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeletePlacementGroup.html for more details.
+func (ec2 *EC2) DeletePlacementGroup(name string) (resp *SimpleResp, err error) {
+	params := makeParams("DeletePlacementGroup")
+	params["GroupName"] = name
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// PlacementGroups returns details about placement groups in EC2. Both
+// parameters are optional, and if provided will limit the placement groups
+// returned to those matching the given names or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribePlacementGroups.html for more details.
+func (ec2 *EC2) PlacementGroups(names []string, filter *Filter) (resp *PlacementGroupsResp, err error) {
+	params := makeParams("DescribePlacementGroups")
+	addParamsList(params, "GroupName", names)
+	filter.addParams(params)
+
+	resp = &PlacementGroupsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// Instance type offerings.
+
+// InstanceTypeOffering describes an instance type available at a location.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InstanceTypeOffering.html for more details.
+type InstanceTypeOffering struct {
+	InstanceType string `xml:"instanceType"`
+	LocationType string `xml:"locationType"` // Valid values: region | availability-zone | availability-zone-id
+	Location     string `xml:"location"`
+}
+
+// InstanceTypeOfferingsResp represents a response to a
+// DescribeInstanceTypeOfferings request in EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceTypeOfferings.html for more details.
+type InstanceTypeOfferingsResp struct {
+	RequestId string                 `xml:"requestId"`
+	Offerings []InstanceTypeOffering `xml:"instanceTypeOfferingSet>item"`
+	NextToken string                 `xml:"nextToken"`
+}
+
+// InstanceTypeOfferings returns the instance types offered at each location
+// of the given type (region, availability-zone or availability-zone-id).
+// If locationType is empty, offerings for the region are returned. The
+// "location" and "instance-type" filters may be used to check whether an
+// instance type is available in a given zone before calling RunInstances.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceTypeOfferings.html for more details.
+func (ec2 *EC2) InstanceTypeOfferings(locationType string, filter *Filter) (resp *InstanceTypeOfferingsResp, err error) {
+	params := makeParams("DescribeInstanceTypeOfferings")
+	params["Version"] = newAPIVersion
+	if locationType != "" {
+		params["LocationType"] = locationType
+	}
+	filter.addParams(params)
+
+	resp = &InstanceTypeOfferingsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// InstanceTypeInfo describes the hardware of an instance type.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InstanceTypeInfo.html for more details.
+type InstanceTypeInfo struct {
+	InstanceType           string      `xml:"instanceType"`
+	CurrentGeneration      bool        `xml:"currentGeneration"`
+	VCpuInfo               VCpuInfo    `xml:"vCpuInfo"`
+	MemoryInfo             MemoryInfo  `xml:"memoryInfo"`
+	NetworkInfo            NetworkInfo `xml:"networkInfo"`
+	SupportedArchitectures []string    `xml:"processorInfo>supportedArchitectures>item"` // i386 | x86_64 | arm64
+}
+
+// VCpuInfo describes the vCPUs of an instance type.
+type VCpuInfo struct {
+	DefaultVCpus          int `xml:"defaultVCpus"`
+	DefaultCores          int `xml:"defaultCores"`
+	DefaultThreadsPerCore int `xml:"defaultThreadsPerCore"`
+}
+
+// MemoryInfo describes the memory of an instance type.
+type MemoryInfo struct {
+	SizeInMiB int64 `xml:"sizeInMiB"`
+}
+
+// NetworkInfo describes the networking features of an instance type.
+type NetworkInfo struct {
+	NetworkPerformance        string `xml:"networkPerformance"`
+	MaximumNetworkInterfaces  int    `xml:"maximumNetworkInterfaces"`
+	Ipv4AddressesPerInterface int    `xml:"ipv4AddressesPerInterface"`
+	Ipv6Supported             bool   `xml:"ipv6Supported"`
+	EnaSupport                string `xml:"enaSupport"` // unsupported | supported | required
+}
+
+// InstanceTypeInfoResp represents a response to a DescribeInstanceTypes
+// request in EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceTypes.html for more details.
+type InstanceTypeInfoResp struct {
+	RequestId     string             `xml:"requestId"`
+	InstanceTypes []InstanceTypeInfo `xml:"instanceTypeSet>item"`
+	NextToken     string             `xml:"nextToken"`
+}
+
+// InstanceTypeInfo returns the vCPU, memory, network and architecture
+// details of the given instance types, or of all instance types available
+// in the region if types is empty. All pages of results are fetched and
+// returned together in a single response.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceTypes.html for more details.
+func (ec2 *EC2) InstanceTypeInfo(types []string) (resp *InstanceTypeInfoResp, err error) {
+	resp = &InstanceTypeInfoResp{}
+	nextToken := ""
+	for {
+		params := makeParams("DescribeInstanceTypes")
+		params["Version"] = newAPIVersion
+		if nextToken != "" {
+			params["NextToken"] = nextToken
+		}
+		addParamsList(params, "InstanceType", types)
+
+		page := &InstanceTypeInfoResp{}
+		err = ec2.query(params, page)
+		if err != nil {
+			return nil, err
+		}
+		resp.RequestId = page.RequestId
+		resp.InstanceTypes = append(resp.InstanceTypes, page.InstanceTypes...)
+		if page.NextToken == "" {
+			return resp, nil
+		}
+		nextToken = page.NextToken
+	}
+}
+
+// ----------------------------------------------------------------------------
+// IAM instance profile associations.
+
+// IamInstanceProfileAssociation describes the association of an IAM
+// instance profile with an instance.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_IamInstanceProfileAssociation.html for more details.
+type IamInstanceProfileAssociation struct {
+	AssociationId      string             `xml:"associationId"`
+	InstanceId         string             `xml:"instanceId"`
+	IamInstanceProfile IamInstanceProfile `xml:"iamInstanceProfile"`
+	State              string             `xml:"state"` // Valid values: associating | associated | disassociating | disassociated
+	Timestamp          string             `xml:"timestamp"`
+}
+
+// IamProfileAssociationResp represents a response to an
+// AssociateIamInstanceProfile or DisassociateIamInstanceProfile request.
+type IamProfileAssociationResp struct {
+	RequestId   string                        `xml:"requestId"`
+	Association IamInstanceProfileAssociation `xml:"iamInstanceProfileAssociation"`
+}
+
+// IamInstanceProfileAssociationsResp represents a response to a
+// DescribeIamInstanceProfileAssociations request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeIamInstanceProfileAssociations.html for more details.
+type IamInstanceProfileAssociationsResp struct {
+	RequestId    string                          `xml:"requestId"`
+	Associations []IamInstanceProfileAssociation `xml:"iamInstanceProfileAssociationSet>item"`
+	NextToken    string                          `xml:"nextToken"`
+}
+
+// AssociateIamInstanceProfile associates an IAM instance profile with a
+// running or stopped instance. The profile is identified by its ARN or,
+// if that is empty, by its name.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateIamInstanceProfile.html for more details.
+func (ec2 *EC2) AssociateIamInstanceProfile(instanceId string, profile IamInstanceProfile) (resp *IamProfileAssociationResp, err error) {
+	params := makeParams("AssociateIamInstanceProfile")
+	params["Version"] = newAPIVersion
+	params["InstanceId"] = instanceId
+	if profile.ARN != "" {
+		params["IamInstanceProfile.Arn"] = profile.ARN
+	} else {
+		params["IamInstanceProfile.Name"] = profile.Name
+	}
+
+	resp = &IamProfileAssociationResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DisassociateIamInstanceProfile removes the IAM instance profile
+// association with the given id from its instance.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateIamInstanceProfile.html for more details.
+func (ec2 *EC2) DisassociateIamInstanceProfile(associationId string) (resp *IamProfileAssociationResp, err error) {
+	params := makeParams("DisassociateIamInstanceProfile")
+	params["Version"] = newAPIVersion
+	params["AssociationId"] = associationId
+
+	resp = &IamProfileAssociationResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReplaceIamInstanceProfileAssociation replaces the IAM instance profile
+// of the association with the given id, without leaving the instance
+// without a profile in between. The profile is identified by its ARN or,
+// if that is empty, by its name.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReplaceIamInstanceProfileAssociation.html for more details.
+func (ec2 *EC2) ReplaceIamInstanceProfileAssociation(associationId string, profile IamInstanceProfile) (resp *IamProfileAssociationResp, err error) {
+	params := makeParams("ReplaceIamInstanceProfileAssociation")
+	params["Version"] = newAPIVersion
+	params["AssociationId"] = associationId
+	if profile.ARN != "" {
+		params["IamInstanceProfile.Arn"] = profile.ARN
+	} else {
+		params["IamInstanceProfile.Name"] = profile.Name
+	}
+
+	resp = &IamProfileAssociationResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// IamInstanceProfileAssociations returns the IAM instance profile
+// associations, optionally limited by the given filtering rules such as
+// "instance-id" or "state".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeIamInstanceProfileAssociations.html for more details.
+func (ec2 *EC2) IamInstanceProfileAssociations(filter *Filter) (resp *IamInstanceProfileAssociationsResp, err error) {
+	params := makeParams("DescribeIamInstanceProfileAssociations")
+	params["Version"] = newAPIVersion
+	filter.addParams(params)
+
+	resp = &IamInstanceProfileAssociationsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// Spot fleet requests.
+
+// SpotFleetRequestConfig encapsulates options for the RequestSpotFleet call.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_SpotFleetRequestConfigData.html for more details.
+type SpotFleetRequestConfig struct {
+	IamFleetRole                     string
+	TargetCapacity                   int
+	SpotPrice                        string // Maximum price per unit hour; optional
+	AllocationStrategy               string // Valid values: lowestPrice | diversified
+	ClientToken                      string // Generated if empty
+	TerminateInstancesWithExpiration bool
+	LaunchSpecifications             []SpotFleetLaunchSpecification
+}
+
+// SpotFleetLaunchSpecification describes one of the launch configurations
+// a spot fleet may use to fulfil its target capacity. The fields mirror
+// those of RunInstancesOptions.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_SpotFleetLaunchSpecification.html for more details.
+type SpotFleetLaunchSpecification struct {
+	ImageId             string
+	InstanceType        string
+	KeyName             string
+	SecurityGroups      []SecurityGroup // Only the Id of each group is used
+	SubnetId            string
+	AvailabilityZone    string
+	UserData            []byte
+	IamInstanceProfile  IamInstanceProfile
+	BlockDeviceMappings []BlockDeviceMapping
+	EbsOptimized        bool
+	Monitoring          bool
+	SpotPrice           string  // Overrides the fleet's SpotPrice for this specification
+	WeightedCapacity    float64 // Units of capacity an instance counts for; defaults to 1
+}
+
+// RequestSpotFleetResp represents a response to a RequestSpotFleet request.
+type RequestSpotFleetResp struct {
+	RequestId          string `xml:"requestId"`
+	SpotFleetRequestId string `xml:"spotFleetRequestId"`
+	ClientToken        string `xml:"-"` // The idempotency token sent with the request
+}
+
+// RequestSpotFleet creates a spot fleet request which launches and
+// maintains spot instances up to the target capacity of config.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RequestSpotFleet.html for more details.
+func (ec2 *EC2) RequestSpotFleet(config *SpotFleetRequestConfig) (resp *RequestSpotFleetResp, err error) {
+	for _, spec := range config.LaunchSpecifications {
+		if err := validateBlockDeviceMappings(spec.BlockDeviceMappings); err != nil {
+			return nil, err
+		}
+	}
+	params := makeParams("RequestSpotFleet")
+	params["Version"] = newAPIVersion
+	prefix := "SpotFleetRequestConfig."
+	params[prefix+"IamFleetRole"] = config.IamFleetRole
+	params[prefix+"TargetCapacity"] = strconv.Itoa(config.TargetCapacity)
+	if config.SpotPrice != "" {
+		params[prefix+"SpotPrice"] = config.SpotPrice
+	}
+	if config.AllocationStrategy != "" {
+		params[prefix+"AllocationStrategy"] = config.AllocationStrategy
+	}
+	token, err := orClientToken(config.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	params[prefix+"ClientToken"] = token
+	if config.TerminateInstancesWithExpiration {
+		params[prefix+"TerminateInstancesWithExpiration"] = "true"
+	}
+	for i, spec := range config.LaunchSpecifications {
+		spec.addParams(params, prefix+"LaunchSpecifications."+strconv.Itoa(i+1)+".")
+	}
+
+	resp = &RequestSpotFleetResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.ClientToken = token
+	return resp, nil
+}
+
+func (spec *SpotFleetLaunchSpecification) addParams(params map[string]string, prefix string) {
+	params[prefix+"ImageId"] = spec.ImageId
+	params[prefix+"InstanceType"] = spec.InstanceType
+	if spec.KeyName != "" {
+		params[prefix+"KeyName"] = spec.KeyName
+	}
+	for j, g := range spec.SecurityGroups {
+		params[prefix+"GroupSet."+strconv.Itoa(j+1)+".GroupId"] = g.Id
+	}
+	if spec.SubnetId != "" {
+		params[prefix+"SubnetId"] = spec.SubnetId
+	}
+	if spec.AvailabilityZone != "" {
+		params[prefix+"Placement.AvailabilityZone"] = spec.AvailabilityZone
+	}
+	if spec.UserData != nil {
+		params[prefix+"UserData"] = base64.StdEncoding.EncodeToString(spec.UserData)
+	}
+	if spec.IamInstanceProfile.ARN != "" {
+		params[prefix+"IamInstanceProfile.Arn"] = spec.IamInstanceProfile.ARN
+	} else if spec.IamInstanceProfile.Name != "" {
+		params[prefix+"IamInstanceProfile.Name"] = spec.IamInstanceProfile.Name
+	}
+	addBlockDeviceMappings(params, prefix, spec.BlockDeviceMappings)
+	if spec.EbsOptimized {
+		params[prefix+"EbsOptimized"] = "true"
+	}
+	if spec.Monitoring {
+		params[prefix+"Monitoring.Enabled"] = "true"
+	}
+	if spec.SpotPrice != "" {
+		params[prefix+"SpotPrice"] = spec.SpotPrice
+	}
+	if spec.WeightedCapacity != 0 {
+		params[prefix+"WeightedCapacity"] = strconv.FormatFloat(spec.WeightedCapacity, 'f', -1, 64)
+	}
+}
+
+// SpotFleetRequest describes the state of a spot fleet request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_SpotFleetRequestConfig.html for more details.
+type SpotFleetRequest struct {
+	SpotFleetRequestId string `xml:"spotFleetRequestId"`
+	State              string `xml:"spotFleetRequestState"` // Valid values: submitted | active | cancelled | failed | cancelled_running | cancelled_terminating | modifying
+	ActivityStatus     string `xml:"activityStatus"`        // Valid values: error | pending_fulfillment | pending_termination | fulfilled
+	CreateTime         string `xml:"createTime"`
+	IamFleetRole       string `xml:"spotFleetRequestConfig>iamFleetRole"`
+	TargetCapacity     int    `xml:"spotFleetRequestConfig>targetCapacity"`
+	SpotPrice          string `xml:"spotFleetRequestConfig>spotPrice"`
+	AllocationStrategy string `xml:"spotFleetRequestConfig>allocationStrategy"`
+}
+
+// SpotFleetRequestsResp represents a response to a
+// DescribeSpotFleetRequests request.
+type SpotFleetRequestsResp struct {
+	RequestId         string             `xml:"requestId"`
+	SpotFleetRequests []SpotFleetRequest `xml:"spotFleetRequestConfigSet>item"`
+	NextToken         string             `xml:"nextToken"`
+}
+
+// DescribeSpotFleetRequests returns details about the spot fleet requests
+// with the given ids, or about all of them if ids is empty.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSpotFleetRequests.html for more details.
+func (ec2 *EC2) DescribeSpotFleetRequests(ids []string) (resp *SpotFleetRequestsResp, err error) {
+	params := makeParams("DescribeSpotFleetRequests")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "SpotFleetRequestId", ids)
+
+	resp = &SpotFleetRequestsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CancelSpotFleetRequestsResp represents a response to a
+// CancelSpotFleetRequests request.
+type CancelSpotFleetRequestsResp struct {
+	RequestId    string                        `xml:"requestId"`
+	Successful   []CancelledSpotFleetRequest   `xml:"successfulFleetRequestSet>item"`
+	Unsuccessful []UncancelledSpotFleetRequest `xml:"unsuccessfulFleetRequestSet>item"`
+}
+
+// CancelledSpotFleetRequest describes a spot fleet request that was
+// successfully cancelled.
+type CancelledSpotFleetRequest struct {
+	SpotFleetRequestId string `xml:"spotFleetRequestId"`
+	CurrentState       string `xml:"currentSpotFleetRequestState"`
+	PreviousState      string `xml:"previousSpotFleetRequestState"`
+}
+
+// UncancelledSpotFleetRequest describes a spot fleet request that could
+// not be cancelled, and why.
+type UncancelledSpotFleetRequest struct {
+	SpotFleetRequestId string `xml:"spotFleetRequestId"`
+	Code               string `xml:"error>code"`
+	Message            string `xml:"error>message"`
+}
+
+// CancelSpotFleetRequests cancels the spot fleet requests with the given
+// ids. If terminateInstances is true the instances launched by the fleets
+// are terminated as well; otherwise they keep running.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CancelSpotFleetRequests.html for more details.
+func (ec2 *EC2) CancelSpotFleetRequests(ids []string, terminateInstances bool) (resp *CancelSpotFleetRequestsResp, err error) {
+	params := makeParams("CancelSpotFleetRequests")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "SpotFleetRequestId", ids)
+	params["TerminateInstances"] = strconv.FormatBool(terminateInstances)
+
+	resp = &CancelSpotFleetRequestsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// Key pairs.
+
+// KeyPair describes a key pair registered with EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_KeyPairInfo.html for more details.
+type KeyPair struct {
+	Name        string `xml:"keyName"`
+	Fingerprint string `xml:"keyFingerprint"`
+}
+
+// KeyPairsResp represents a response to a DescribeKeyPairs request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeKeyPairs.html for more details.
+type KeyPairsResp struct {
+	RequestId string    `xml:"requestId"`
+	Keys      []KeyPair `xml:"keySet>item"`
+}
+
+// KeyPairs returns the key pairs available to the account. Both parameters
+// are optional, and if provided will limit the key pairs returned to those
+// with the given names or matching the filtering rules, such as
+// "key-name" or "key-fingerprint".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeKeyPairs.html for more details.
+func (ec2 *EC2) KeyPairs(names []string, filter *Filter) (resp *KeyPairsResp, err error) {
+	params := makeParams("DescribeKeyPairs")
+	addParamsList(params, "KeyName", names)
+	filter.addParams(params)
+
+	resp = &KeyPairsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// Network interface private addresses.
+
+// AssignPrivateIpAddresses assigns secondary private IP addresses to a
+// network interface. Either the addresses to assign are given, or
+// secondaryCount addresses are picked by EC2 from the subnet of the
+// interface. If allowReassignment is true, addresses already assigned to
+// another interface are moved to this one.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssignPrivateIpAddresses.html for more details.
+func (ec2 *EC2) AssignPrivateIpAddresses(networkInterfaceId string, addresses []string, secondaryCount int, allowReassignment bool) (resp *SimpleResp, err error) {
+	params := makeParams("AssignPrivateIpAddresses")
+	params["NetworkInterfaceId"] = networkInterfaceId
+	addParamsList(params, "PrivateIpAddress", addresses)
+	if secondaryCount > 0 {
+		params["SecondaryPrivateIpAddressCount"] = strconv.Itoa(secondaryCount)
+	}
+	if allowReassignment {
+		params["AllowReassignment"] = "true"
+	}
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UnassignPrivateIpAddresses removes the given secondary private IP
+// addresses from a network interface.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_UnassignPrivateIpAddresses.html for more details.
+func (ec2 *EC2) UnassignPrivateIpAddresses(networkInterfaceId string, addresses []string) (resp *SimpleResp, err error) {
+	params := makeParams("UnassignPrivateIpAddresses")
+	params["NetworkInterfaceId"] = networkInterfaceId
+	addParamsList(params, "PrivateIpAddress", addresses)
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// NAT gateways.
+
+// NatGateway describes a NAT gateway in a VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_NatGateway.html for more details.
+type NatGateway struct {
+	NatGatewayId        string              `xml:"natGatewayId"`
+	SubnetId            string              `xml:"subnetId"`
+	VpcId               string              `xml:"vpcId"`
+	State               string              `xml:"state"` // pending | failed | available | deleting | deleted
+	CreateTime          string              `xml:"createTime"`
+	DeleteTime          string              `xml:"deleteTime"`
+	FailureCode         string              `xml:"failureCode"`
+	FailureMessage      string              `xml:"failureMessage"`
+	NatGatewayAddresses []NatGatewayAddress `xml:"natGatewayAddressSet>item"`
+	Tags                []Tag               `xml:"tagSet>item"`
+}
+
+// NatGatewayAddress describes the Elastic IP address and network
+// interface of a NAT gateway.
+type NatGatewayAddress struct {
+	AllocationId       string `xml:"allocationId"`
+	NetworkInterfaceId string `xml:"networkInterfaceId"`
+	PrivateIp          string `xml:"privateIp"`
+	PublicIp           string `xml:"publicIp"`
+}
+
+// CreateNatGatewayResp represents a response to a CreateNatGateway request.
+type CreateNatGatewayResp struct {
+	RequestId   string     `xml:"requestId"`
+	ClientToken string     `xml:"clientToken"`
+	NatGateway  NatGateway `xml:"natGateway"`
+}
+
+// CreateNatGateway creates a NAT gateway in the given public subnet, using
+// the Elastic IP address with the given allocation id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateNatGateway.html for more details.
+func (ec2 *EC2) CreateNatGateway(subnetId, allocationId string) (resp *CreateNatGatewayResp, err error) {
+	return ec2.CreateNatGatewayWithOptions(&CreateNatGatewayOptions{SubnetId: subnetId, AllocationId: allocationId})
+}
+
+// CreateNatGatewayOptions encapsulates options for the
+// CreateNatGatewayWithOptions call.
+type CreateNatGatewayOptions struct {
+	SubnetId     string
+	AllocationId string
+	ClientToken  string // Generated if empty
+}
+
+// CreateNatGatewayWithOptions creates a NAT gateway, like
+// CreateNatGateway, but also supports the ClientToken parameter.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateNatGateway.html for more details.
+func (ec2 *EC2) CreateNatGatewayWithOptions(opts *CreateNatGatewayOptions) (resp *CreateNatGatewayResp, err error) {
+	params := makeParams("CreateNatGateway")
+	params["Version"] = newAPIVersion
+	params["SubnetId"] = opts.SubnetId
+	params["AllocationId"] = opts.AllocationId
+	token, err := orClientToken(opts.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
+
+	resp = &CreateNatGatewayResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteNatGatewayResp represents a response to a DeleteNatGateway request.
+type DeleteNatGatewayResp struct {
+	RequestId    string `xml:"requestId"`
+	NatGatewayId string `xml:"natGatewayId"`
+}
+
+// DeleteNatGateway deletes a NAT gateway. Its Elastic IP address is
+// disassociated but not released.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteNatGateway.html for more details.
+func (ec2 *EC2) DeleteNatGateway(id string) (resp *DeleteNatGatewayResp, err error) {
+	params := makeParams("DeleteNatGateway")
+	params["Version"] = newAPIVersion
+	params["NatGatewayId"] = id
+
+	resp = &DeleteNatGatewayResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// NatGatewaysResp represents a response to a DescribeNatGateways request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeNatGateways.html for more details.
+type NatGatewaysResp struct {
+	RequestId   string       `xml:"requestId"`
+	NatGateways []NatGateway `xml:"natGatewaySet>item"`
+}
+
+// NatGateways returns details about NAT gateways. Both parameters are
+// optional, and if provided will limit the gateways returned to those
+// matching the given ids or filtering rules, such as "vpc-id" or "state".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeNatGateways.html for more details.
+func (ec2 *EC2) NatGateways(ids []string, filter *Filter) (resp *NatGatewaysResp, err error) {
+	params := makeParams("DescribeNatGateways")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "NatGatewayId", ids)
+	filter.addParams(params)
+
+	resp = &NatGatewaysResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// DHCP options sets.
+
+// DhcpConfig is a single DHCP option of a DHCP options set, such as
+// "domain-name", "domain-name-servers", "ntp-servers",
+// "netbios-name-servers" or "netbios-node-type".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DhcpConfiguration.html for more details.
+type DhcpConfig struct {
+	Key    string   `xml:"key"`
+	Values []string `xml:"valueSet>item>value"`
+}
+
+// DhcpOptions describes a set of DHCP options.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DhcpOptions.html for more details.
+type DhcpOptions struct {
+	DhcpOptionsId  string       `xml:"dhcpOptionsId"`
+	OwnerId        string       `xml:"ownerId"`
+	Configurations []DhcpConfig `xml:"dhcpConfigurationSet>item"`
+	Tags           []Tag        `xml:"tagSet>item"`
+}
+
+// CreateDhcpOptionsResp represents a response to a CreateDhcpOptions request.
+type CreateDhcpOptionsResp struct {
+	RequestId   string      `xml:"requestId"`
+	DhcpOptions DhcpOptions `xml:"dhcpOptions"`
+}
+
+// CreateDhcpOptions creates a set of DHCP options holding the given
+// configurations. The set can then be associated with VPCs using
+// AssociateDhcpOptions.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateDhcpOptions.html for more details.
+func (ec2 *EC2) CreateDhcpOptions(configs []DhcpConfig) (resp *CreateDhcpOptionsResp, err error) {
+	params := makeParams("CreateDhcpOptions")
+	for i, config := range configs {
+		prefix := "DhcpConfiguration." + strconv.Itoa(i+1) + "."
+		params[prefix+"Key"] = config.Key
+		addParamsList(params, prefix+"Value", config.Values)
+	}
+
+	resp = &CreateDhcpOptionsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteDhcpOptions deletes a set of DHCP options. The set must not be
+// associated with any VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteDhcpOptions.html for more details.
+func (ec2 *EC2) DeleteDhcpOptions(id string) (resp *SimpleResp, err error) {
+	params := makeParams("DeleteDhcpOptions")
+	params["DhcpOptionsId"] = id
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AssociateDhcpOptions associates a set of DHCP options with a VPC,
+// replacing the set previously associated with it. Instances pick up the
+// new options when their DHCP lease is renewed. Pass "default" as
+// dhcpOptionsId to go back to the default options, or "none" to use none.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateDhcpOptions.html for more details.
+func (ec2 *EC2) AssociateDhcpOptions(dhcpOptionsId, vpcId string) (resp *SimpleResp, err error) {
+	params := makeParams("AssociateDhcpOptions")
+	params["DhcpOptionsId"] = dhcpOptionsId
+	params["VpcId"] = vpcId
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DhcpOptionsResp represents a response to a DescribeDhcpOptions request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeDhcpOptions.html for more details.
+type DhcpOptionsResp struct {
+	RequestId   string        `xml:"requestId"`
+	DhcpOptions []DhcpOptions `xml:"dhcpOptionsSet>item"`
+}
+
+// DhcpOptions returns details about sets of DHCP options. Both parameters
+// are optional, and if provided will limit the sets returned to those
+// matching the given ids or filtering rules, such as "key" or "value".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeDhcpOptions.html for more details.
+func (ec2 *EC2) DhcpOptions(ids []string, filter *Filter) (resp *DhcpOptionsResp, err error) {
+	params := makeParams("DescribeDhcpOptions")
+	addParamsList(params, "DhcpOptionsId", ids)
+	filter.addParams(params)
+
+	resp = &DhcpOptionsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// Network ACLs.
+
+// NetworkAcl describes a network ACL, which controls the traffic in and
+// out of the subnets associated with it.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_NetworkAcl.html for more details.
+type NetworkAcl struct {
+	NetworkAclId string                  `xml:"networkAclId"`
+	VpcId        string                  `xml:"vpcId"`
+	Default      bool                    `xml:"default"`
+	Entries      []NetworkAclEntry       `xml:"entrySet>item"`
+	Associations []NetworkAclAssociation `xml:"associationSet>item"`
+	Tags         []Tag                   `xml:"tagSet>item"`
+}
+
+// NetworkAclEntry is a single numbered rule of a network ACL. Rules are
+// evaluated in increasing RuleNumber order, separately for inbound and
+// egress traffic.
+//
+// Protocol is a protocol number, or "-1" for all protocols.
+// CreateNetworkAclEntry also accepts names such as ProtocolTCP, in any
+// case, and sends them as their numbers. The port range applies to TCP
+// ("6") and UDP ("17") only, and the ICMP type and code to ICMP ("1") and
+// ICMPv6 ("58") only.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_NetworkAclEntry.html for more details.
+type NetworkAclEntry struct {
+	RuleNumber int    `xml:"ruleNumber"`
+	Protocol   string `xml:"protocol"`
+	RuleAction string `xml:"ruleAction"` // allow | deny
+	Egress     bool   `xml:"egress"`
+	CidrBlock  string `xml:"cidrBlock"`
+	FromPort   int    `xml:"portRange>from"`
+	ToPort     int    `xml:"portRange>to"`
+	IcmpType   int    `xml:"icmpTypeCode>type"`
+	IcmpCode   int    `xml:"icmpTypeCode>code"`
+}
+
+// NetworkAclAssociation describes the association of a network ACL with
+// a subnet.
+type NetworkAclAssociation struct {
+	NetworkAclAssociationId string `xml:"networkAclAssociationId"`
+	NetworkAclId            string `xml:"networkAclId"`
+	SubnetId                string `xml:"subnetId"`
+}
+
+// CreateNetworkAclResp represents a response to a CreateNetworkAcl request.
+type CreateNetworkAclResp struct {
+	RequestId  string     `xml:"requestId"`
+	NetworkAcl NetworkAcl `xml:"networkAcl"`
+}
+
+// CreateNetworkAcl creates a network ACL in a VPC. A new network ACL
+// denies all traffic until entries are added to it.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateNetworkAcl.html for more details.
+func (ec2 *EC2) CreateNetworkAcl(vpcId string) (resp *CreateNetworkAclResp, err error) {
+	params := makeParams("CreateNetworkAcl")
+	params["VpcId"] = vpcId
+
+	resp = &CreateNetworkAclResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteNetworkAcl deletes a network ACL. The default network ACL of a
+// VPC and ACLs still associated with subnets cannot be deleted.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteNetworkAcl.html for more details.
+func (ec2 *EC2) DeleteNetworkAcl(id string) (resp *SimpleResp, err error) {
+	params := makeParams("DeleteNetworkAcl")
+	params["NetworkAclId"] = id
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// NetworkAclsResp represents a response to a DescribeNetworkAcls request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeNetworkAcls.html for more details.
+type NetworkAclsResp struct {
+	RequestId   string       `xml:"requestId"`
+	NetworkAcls []NetworkAcl `xml:"networkAclSet>item"`
+}
+
+// NetworkAcls returns details about network ACLs. Both parameters are
+// optional, and if provided will limit the ACLs returned to those matching
+// the given ids or filtering rules, such as "vpc-id" or
+// "association.subnet-id".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeNetworkAcls.html for more details.
+func (ec2 *EC2) NetworkAcls(ids []string, filter *Filter) (resp *NetworkAclsResp, err error) {
+	params := makeParams("DescribeNetworkAcls")
+	addParamsList(params, "NetworkAclId", ids)
+	filter.addParams(params)
+
+	resp = &NetworkAclsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// aclProtocolNumbers maps protocol names to the numbers network ACL
+// entries require.
+var aclProtocolNumbers = map[string]string{
+	ProtocolTCP:    "6",
+	ProtocolUDP:    "17",
+	ProtocolICMP:   "1",
+	ProtocolICMPv6: "58",
+}
+
+// aclProtocolNumber returns protocol as a protocol number, as network ACL
+// entries reject protocol names.
+func aclProtocolNumber(protocol string) (string, error) {
+	protocol, err := normalizeProtocol(protocol)
+	if err != nil {
+		return "", err
+	}
+	if n, ok := aclProtocolNumbers[protocol]; ok {
+		return n, nil
+	}
+	return protocol, nil
+}
+
+// CreateNetworkAclEntry adds a rule to a network ACL. An existing rule
+// with the same number and direction must first be deleted.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateNetworkAclEntry.html for more details.
+func (ec2 *EC2) CreateNetworkAclEntry(networkAclId string, entry *NetworkAclEntry) (resp *SimpleResp, err error) {
+	protocol, err := aclProtocolNumber(entry.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	params := makeParams("CreateNetworkAclEntry")
+	params["NetworkAclId"] = networkAclId
+	params["RuleNumber"] = strconv.Itoa(entry.RuleNumber)
+	params["Protocol"] = protocol
+	params["RuleAction"] = entry.RuleAction
+	params["Egress"] = strconv.FormatBool(entry.Egress)
+	params["CidrBlock"] = entry.CidrBlock
+	switch protocol {
+	case "6", "17":
+		params["PortRange.From"] = strconv.Itoa(entry.FromPort)
+		params["PortRange.To"] = strconv.Itoa(entry.ToPort)
+	case "1", "58":
+		params["Icmp.Type"] = strconv.Itoa(entry.IcmpType)
+		params["Icmp.Code"] = strconv.Itoa(entry.IcmpCode)
+	}
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteNetworkAclEntry deletes the inbound or egress rule with the given
+// number from a network ACL.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteNetworkAclEntry.html for more details.
+func (ec2 *EC2) DeleteNetworkAclEntry(networkAclId string, ruleNumber int, egress bool) (resp *SimpleResp, err error) {
+	params := makeParams("DeleteNetworkAclEntry")
+	params["NetworkAclId"] = networkAclId
+	params["RuleNumber"] = strconv.Itoa(ruleNumber)
+	params["Egress"] = strconv.FormatBool(egress)
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReplaceNetworkAclAssociationResp represents a response to a
+// ReplaceNetworkAclAssociation request.
+type ReplaceNetworkAclAssociationResp struct {
+	RequestId        string `xml:"requestId"`
+	NewAssociationId string `xml:"newAssociationId"`
+}
+
+// ReplaceNetworkAclAssociation changes the network ACL associated with a
+// subnet. The association id is that of the subnet's current association,
+// as found in NetworkAcl.Associations.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReplaceNetworkAclAssociation.html for more details.
+func (ec2 *EC2) ReplaceNetworkAclAssociation(associationId, networkAclId string) (resp *ReplaceNetworkAclAssociationResp, err error) {
+	params := makeParams("ReplaceNetworkAclAssociation")
+	params["AssociationId"] = associationId
+	params["NetworkAclId"] = networkAclId
+
+	resp = &ReplaceNetworkAclAssociationResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// VPC peering connections.
+
+// VpcPeeringConnection describes a peering connection between two VPCs.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VpcPeeringConnection.html for more details.
+type VpcPeeringConnection struct {
+	VpcPeeringConnectionId string                      `xml:"vpcPeeringConnectionId"`
+	RequesterVpcInfo       VpcPeeringConnectionVpcInfo `xml:"requesterVpcInfo"`
+	AccepterVpcInfo        VpcPeeringConnectionVpcInfo `xml:"accepterVpcInfo"`
+	StatusCode             string                      `xml:"status>code"` // pending-acceptance | active | deleted | rejected | failed | expired | provisioning | deleting
+	StatusMessage          string                      `xml:"status>message"`
+	ExpirationTime         string                      `xml:"expirationTime"`
+	Tags                   []Tag                       `xml:"tagSet>item"`
+}
+
+// VpcPeeringConnectionVpcInfo describes one side of a VPC peering
+// connection.
+type VpcPeeringConnectionVpcInfo struct {
+	VpcId     string `xml:"vpcId"`
+	OwnerId   string `xml:"ownerId"`
+	CidrBlock string `xml:"cidrBlock"`
+	Region    string `xml:"region"`
+}
+
+// VpcPeeringConnectionResp represents a response to a
+// CreateVpcPeeringConnection or AcceptVpcPeeringConnection request.
+type VpcPeeringConnectionResp struct {
+	RequestId            string               `xml:"requestId"`
+	VpcPeeringConnection VpcPeeringConnection `xml:"vpcPeeringConnection"`
+}
+
+// CreateVpcPeeringConnection requests a peering connection from vpcId to
+// peerVpcId. peerOwnerId and peerRegion are optional, and default to the
+// account and region of the requester; a connection to another account
+// must be accepted by that account with AcceptVpcPeeringConnection.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVpcPeeringConnection.html for more details.
+func (ec2 *EC2) CreateVpcPeeringConnection(vpcId, peerVpcId, peerOwnerId, peerRegion string) (resp *VpcPeeringConnectionResp, err error) {
+	params := makeParams("CreateVpcPeeringConnection")
+	params["Version"] = newAPIVersion
+	params["VpcId"] = vpcId
+	params["PeerVpcId"] = peerVpcId
+	if peerOwnerId != "" {
+		params["PeerOwnerId"] = peerOwnerId
+	}
+	if peerRegion != "" {
+		params["PeerRegion"] = peerRegion
+	}
+
+	resp = &VpcPeeringConnectionResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AcceptVpcPeeringConnection accepts a peering connection request. It
+// must be called by the owner of the accepter VPC while the connection is
+// in the pending-acceptance state.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AcceptVpcPeeringConnection.html for more details.
+func (ec2 *EC2) AcceptVpcPeeringConnection(id string) (resp *VpcPeeringConnectionResp, err error) {
+	params := makeParams("AcceptVpcPeeringConnection")
+	params["Version"] = newAPIVersion
+	params["VpcPeeringConnectionId"] = id
+
+	resp = &VpcPeeringConnectionResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteVpcPeeringConnection deletes a VPC peering connection. Either
+// owner may delete an active connection.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteVpcPeeringConnection.html for more details.
+func (ec2 *EC2) DeleteVpcPeeringConnection(id string) (resp *SimpleResp, err error) {
+	params := makeParams("DeleteVpcPeeringConnection")
+	params["Version"] = newAPIVersion
+	params["VpcPeeringConnectionId"] = id
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// VpcPeeringConnectionsResp represents a response to a
+// DescribeVpcPeeringConnections request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcPeeringConnections.html for more details.
+type VpcPeeringConnectionsResp struct {
+	RequestId             string                 `xml:"requestId"`
+	VpcPeeringConnections []VpcPeeringConnection `xml:"vpcPeeringConnectionSet>item"`
+}
+
+// VpcPeeringConnections returns details about VPC peering connections.
+// Both parameters are optional, and if provided will limit the connections
+// returned to those matching the given ids or filtering rules, such as
+// "status-code" or "accepter-vpc-info.vpc-id".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcPeeringConnections.html for more details.
+func (ec2 *EC2) VpcPeeringConnections(ids []string, filter *Filter) (resp *VpcPeeringConnectionsResp, err error) {
+	params := makeParams("DescribeVpcPeeringConnections")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "VpcPeeringConnectionId", ids)
+	filter.addParams(params)
+
+	resp = &VpcPeeringConnectionsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// VPC endpoints.
+
+// CreateVpcEndpointOptions encapsulates options for the CreateVpcEndpoint
+// call. Gateway endpoints (the default, used for S3 and DynamoDB) are
+// reached through RouteTableIds; interface endpoints are placed in
+// SubnetIds and protected by SecurityGroupIds.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVpcEndpoint.html for more details.
+type CreateVpcEndpointOptions struct {
+	VpcId             string
+	ServiceName       string // e.g. "com.amazonaws.us-east-1.s3"
+	VpcEndpointType   string // "Gateway" or "Interface"; optional
+	PolicyDocument    string // JSON policy; optional, gateway endpoints only
+	RouteTableIds     []string
+	SubnetIds         []string
+	SecurityGroupIds  []string
+	PrivateDnsEnabled bool
+	ClientToken       string // Generated if empty
+}
+
+// VpcEndpoint describes a VPC endpoint.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VpcEndpoint.html for more details.
+type VpcEndpoint struct {
+	VpcEndpointId       string           `xml:"vpcEndpointId"`
+	VpcEndpointType     string           `xml:"vpcEndpointType"`
+	VpcId               string           `xml:"vpcId"`
+	ServiceName         string           `xml:"serviceName"`
+	State               string           `xml:"state"`
+	PolicyDocument      string           `xml:"policyDocument"`
+	RouteTableIds       []string         `xml:"routeTableIdSet>item"`
+	SubnetIds           []string         `xml:"subnetIdSet>item"`
+	Groups              []SecurityGroup  `xml:"groupSet>item"`
+	NetworkInterfaceIds []string         `xml:"networkInterfaceIdSet>item"`
+	DnsEntries          []VpcEndpointDns `xml:"dnsEntrySet>item"`
+	PrivateDnsEnabled   bool             `xml:"privateDnsEnabled"`
+	CreationTimestamp   string           `xml:"creationTimestamp"`
+	Tags                []Tag            `xml:"tagSet>item"`
+}
+
+// VpcEndpointDns is a DNS name under which an interface endpoint is
+// reachable.
+type VpcEndpointDns struct {
+	DnsName      string `xml:"dnsName"`
+	HostedZoneId string `xml:"hostedZoneId"`
+}
+
+// CreateVpcEndpointResp represents a response to a CreateVpcEndpoint request.
+type CreateVpcEndpointResp struct {
+	RequestId   string      `xml:"requestId"`
+	ClientToken string      `xml:"clientToken"`
+	VpcEndpoint VpcEndpoint `xml:"vpcEndpoint"`
+}
+
+// CreateVpcEndpoint creates a VPC endpoint for the given service. The
+// response's ClientToken is the token sent with the request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVpcEndpoint.html for more details.
+func (ec2 *EC2) CreateVpcEndpoint(opts *CreateVpcEndpointOptions) (resp *CreateVpcEndpointResp, err error) {
+	params := makeParams("CreateVpcEndpoint")
+	params["Version"] = newAPIVersion
+	params["VpcId"] = opts.VpcId
+	params["ServiceName"] = opts.ServiceName
+	if opts.VpcEndpointType != "" {
+		params["VpcEndpointType"] = opts.VpcEndpointType
+	}
+	if opts.PolicyDocument != "" {
+		params["PolicyDocument"] = opts.PolicyDocument
+	}
+	addParamsList(params, "RouteTableId", opts.RouteTableIds)
+	addParamsList(params, "SubnetId", opts.SubnetIds)
+	addParamsList(params, "SecurityGroupId", opts.SecurityGroupIds)
+	if opts.PrivateDnsEnabled {
+		params["PrivateDnsEnabled"] = "true"
+	}
+	token, err := orClientToken(opts.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
+
+	resp = &CreateVpcEndpointResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.ClientToken = token
+	return resp, nil
+}
+
+// UnsuccessfulItem describes a resource on which a batch action failed.
+type UnsuccessfulItem struct {
+	ResourceId string `xml:"resourceId"`
+	Code       string `xml:"error>code"`
+	Message    string `xml:"error>message"`
+}
+
+// DeleteVpcEndpointsResp represents a response to a DeleteVpcEndpoints
+// request.
+type DeleteVpcEndpointsResp struct {
+	RequestId    string             `xml:"requestId"`
+	Unsuccessful []UnsuccessfulItem `xml:"unsuccessful>item"`
+}
+
+// DeleteVpcEndpoints deletes the given VPC endpoints. Endpoints that could
+// not be deleted are listed in the Unsuccessful field of the response.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteVpcEndpoints.html for more details.
+func (ec2 *EC2) DeleteVpcEndpoints(ids []string) (resp *DeleteVpcEndpointsResp, err error) {
+	params := makeParams("DeleteVpcEndpoints")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "VpcEndpointId", ids)
+
+	resp = &DeleteVpcEndpointsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// VpcEndpointsResp represents a response to a DescribeVpcEndpoints request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcEndpoints.html for more details.
+type VpcEndpointsResp struct {
+	RequestId    string        `xml:"requestId"`
+	VpcEndpoints []VpcEndpoint `xml:"vpcEndpointSet>item"`
+}
+
+// VpcEndpoints returns details about VPC endpoints. Both parameters are
+// optional, and if provided will limit the endpoints returned to those
+// matching the given ids or filtering rules, such as "vpc-id" or
+// "service-name".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcEndpoints.html for more details.
+func (ec2 *EC2) VpcEndpoints(ids []string, filter *Filter) (resp *VpcEndpointsResp, err error) {
+	params := makeParams("DescribeVpcEndpoints")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "VpcEndpointId", ids)
+	filter.addParams(params)
+
+	resp = &VpcEndpointsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ModifyVpcEndpointOptions encapsulates options for the ModifyVpcEndpoint
+// call. Route tables are added to or removed from gateway endpoints;
+// subnets and security groups from interface endpoints. All fields are
+// optional.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVpcEndpoint.html for more details.
+type ModifyVpcEndpointOptions struct {
+	AddRouteTableIds       []string
+	RemoveRouteTableIds    []string
+	AddSubnetIds           []string
+	RemoveSubnetIds        []string
+	AddSecurityGroupIds    []string
+	RemoveSecurityGroupIds []string
+	PolicyDocument         string // JSON policy; gateway endpoints only
+	ResetPolicy            bool   // Restores the default full access policy
+	PrivateDnsEnabled      *bool  // Interface endpoints only
+}
+
+// ModifyVpcEndpoint changes the route tables, subnets, security groups or
+// policy of the VPC endpoint with the given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVpcEndpoint.html for more details.
+func (ec2 *EC2) ModifyVpcEndpoint(id string, opts *ModifyVpcEndpointOptions) (resp *SimpleResp, err error) {
+	params := makeParams("ModifyVpcEndpoint")
+	params["Version"] = newAPIVersion
+	params["VpcEndpointId"] = id
+	addParamsList(params, "AddRouteTableId", opts.AddRouteTableIds)
+	addParamsList(params, "RemoveRouteTableId", opts.RemoveRouteTableIds)
+	addParamsList(params, "AddSubnetId", opts.AddSubnetIds)
+	addParamsList(params, "RemoveSubnetId", opts.RemoveSubnetIds)
+	addParamsList(params, "AddSecurityGroupId", opts.AddSecurityGroupIds)
+	addParamsList(params, "RemoveSecurityGroupId", opts.RemoveSecurityGroupIds)
+	if opts.PolicyDocument != "" {
+		params["PolicyDocument"] = opts.PolicyDocument
+	}
+	if opts.ResetPolicy {
+		params["ResetPolicy"] = "true"
+	}
+	if opts.PrivateDnsEnabled != nil {
+		params["PrivateDnsEnabled"] = strconv.FormatBool(*opts.PrivateDnsEnabled)
+	}
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// VM import conversion tasks.
+
+// ConversionTask describes the conversion of an imported disk image.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ConversionTask.html for more details.
+type ConversionTask struct {
+	ConversionTaskId string                  `xml:"conversionTaskId"`
+	ExpirationTime   string                  `xml:"expirationTime"`
+	State            string                  `xml:"state"` // active | cancelling | cancelled | completed
+	StatusMessage    string                  `xml:"statusMessage"`
+	ImportVolume     ImportVolumeTaskDetails `xml:"importVolume"`
+	Tags             []Tag                   `xml:"tagSet>item"`
+}
+
+// ImportVolumeTaskDetails describes a volume import task. Its fields are
+// empty for tasks importing instances.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ImportVolumeTaskDetails.html for more details.
+type ImportVolumeTaskDetails struct {
+	BytesConverted    int64  `xml:"bytesConverted"`
+	AvailabilityZone  string `xml:"availabilityZone"`
+	Description       string `xml:"description"`
+	ImageFormat       string `xml:"image>format"`
+	ImageSize         int64  `xml:"image>size"`
+	ImportManifestUrl string `xml:"image>importManifestUrl"`
+	VolumeId          string `xml:"volume>id"`
+	VolumeSize        int    `xml:"volume>size"` // GiB
+}
+
+// ImportVolumeOptions encapsulates options for the ImportVolume call.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ImportVolume.html for more details.
+type ImportVolumeOptions struct {
+	AvailabilityZone  string
+	Description       string
+	ImageFormat       string // VMDK | RAW | VHD
+	ImageBytes        int64  // Size of the disk image
+	ImportManifestUrl string // Presigned URL of the import manifest in S3
+	VolumeSize        int    // Size of the EBS volume in GiB
+}
+
+// ImportVolumeResp represents a response to an ImportVolume request.
+type ImportVolumeResp struct {
+	RequestId      string         `xml:"requestId"`
+	ConversionTask ConversionTask `xml:"conversionTask"`
+}
+
+// ImportVolume starts the import of a disk image, previously uploaded to
+// S3, into a new EBS volume. Its progress is reported by
+// DescribeConversionTasks.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ImportVolume.html for more details.
+func (ec2 *EC2) ImportVolume(opts *ImportVolumeOptions) (resp *ImportVolumeResp, err error) {
+	params := makeParams("ImportVolume")
+	params["AvailabilityZone"] = opts.AvailabilityZone
+	if opts.Description != "" {
+		params["Description"] = opts.Description
+	}
+	params["Image.Format"] = opts.ImageFormat
+	params["Image.Bytes"] = strconv.FormatInt(opts.ImageBytes, 10)
+	params["Image.ImportManifestUrl"] = opts.ImportManifestUrl
+	params["Volume.Size"] = strconv.Itoa(opts.VolumeSize)
+
+	resp = &ImportVolumeResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DescribeConversionTasksResp represents a response to a
+// DescribeConversionTasks request.
+type DescribeConversionTasksResp struct {
+	RequestId       string           `xml:"requestId"`
+	ConversionTasks []ConversionTask `xml:"conversionTasks>item"`
+}
+
+// DescribeConversionTasks returns details about the given conversion
+// tasks, or about all of them if ids is empty.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeConversionTasks.html for more details.
+func (ec2 *EC2) DescribeConversionTasks(ids []string) (resp *DescribeConversionTasksResp, err error) {
+	params := makeParams("DescribeConversionTasks")
+	addParamsList(params, "ConversionTaskId", ids)
+
+	resp = &DescribeConversionTasksResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// Instance export tasks.
+
+// ExportTask describes the export of an instance to a VM image in S3.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ExportTask.html for more details.
+type ExportTask struct {
+	ExportTaskId      string `xml:"exportTaskId"`
+	Description       string `xml:"description"`
+	State             string `xml:"state"` // active | cancelling | cancelled | completed
+	StatusMessage     string `xml:"statusMessage"`
+	InstanceId        string `xml:"instanceExport>instanceId"`
+	TargetEnvironment string `xml:"instanceExport>targetEnvironment"`
+	DiskImageFormat   string `xml:"exportToS3>diskImageFormat"`
+	ContainerFormat   string `xml:"exportToS3>containerFormat"`
+	S3Bucket          string `xml:"exportToS3>s3Bucket"`
+	S3Key             string `xml:"exportToS3>s3Key"`
+}
+
+// ExportTaskOptions encapsulates options for the CreateInstanceExportTask
+// call. TargetEnvironment and S3Bucket are required.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateInstanceExportTask.html for more details.
+type ExportTaskOptions struct {
+	Description       string
+	TargetEnvironment string // citrix | vmware | microsoft
+	DiskImageFormat   string // VMDK | RAW | VHD
+	ContainerFormat   string // ova
+	S3Bucket          string
+	S3Prefix          string
+}
+
+// CreateInstanceExportTaskResp represents a response to a
+// CreateInstanceExportTask request.
+type CreateInstanceExportTaskResp struct {
+	RequestId  string     `xml:"requestId"`
+	ExportTask ExportTask `xml:"exportTask"`
+}
+
+// CreateInstanceExportTask starts exporting the instance with the given id
+// to a VM image in the S3 bucket of opts. Its progress is reported by
+// DescribeExportTasks.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateInstanceExportTask.html for more details.
+func (ec2 *EC2) CreateInstanceExportTask(instanceId string, opts *ExportTaskOptions) (resp *CreateInstanceExportTaskResp, err error) {
+	params := makeParams("CreateInstanceExportTask")
+	params["InstanceId"] = instanceId
+	if opts.Description != "" {
+		params["Description"] = opts.Description
+	}
+	params["TargetEnvironment"] = opts.TargetEnvironment
+	if opts.DiskImageFormat != "" {
+		params["ExportToS3.DiskImageFormat"] = opts.DiskImageFormat
+	}
+	if opts.ContainerFormat != "" {
+		params["ExportToS3.ContainerFormat"] = opts.ContainerFormat
+	}
+	params["ExportToS3.S3Bucket"] = opts.S3Bucket
+	if opts.S3Prefix != "" {
+		params["ExportToS3.S3Prefix"] = opts.S3Prefix
+	}
+
+	resp = &CreateInstanceExportTaskResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DescribeExportTasksResp represents a response to a DescribeExportTasks
+// request.
+type DescribeExportTasksResp struct {
+	RequestId   string       `xml:"requestId"`
+	ExportTasks []ExportTask `xml:"exportTaskSet>item"`
+}
+
+// DescribeExportTasks returns details about the given export tasks, or
+// about all of them if ids is empty.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeExportTasks.html for more details.
+func (ec2 *EC2) DescribeExportTasks(ids []string) (resp *DescribeExportTasksResp, err error) {
+	params := makeParams("DescribeExportTasks")
+	addParamsList(params, "ExportTaskId", ids)
+
+	resp = &DescribeExportTasksResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CancelExportTask cancels an active export task. Any data already
+// written to S3 is removed.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CancelExportTask.html for more details.
+func (ec2 *EC2) CancelExportTask(id string) (resp *SimpleResp, err error) {
+	params := makeParams("CancelExportTask")
+	params["ExportTaskId"] = id
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// VPC flow logs.
+
+// CreateFlowLogsOptions encapsulates options for the CreateFlowLogs call.
+// Logs are published to the CloudWatch Logs group LogGroupName unless
+// LogDestinationType is "s3", in which case LogDestination is the ARN of
+// the S3 bucket to publish them to.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateFlowLogs.html for more details.
+type CreateFlowLogsOptions struct {
+	ResourceIds              []string
+	ResourceType             string // VPC | Subnet | NetworkInterface
+	TrafficType              string // ACCEPT | REJECT | ALL
+	LogGroupName             string
+	LogDestinationType       string // cloud-watch-logs | s3
+	LogDestination           string
+	DeliverLogsPermissionArn string // Required for CloudWatch Logs
+
+	// ClientToken ensures the idempotency of the request. If empty, a
+	// random token is generated.
+	ClientToken string
+}
+
+// CreateFlowLogsResp represents a response to a CreateFlowLogs request.
+// Unsuccessful lists the resources for which no flow log was created.
+type CreateFlowLogsResp struct {
+	RequestId    string             `xml:"requestId"`
+	ClientToken  string             `xml:"clientToken"`
+	FlowLogIds   []string           `xml:"flowLogIdSet>item"`
+	Unsuccessful []UnsuccessfulItem `xml:"unsuccessful>item"`
+}
+
+// CreateFlowLogs creates flow logs capturing the IP traffic of the given
+// VPCs, subnets or network interfaces.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateFlowLogs.html for more details.
+func (ec2 *EC2) CreateFlowLogs(opts *CreateFlowLogsOptions) (resp *CreateFlowLogsResp, err error) {
+	params := makeParams("CreateFlowLogs")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "ResourceId", opts.ResourceIds)
+	params["ResourceType"] = opts.ResourceType
+	params["TrafficType"] = opts.TrafficType
+	if opts.LogGroupName != "" {
+		params["LogGroupName"] = opts.LogGroupName
+	}
+	if opts.LogDestinationType != "" {
+		params["LogDestinationType"] = opts.LogDestinationType
+	}
+	if opts.LogDestination != "" {
+		params["LogDestination"] = opts.LogDestination
+	}
+	if opts.DeliverLogsPermissionArn != "" {
+		params["DeliverLogsPermissionArn"] = opts.DeliverLogsPermissionArn
+	}
+	token, err := orClientToken(opts.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
+
+	resp = &CreateFlowLogsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteFlowLogsResp represents a response to a DeleteFlowLogs request.
+type DeleteFlowLogsResp struct {
+	RequestId    string             `xml:"requestId"`
+	Unsuccessful []UnsuccessfulItem `xml:"unsuccessful>item"`
+}
+
+// DeleteFlowLogs deletes the given flow logs. Flow logs that could not be
+// deleted are listed in the Unsuccessful field of the response.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteFlowLogs.html for more details.
+func (ec2 *EC2) DeleteFlowLogs(ids []string) (resp *DeleteFlowLogsResp, err error) {
+	params := makeParams("DeleteFlowLogs")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "FlowLogId", ids)
+
+	resp = &DeleteFlowLogsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FlowLog describes a flow log.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_FlowLog.html for more details.
+type FlowLog struct {
+	FlowLogId                string `xml:"flowLogId"`
+	FlowLogStatus            string `xml:"flowLogStatus"`
+	CreationTime             string `xml:"creationTime"`
+	ResourceId               string `xml:"resourceId"`
+	TrafficType              string `xml:"trafficType"`
+	LogGroupName             string `xml:"logGroupName"`
+	LogDestinationType       string `xml:"logDestinationType"`
+	LogDestination           string `xml:"logDestination"`
+	DeliverLogsPermissionArn string `xml:"deliverLogsPermissionArn"`
+	DeliverLogsStatus        string `xml:"deliverLogsStatus"`
+	DeliverLogsErrorMessage  string `xml:"deliverLogsErrorMessage"`
+}
+
+// FlowLogsResp represents a response to a DescribeFlowLogs request.
+type FlowLogsResp struct {
+	RequestId string    `xml:"requestId"`
+	FlowLogs  []FlowLog `xml:"flowLogSet>item"`
+}
+
+// FlowLogs returns details about the given flow logs, or about all of
+// them if ids is empty, optionally limited by the given filtering rules
+// such as "resource-id" or "log-group-name".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeFlowLogs.html for more details.
+func (ec2 *EC2) FlowLogs(ids []string, filter *Filter) (resp *FlowLogsResp, err error) {
+	params := makeParams("DescribeFlowLogs")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "FlowLogId", ids)
+	filter.addParams(params)
+
+	resp = &FlowLogsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// Instance store bundle tasks.
+
+// BundleTask describes the bundling of an instance store-backed instance
+// into an S3-backed image.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_BundleTask.html for more details.
+type BundleTask struct {
+	BundleId     string `xml:"bundleId"`
+	InstanceId   string `xml:"instanceId"`
+	State        string `xml:"state"` // pending | waiting-for-shutdown | bundling | storing | cancelling | complete | failed
+	Progress     string `xml:"progress"`
+	StartTime    string `xml:"startTime"`
+	UpdateTime   string `xml:"updateTime"`
+	Bucket       string `xml:"storage>S3>bucket"`
+	Prefix       string `xml:"storage>S3>prefix"`
+	ErrorCode    string `xml:"error>code"`
+	ErrorMessage string `xml:"error>message"`
+}
+
+// BundleOptions encapsulates options for the BundleInstance call. Bucket,
+// Prefix and UploadPolicy are required.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_BundleInstance.html for more details.
+type BundleOptions struct {
+	Bucket string
+	Prefix string
+
+	// AWSAccessKeyId and AWSSecretKey are the credentials of the owner
+	// of Bucket. They default to the credentials of the client, and must
+	// be set together.
+	AWSAccessKeyId string
+	AWSSecretKey   string
+
+	// UploadPolicy is the JSON policy document giving EC2 permission to
+	// write the bundle to Bucket. It is base64 encoded and signed with
+	// AWSSecretKey.
+	UploadPolicy string
+}
+
+// BundleTaskResp represents a response to a BundleInstance or
+// CancelBundleTask request.
+type BundleTaskResp struct {
+	RequestId  string     `xml:"requestId"`
+	BundleTask BundleTask `xml:"bundleInstanceTask"`
+}
+
+// BundleInstance starts bundling the instance store-backed instance with
+// the given id into the S3 bucket of opts. The instance is rebooted while
+// it is bundled. Progress is reported by DescribeBundleTasks.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_BundleInstance.html for more details.
+func (ec2 *EC2) BundleInstance(instanceId string, opts *BundleOptions) (resp *BundleTaskResp, err error) {
+	accessKey, secretKey := opts.AWSAccessKeyId, opts.AWSSecretKey
+	if (accessKey == "") != (secretKey == "") {
+		return nil, errors.New("AWSAccessKeyId and AWSSecretKey must be set together")
+	}
+	if accessKey == "" {
+		accessKey, secretKey = ec2.Auth.AccessKey, ec2.Auth.SecretKey
+	}
+
+	params := makeParams("BundleInstance")
+	params["InstanceId"] = instanceId
+	params["Storage.S3.Bucket"] = opts.Bucket
+	params["Storage.S3.Prefix"] = opts.Prefix
+	params["Storage.S3.AWSAccessKeyId"] = accessKey
+	policy := base64.StdEncoding.EncodeToString([]byte(opts.UploadPolicy))
+	params["Storage.S3.UploadPolicy"] = policy
+	hash := hmac.New(sha1.New, []byte(secretKey))
+	hash.Write([]byte(policy))
+	params["Storage.S3.UploadPolicySignature"] = base64.StdEncoding.EncodeToString(hash.Sum(nil))
+
+	resp = &BundleTaskResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DescribeBundleTasksResp represents a response to a DescribeBundleTasks
+// request.
+type DescribeBundleTasksResp struct {
+	RequestId   string       `xml:"requestId"`
+	BundleTasks []BundleTask `xml:"bundleInstanceTasksSet>item"`
+}
+
+// DescribeBundleTasks returns details about the given bundle tasks, or
+// about all of them if ids is empty. The filter parameter, if provided,
+// limits the tasks returned.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeBundleTasks.html for more details.
+func (ec2 *EC2) DescribeBundleTasks(ids []string, filter *Filter) (resp *DescribeBundleTasksResp, err error) {
+	params := makeParams("DescribeBundleTasks")
+	addParamsList(params, "BundleId", ids)
+	filter.addParams(params)
+
+	resp = &DescribeBundleTasksResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CancelBundleTask cancels the bundle task with the given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CancelBundleTask.html for more details.
+func (ec2 *EC2) CancelBundleTask(id string) (resp *BundleTaskResp, err error) {
+	params := makeParams("CancelBundleTask")
+	params["BundleId"] = id
+
+	resp = &BundleTaskResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// EC2 fleets.
+
+// LaunchTemplateSpec identifies a launch template, by id or by name, and
+// one of its versions.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_FleetLaunchTemplateSpecificationRequest.html for more details.
+type LaunchTemplateSpec struct {
+	LaunchTemplateId   string
+	LaunchTemplateName string // Used if LaunchTemplateId is empty
+	Version            string // A version number, "$Latest" or "$Default"; optional
+}
+
+func (spec *LaunchTemplateSpec) addParams(params map[string]string, prefix string) {
+	if spec.LaunchTemplateId != "" {
+		params[prefix+"LaunchTemplateId"] = spec.LaunchTemplateId
+	} else {
+		params[prefix+"LaunchTemplateName"] = spec.LaunchTemplateName
+	}
+	if spec.Version != "" {
+		params[prefix+"Version"] = spec.Version
+	}
+}
+
+// FleetConfig encapsulates options for the CreateFleet call. The fleet
+// launches instances from LaunchTemplateConfigs up to TotalTargetCapacity,
+// of which OnDemandTargetCapacity and SpotTargetCapacity are launched as
+// on-demand and spot instances respectively, and the rest as
+// DefaultTargetCapacityType.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateFleet.html for more details.
+type FleetConfig struct {
+	LaunchTemplateConfigs            []FleetLaunchTemplateConfig
+	TotalTargetCapacity              int
+	OnDemandTargetCapacity           int
+	SpotTargetCapacity               int
+	DefaultTargetCapacityType        string // spot | on-demand
+	Type                             string // request | maintain | instant; optional
+	SpotAllocationStrategy           string // lowest-price | diversified | capacity-optimized; optional
+	OnDemandAllocationStrategy       string // lowest-price | prioritized; optional
+	TerminateInstancesWithExpiration bool
+	ReplaceUnhealthyInstances        bool
+	ClientToken                      string // Generated if empty
+}
+
+// FleetLaunchTemplateConfig describes a launch template a fleet may use,
+// and the overrides of its instance type, subnet or price the fleet may
+// choose from.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_FleetLaunchTemplateConfigRequest.html for more details.
+type FleetLaunchTemplateConfig struct {
+	LaunchTemplate LaunchTemplateSpec
+	Overrides      []FleetLaunchTemplateOverrides
+}
+
+// FleetLaunchTemplateOverrides overrides some values of a launch template
+// for instances launched by a fleet. All fields are optional.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_FleetLaunchTemplateOverridesRequest.html for more details.
+type FleetLaunchTemplateOverrides struct {
+	InstanceType     string
+	SubnetId         string
+	AvailabilityZone string
+	MaxPrice         string  // Maximum spot price per unit hour
+	WeightedCapacity float64 // Units of capacity an instance counts for; defaults to 1
+	Priority         float64 // Lower values are used first by the prioritized strategy
+}
+
+// FleetError describes a failure to launch instances for a fleet.
+type FleetError struct {
+	LaunchTemplateId string `xml:"launchTemplateAndOverrides>launchTemplateSpecification>launchTemplateId"`
+	InstanceType     string `xml:"launchTemplateAndOverrides>overrides>instanceType"`
+	Lifecycle        string `xml:"lifecycle"` // spot | on-demand
+	Code             string `xml:"errorCode"`
+	Message          string `xml:"errorMessage"`
+}
+
+// CreateFleetResp represents a response to a CreateFleet request.
+// InstanceIds is only set for fleets of type instant.
+type CreateFleetResp struct {
+	RequestId   string       `xml:"requestId"`
+	FleetId     string       `xml:"fleetId"`
+	Errors      []FleetError `xml:"errorSet>item"`
+	InstanceIds []string     `xml:"fleetInstanceSet>item>instanceIds>item"`
+	ClientToken string       `xml:"-"` // The idempotency token sent with the request
+}
+
+// CreateFleet creates an EC2 fleet which launches on-demand and spot
+// instances from launch templates up to the target capacity of config.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateFleet.html for more details.
+func (ec2 *EC2) CreateFleet(config *FleetConfig) (resp *CreateFleetResp, err error) {
+	params := makeParams("CreateFleet")
+	params["Version"] = newAPIVersion
+	for i, ltc := range config.LaunchTemplateConfigs {
+		prefix := "LaunchTemplateConfigs." + strconv.Itoa(i+1) + "."
+		ltc.LaunchTemplate.addParams(params, prefix+"LaunchTemplateSpecification.")
+		for j, o := range ltc.Overrides {
+			o.addParams(params, prefix+"Overrides."+strconv.Itoa(j+1)+".")
+		}
+	}
+	prefix := "TargetCapacitySpecification."
+	params[prefix+"TotalTargetCapacity"] = strconv.Itoa(config.TotalTargetCapacity)
+	if config.OnDemandTargetCapacity != 0 {
+		params[prefix+"OnDemandTargetCapacity"] = strconv.Itoa(config.OnDemandTargetCapacity)
+	}
+	if config.SpotTargetCapacity != 0 {
+		params[prefix+"SpotTargetCapacity"] = strconv.Itoa(config.SpotTargetCapacity)
+	}
+	if config.DefaultTargetCapacityType != "" {
+		params[prefix+"DefaultTargetCapacityType"] = config.DefaultTargetCapacityType
+	}
+	if config.Type != "" {
+		params["Type"] = config.Type
+	}
+	if config.SpotAllocationStrategy != "" {
+		params["SpotOptions.AllocationStrategy"] = config.SpotAllocationStrategy
+	}
+	if config.OnDemandAllocationStrategy != "" {
+		params["OnDemandOptions.AllocationStrategy"] = config.OnDemandAllocationStrategy
+	}
+	if config.TerminateInstancesWithExpiration {
+		params["TerminateInstancesWithExpiration"] = "true"
+	}
+	if config.ReplaceUnhealthyInstances {
+		params["ReplaceUnhealthyInstances"] = "true"
+	}
+	token, err := orClientToken(config.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
+
+	resp = &CreateFleetResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.ClientToken = token
+	return resp, nil
+}
+
+func (o *FleetLaunchTemplateOverrides) addParams(params map[string]string, prefix string) {
+	if o.InstanceType != "" {
+		params[prefix+"InstanceType"] = o.InstanceType
+	}
+	if o.SubnetId != "" {
+		params[prefix+"SubnetId"] = o.SubnetId
+	}
+	if o.AvailabilityZone != "" {
+		params[prefix+"AvailabilityZone"] = o.AvailabilityZone
+	}
+	if o.MaxPrice != "" {
+		params[prefix+"MaxPrice"] = o.MaxPrice
+	}
+	if o.WeightedCapacity != 0 {
+		params[prefix+"WeightedCapacity"] = strconv.FormatFloat(o.WeightedCapacity, 'f', -1, 64)
+	}
+	if o.Priority != 0 {
+		params[prefix+"Priority"] = strconv.FormatFloat(o.Priority, 'f', -1, 64)
+	}
+}
+
+// Fleet describes the state of an EC2 fleet.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_FleetData.html for more details.
+type Fleet struct {
+	FleetId                   string  `xml:"fleetId"`
+	State                     string  `xml:"fleetState"`     // submitted | active | deleted | failed | deleted_running | deleted_terminating | modifying
+	ActivityStatus            string  `xml:"activityStatus"` // error | pending_fulfillment | pending_termination | fulfilled
+	CreateTime                string  `xml:"createTime"`
+	Type                      string  `xml:"type"`
+	TotalTargetCapacity       int     `xml:"targetCapacitySpecification>totalTargetCapacity"`
+	OnDemandTargetCapacity    int     `xml:"targetCapacitySpecification>onDemandTargetCapacity"`
+	SpotTargetCapacity        int     `xml:"targetCapacitySpecification>spotTargetCapacity"`
+	DefaultTargetCapacityType string  `xml:"targetCapacitySpecification>defaultTargetCapacityType"`
+	FulfilledCapacity         float64 `xml:"fulfilledCapacity"`
+	FulfilledOnDemandCapacity float64 `xml:"fulfilledOnDemandCapacity"`
+	Tags                      []Tag   `xml:"tagSet>item"`
+}
+
+// FleetsResp represents a response to a DescribeFleets request.
+type FleetsResp struct {
+	RequestId string  `xml:"requestId"`
+	Fleets    []Fleet `xml:"fleetSet>item"`
+	NextToken string  `xml:"nextToken"`
+}
+
+// DescribeFleets returns details about the EC2 fleets with the given ids,
+// or about all of them if ids is empty.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeFleets.html for more details.
+func (ec2 *EC2) DescribeFleets(ids []string) (resp *FleetsResp, err error) {
+	params := makeParams("DescribeFleets")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "FleetId", ids)
+
+	resp = &FleetsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteFleetsResp represents a response to a DeleteFleets request.
+type DeleteFleetsResp struct {
+	RequestId    string           `xml:"requestId"`
+	Successful   []DeletedFleet   `xml:"successfulFleetDeletionSet>item"`
+	Unsuccessful []UndeletedFleet `xml:"unsuccessfulFleetDeletionSet>item"`
+}
+
+// DeletedFleet describes an EC2 fleet that was successfully deleted.
+type DeletedFleet struct {
+	FleetId       string `xml:"fleetId"`
+	CurrentState  string `xml:"currentFleetState"`
+	PreviousState string `xml:"previousFleetState"`
+}
+
+// UndeletedFleet describes an EC2 fleet that could not be deleted, and
+// why.
+type UndeletedFleet struct {
+	FleetId string `xml:"fleetId"`
+	Code    string `xml:"error>code"`
+	Message string `xml:"error>message"`
+}
+
+// DeleteFleets deletes the EC2 fleets with the given ids. If
+// terminateInstances is true the instances launched by the fleets are
+// terminated as well; otherwise they keep running.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteFleets.html for more details.
+func (ec2 *EC2) DeleteFleets(ids []string, terminateInstances bool) (resp *DeleteFleetsResp, err error) {
+	params := makeParams("DeleteFleets")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "FleetId", ids)
+	params["TerminateInstances"] = strconv.FormatBool(terminateInstances)
+
+	resp = &DeleteFleetsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// Launch templates.
+
+// LaunchTemplateData describes the instances launched from a launch
+// template. Its fields mirror those of RunInstancesOptions; all of them
+// are optional.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RequestLaunchTemplateData.html for more details.
+type LaunchTemplateData struct {
+	ImageId               string
+	InstanceType          string
+	KeyName               string
+	SecurityGroups        []SecurityGroup
+	KernelId              string
+	RamdiskId             string
+	UserData              []byte
+	AvailabilityZone      string
+	PlacementGroupName    string
+	Tenancy               string
+	Monitoring            bool
+	DisableAPITermination bool
+	ShutdownBehavior      string
+	IamInstanceProfile    IamInstanceProfile
+	BlockDeviceMappings   []BlockDeviceMapping
+	EbsOptimized          bool
+	NetworkInterfaces     []NetworkInterface
+	InstanceMarketOptions *MarketOptions
+	TagSpecifications     []TagSpecification
+}
+
+func (data *LaunchTemplateData) validate() error {
+	if len(data.UserData) > MaxUserDataSize {
+		return ErrUserDataTooLarge
+	}
+	return validateBlockDeviceMappings(data.BlockDeviceMappings)
+}
+
+func (data *LaunchTemplateData) addParams(params map[string]string, prefix string) {
+	if data.ImageId != "" {
+		params[prefix+"ImageId"] = data.ImageId
+	}
+	if data.InstanceType != "" {
+		params[prefix+"InstanceType"] = data.InstanceType
+	}
+	if data.KeyName != "" {
+		params[prefix+"KeyName"] = data.KeyName
+	}
+	addSecurityGroups(params, prefix, data.SecurityGroups)
+	if data.KernelId != "" {
+		params[prefix+"KernelId"] = data.KernelId
+	}
+	if data.RamdiskId != "" {
+		params[prefix+"RamDiskId"] = data.RamdiskId
+	}
+	if data.UserData != nil {
+		params[prefix+"UserData"] = base64.StdEncoding.EncodeToString(data.UserData)
+	}
+	if data.AvailabilityZone != "" {
+		params[prefix+"Placement.AvailabilityZone"] = data.AvailabilityZone
+	}
+	if data.PlacementGroupName != "" {
+		params[prefix+"Placement.GroupName"] = data.PlacementGroupName
+	}
+	if data.Tenancy != "" {
+		params[prefix+"Placement.Tenancy"] = data.Tenancy
+	}
+	if data.Monitoring {
+		params[prefix+"Monitoring.Enabled"] = "true"
+	}
+	if data.DisableAPITermination {
+		params[prefix+"DisableApiTermination"] = "true"
+	}
+	if data.ShutdownBehavior != "" {
+		params[prefix+"InstanceInitiatedShutdownBehavior"] = data.ShutdownBehavior
+	}
+	if data.IamInstanceProfile.ARN != "" {
+		params[prefix+"IamInstanceProfile.Arn"] = data.IamInstanceProfile.ARN
+	} else if data.IamInstanceProfile.Name != "" {
+		params[prefix+"IamInstanceProfile.Name"] = data.IamInstanceProfile.Name
+	}
+	addBlockDeviceMappings(params, prefix, data.BlockDeviceMappings)
+	if data.EbsOptimized {
+		params[prefix+"EbsOptimized"] = "true"
+	}
+	addNetworkInterfaces(params, prefix, data.NetworkInterfaces)
+	if data.InstanceMarketOptions != nil {
+		data.InstanceMarketOptions.addParams(params, prefix)
+	}
+	addTagSpecifications(params, prefix, data.TagSpecifications)
+}
+
+// LaunchTemplate describes a launch template.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_LaunchTemplate.html for more details.
+type LaunchTemplate struct {
+	LaunchTemplateId     string `xml:"launchTemplateId"`
+	LaunchTemplateName   string `xml:"launchTemplateName"`
+	CreateTime           string `xml:"createTime"`
+	CreatedBy            string `xml:"createdBy"`
+	DefaultVersionNumber int64  `xml:"defaultVersionNumber"`
+	LatestVersionNumber  int64  `xml:"latestVersionNumber"`
+	Tags                 []Tag  `xml:"tagSet>item"`
+}
+
+// LaunchTemplateResp represents a response to a CreateLaunchTemplate or
+// DeleteLaunchTemplate request.
+type LaunchTemplateResp struct {
+	RequestId      string         `xml:"requestId"`
+	LaunchTemplate LaunchTemplate `xml:"launchTemplate"`
+	ClientToken    string         `xml:"-"` // The idempotency token sent with a CreateLaunchTemplate request
+}
+
+// CreateLaunchTemplate creates a launch template with the given name,
+// whose first version describes instances as data does. data may be nil.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplate.html for more details.
+func (ec2 *EC2) CreateLaunchTemplate(name string, data *LaunchTemplateData) (resp *LaunchTemplateResp, err error) {
+	return ec2.CreateLaunchTemplateWithOptions(&CreateLaunchTemplateOptions{LaunchTemplateName: name, Data: data})
+}
+
+// CreateLaunchTemplateOptions encapsulates options for the
+// CreateLaunchTemplateWithOptions call.
+type CreateLaunchTemplateOptions struct {
+	LaunchTemplateName string
+	Data               *LaunchTemplateData // Optional
+	ClientToken        string              // Generated if empty
+}
+
+// CreateLaunchTemplateWithOptions creates a launch template, like
+// CreateLaunchTemplate, but also supports the ClientToken parameter.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplate.html for more details.
+func (ec2 *EC2) CreateLaunchTemplateWithOptions(opts *CreateLaunchTemplateOptions) (resp *LaunchTemplateResp, err error) {
+	data := opts.Data
+	if data == nil {
+		data = &LaunchTemplateData{}
+	}
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+	params := makeParams("CreateLaunchTemplate")
+	params["Version"] = newAPIVersion
+	params["LaunchTemplateName"] = opts.LaunchTemplateName
+	data.addParams(params, "LaunchTemplateData.")
+	token, err := orClientToken(opts.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
+
+	resp = &LaunchTemplateResp{}
+	err = ec2.post(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.ClientToken = token
+	return resp, nil
+}
+
+// CreateLaunchTemplateVersionOptions encapsulates options for the
+// CreateLaunchTemplateVersion call. The template is identified by
+// LaunchTemplateId or, if that is empty, by LaunchTemplateName.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplateVersion.html for more details.
+type CreateLaunchTemplateVersionOptions struct {
+	LaunchTemplateId   string
+	LaunchTemplateName string
+
+	// SourceVersion, if set, is the version the new version is based on.
+	// Fields of Data then override those of the source version.
+	SourceVersion      string
+	VersionDescription string
+	Data               *LaunchTemplateData
+}
+
+// LaunchTemplateVersion describes a version of a launch template.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_LaunchTemplateVersion.html for more details.
+type LaunchTemplateVersion struct {
+	LaunchTemplateId   string `xml:"launchTemplateId"`
+	LaunchTemplateName string `xml:"launchTemplateName"`
+	VersionNumber      int64  `xml:"versionNumber"`
+	VersionDescription string `xml:"versionDescription"`
+	DefaultVersion     bool   `xml:"defaultVersion"`
+	CreateTime         string `xml:"createTime"`
+	CreatedBy          string `xml:"createdBy"`
+}
+
+// CreateLaunchTemplateVersionResp represents a response to a
+// CreateLaunchTemplateVersion request.
+type CreateLaunchTemplateVersionResp struct {
+	RequestId             string                `xml:"requestId"`
+	LaunchTemplateVersion LaunchTemplateVersion `xml:"launchTemplateVersion"`
+}
+
+// CreateLaunchTemplateVersion adds a version to a launch template. The
+// new version doesn't become the template's default version.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplateVersion.html for more details.
+func (ec2 *EC2) CreateLaunchTemplateVersion(opts *CreateLaunchTemplateVersionOptions) (resp *CreateLaunchTemplateVersionResp, err error) {
+	data := opts.Data
+	if data == nil {
+		data = &LaunchTemplateData{}
+	}
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+	params := makeParams("CreateLaunchTemplateVersion")
+	params["Version"] = newAPIVersion
+	if opts.LaunchTemplateId != "" {
+		params["LaunchTemplateId"] = opts.LaunchTemplateId
+	} else {
+		params["LaunchTemplateName"] = opts.LaunchTemplateName
+	}
+	if opts.SourceVersion != "" {
+		params["SourceVersion"] = opts.SourceVersion
+	}
+	if opts.VersionDescription != "" {
+		params["VersionDescription"] = opts.VersionDescription
+	}
+	data.addParams(params, "LaunchTemplateData.")
+
+	resp = &CreateLaunchTemplateVersionResp{}
+	err = ec2.post(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LaunchTemplatesResp represents a response to a DescribeLaunchTemplates
+// request.
+type LaunchTemplatesResp struct {
+	RequestId       string           `xml:"requestId"`
+	LaunchTemplates []LaunchTemplate `xml:"launchTemplates>item"`
+	NextToken       string           `xml:"nextToken"`
+}
+
+// DescribeLaunchTemplates returns details about launch templates. All
+// parameters are optional, and if provided will limit the templates
+// returned to those matching the given ids, names or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeLaunchTemplates.html for more details.
+func (ec2 *EC2) DescribeLaunchTemplates(ids, names []string, filter *Filter) (resp *LaunchTemplatesResp, err error) {
+	params := makeParams("DescribeLaunchTemplates")
+	params["Version"] = newAPIVersion
+	addParamsList(params, "LaunchTemplateId", ids)
+	addParamsList(params, "LaunchTemplateName", names)
+	filter.addParams(params)
+
+	resp = &LaunchTemplatesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteLaunchTemplate deletes the launch template with the given id and
+// all of its versions.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteLaunchTemplate.html for more details.
+func (ec2 *EC2) DeleteLaunchTemplate(id string) (resp *LaunchTemplateResp, err error) {
+	params := makeParams("DeleteLaunchTemplate")
+	params["Version"] = newAPIVersion
+	params["LaunchTemplateId"] = id
+
+	resp = &LaunchTemplateResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -1,11 +1,23 @@
 package ec2_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/AdRoll/goamz/aws"
 	"github.com/AdRoll/goamz/ec2"
 	"github.com/AdRoll/goamz/testutil"
 	"gopkg.in/check.v1"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test(t *testing.T) {
@@ -55,6 +67,233 @@ func (s *S) TestRunInstancesErrorDump(c *check.C) {
 	c.Assert(ec2err.RequestId, check.Equals, "0503f4e9-bbd6-483c-b54f-c4ae9f3b30f4")
 }
 
+func (s *S) TestErrorResponseHeaders(c *check.C) {
+	testServer.Response(503, map[string]string{
+		"x-amzn-RequestId": "d3a9c0ab-7d3c-4c8b-9a4f-EXAMPLE",
+		"Retry-After":      "5",
+	}, "")
+
+	_, err := s.ec2.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+
+	ec2err, ok := err.(*ec2.Error)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(ec2err.StatusCode, check.Equals, 503)
+	c.Assert(ec2err.RequestId, check.Equals, "d3a9c0ab-7d3c-4c8b-9a4f-EXAMPLE")
+	c.Assert(ec2err.RetryAfter, check.Equals, 5*time.Second)
+}
+
+func (s *S) TestErrorRetryAfterDate(c *check.C) {
+	ec2.FakeTime(true)
+	defer ec2.FakeTime(false)
+	testServer.Response(503, map[string]string{"Retry-After": "Sun, 01 Jan 2012 00:02:00 GMT"}, ErrorDump)
+
+	_, err := s.ec2.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+
+	ec2err, ok := err.(*ec2.Error)
+	c.Assert(ok, check.Equals, true)
+	// The request id in the body takes precedence over any header.
+	c.Assert(ec2err.RequestId, check.Equals, "0503f4e9-bbd6-483c-b54f-c4ae9f3b30f4")
+	c.Assert(ec2err.RetryAfter, check.Equals, 2*time.Minute)
+}
+
+func (s *S) TestOnRequest(c *check.C) {
+	type call struct {
+		action     string
+		statusCode int
+	}
+	var calls []call
+	s.ec2.OnRequest = func(action string, duration time.Duration, statusCode int) {
+		c.Check(duration >= 0, check.Equals, true)
+		calls = append(calls, call{action, statusCode})
+	}
+	defer func() { s.ec2.OnRequest = nil }()
+
+	testServer.Response(200, nil, DescribeInstancesExample1)
+	_, err := s.ec2.DescribeInstances(nil, nil)
+	c.Assert(err, check.IsNil)
+	testServer.WaitRequest()
+
+	testServer.Response(503, nil, "")
+	_, err = s.ec2.TerminateInstances([]string{"i-1"})
+	c.Assert(err, check.NotNil)
+	testServer.WaitRequest()
+
+	c.Assert(calls, check.DeepEquals, []call{
+		{"DescribeInstances", 200},
+		{"TerminateInstances", 503},
+	})
+}
+
+func (s *S) TestRetryOnClockSkew(c *check.C) {
+	ec2.FakeTime(true)
+	defer ec2.FakeTime(false)
+
+	var retries []int
+	s.ec2.OnRetry = func(action string, attempt int, err error) {
+		c.Check(action, check.Equals, "DescribeInstances")
+		c.Check(err.(*ec2.Error).Code, check.Equals, "RequestExpired")
+		retries = append(retries, attempt)
+	}
+	defer func() { s.ec2.OnRetry = nil }()
+
+	testServer.Response(400, map[string]string{"Date": "Sun, 01 Jan 2012 00:10:00 GMT"}, RequestExpiredDump)
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	_, err := s.ec2.DescribeInstances(nil, nil)
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["Timestamp"], check.DeepEquals, []string{"2012-01-01T00:00:00Z"})
+	c.Assert(reqs[1].Form["Timestamp"], check.DeepEquals, []string{"2012-01-01T00:10:00Z"})
+	c.Assert(err, check.IsNil)
+	c.Assert(retries, check.DeepEquals, []int{2})
+}
+
+func (s *S) TestRetryOnClockSkewOnce(c *check.C) {
+	testServer.Response(400, map[string]string{"Date": "Sun, 01 Jan 2012 00:10:00 GMT"}, RequestExpiredDump)
+	testServer.Response(400, map[string]string{"Date": "Sun, 01 Jan 2012 00:10:00 GMT"}, RequestExpiredDump)
+
+	_, err := s.ec2.DescribeInstances(nil, nil)
+
+	testServer.WaitRequests(2)
+	ec2err, ok := err.(*ec2.Error)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(ec2err.Code, check.Equals, "RequestExpired")
+}
+
+func (s *S) TestRetryBackoff(c *check.C) {
+	var delays []time.Duration
+	ec2.SetSleep(func(d time.Duration) { delays = append(delays, d) })
+	defer ec2.SetSleep(nil)
+
+	var attempts []int
+	e := ec2.New(s.ec2.Auth, s.ec2.Region)
+	e.MaxRetries = 4
+	e.RetryBaseDelay = 100 * time.Millisecond
+	e.RetryMaxDelay = time.Second
+	e.OnRetry = func(action string, attempt int, err error) {
+		attempts = append(attempts, attempt)
+	}
+
+	testServer.Response(503, nil, "")
+	testServer.Response(400, nil, RequestLimitExceededDump)
+	testServer.Response(500, nil, "")
+	testServer.Response(503, map[string]string{"Retry-After": "120"}, "")
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	_, err := e.DescribeInstances(nil, nil)
+
+	testServer.WaitRequests(5)
+	c.Assert(err, check.IsNil)
+	c.Assert(attempts, check.DeepEquals, []int{2, 3, 4, 5})
+	c.Assert(delays, check.DeepEquals, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		time.Second,
+	})
+}
+
+func (s *S) TestRetryDefaultDelays(c *check.C) {
+	var delays []time.Duration
+	ec2.SetSleep(func(d time.Duration) { delays = append(delays, d) })
+	defer ec2.SetSleep(nil)
+
+	e := ec2.New(s.ec2.Auth, s.ec2.Region)
+	e.MaxRetries = 1
+
+	testServer.Response(503, map[string]string{"Retry-After": "60"}, "")
+	testServer.Response(503, nil, "")
+
+	_, err := e.DescribeInstances(nil, nil)
+
+	testServer.WaitRequests(2)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.(*ec2.Error).StatusCode, check.Equals, 503)
+	c.Assert(delays, check.DeepEquals, []time.Duration{20 * time.Second})
+}
+
+func (s *S) TestNoRetryByDefault(c *check.C) {
+	ec2.SetSleep(func(d time.Duration) { c.Fatalf("unexpected sleep of %v", d) })
+	defer ec2.SetSleep(nil)
+
+	testServer.Response(503, nil, "")
+	_, err := s.ec2.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+	c.Assert(err, check.NotNil)
+
+	e := ec2.New(s.ec2.Auth, s.ec2.Region)
+	e.MaxRetries = 3
+	testServer.Response(400, nil, ErrorDump)
+	_, err = e.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+	c.Assert(err.(*ec2.Error).Code, check.Equals, "UnsupportedOperation")
+}
+
+func (s *S) TestOnResponse(c *check.C) {
+	var action string
+	var body []byte
+	s.ec2.OnResponse = func(a string, b []byte) {
+		action, body = a, b
+	}
+	defer func() { s.ec2.OnResponse = nil }()
+
+	testServer.Response(200, nil, DescribeInstancesExample1)
+	resp, err := s.ec2.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Reservations, check.HasLen, 2)
+	c.Assert(action, check.Equals, "DescribeInstances")
+	c.Assert(string(body), check.Equals, DescribeInstancesExample1)
+}
+
+func (s *S) TestOnSign(c *check.C) {
+	var action, canonical, stringToSign string
+	s.ec2.OnSign = func(a, creq, sts string) {
+		action, canonical, stringToSign = a, creq, sts
+	}
+	defer func() { s.ec2.OnSign = nil }()
+
+	testServer.Response(200, nil, DescribeInstancesExample1)
+	_, err := s.ec2.DescribeInstances(nil, nil)
+	c.Assert(err, check.IsNil)
+	req := testServer.WaitRequest()
+
+	c.Assert(action, check.Equals, "DescribeInstances")
+	c.Assert(strings.Contains(canonical, "Action=DescribeInstances"), check.Equals, true)
+	c.Assert(strings.Contains(canonical, "Signature="), check.Equals, false)
+	c.Assert(strings.HasPrefix(stringToSign, "GET\n"+req.Host+"\n/\n"), check.Equals, true)
+	c.Assert(strings.HasSuffix(stringToSign, canonical), check.Equals, true)
+}
+
+func (s *S) TestOnRequestTransportError(c *check.C) {
+	e := ec2.New(s.ec2.Auth, aws.Region{EC2Endpoint: aws.ServiceInfo{Endpoint: "http://127.0.0.1:1", Signer: aws.V2Signature}})
+	var statusCodes []int
+	e.OnRequest = func(action string, duration time.Duration, statusCode int) {
+		statusCodes = append(statusCodes, statusCode)
+	}
+
+	_, err := e.DescribeInstances(nil, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(statusCodes, check.DeepEquals, []int{0})
+}
+
+func (s *S) TestMissingRegionEndpoint(c *check.C) {
+	for _, endpoint := range []string{"", "ec2.us-east-1.amazonaws.com", "/"} {
+		c.Logf("endpoint %q", endpoint)
+		e := ec2.New(s.ec2.Auth, aws.Region{EC2Endpoint: aws.ServiceInfo{Endpoint: endpoint, Signer: aws.V2Signature}})
+		resp, err := e.DescribeInstances(nil, nil)
+		c.Assert(resp, check.IsNil)
+		c.Assert(err, check.Equals, ec2.ErrMissingRegionEndpoint)
+	}
+
+	e := ec2.New(s.ec2.Auth, aws.Region{})
+	_, err := e.DescribeInstances(nil, nil)
+	c.Assert(err, check.Equals, ec2.ErrMissingRegionEndpoint)
+}
+
 func (s *S) TestRunInstancesErrorWithoutXML(c *check.C) {
 	testServer.Response(500, nil, "")
 	options := ec2.RunInstancesOptions{ImageId: "image-id"}
@@ -155,391 +394,694 @@ func (s *S) TestRunInstancesExample(c *check.C) {
 	c.Assert(i2.Hypervisor, check.Equals, "xen")
 }
 
-func (s *S) TestTerminateInstancesExample(c *check.C) {
-	testServer.Response(200, nil, TerminateInstancesExample)
+func (s *S) TestRunInstancesBlockDeviceEncryption(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	resp, err := s.ec2.TerminateInstances([]string{"i-1", "i-2"})
+	options := ec2.RunInstancesOptions{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{
+			{DeviceName: "/dev/sda1", VolumeSize: 50, Encrypted: true, KmsKeyId: "arn:aws:kms:us-east-1:012345678910:key/abcd1234-a123-456a-a12b-a123b4cd56ef"},
+			{DeviceName: "/dev/sdb", NoDevice: true},
+		},
+	}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"TerminateInstances"})
-	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-1"})
-	c.Assert(req.Form["InstanceId.2"], check.DeepEquals, []string{"i-2"})
-	c.Assert(req.Form["UserData"], check.IsNil)
-	c.Assert(req.Form["KernelId"], check.IsNil)
-	c.Assert(req.Form["RamdiskId"], check.IsNil)
-	c.Assert(req.Form["Placement.AvailabilityZone"], check.IsNil)
-	c.Assert(req.Form["Placement.GroupName"], check.IsNil)
-	c.Assert(req.Form["Monitoring.Enabled"], check.IsNil)
-	c.Assert(req.Form["SubnetId"], check.IsNil)
-	c.Assert(req.Form["DisableApiTermination"], check.IsNil)
-	c.Assert(req.Form["InstanceInitiatedShutdownBehavior"], check.IsNil)
-	c.Assert(req.Form["PrivateIpAddress"], check.IsNil)
+	c.Assert(req.Form["BlockDeviceMapping.1.DeviceName"], check.DeepEquals, []string{"/dev/sda1"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.Encrypted"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.KmsKeyId"], check.DeepEquals, []string{"arn:aws:kms:us-east-1:012345678910:key/abcd1234-a123-456a-a12b-a123b4cd56ef"})
+	c.Assert(req.Form["BlockDeviceMapping.1.NoDevice"], check.IsNil)
+	c.Assert(req.Form["BlockDeviceMapping.2.DeviceName"], check.DeepEquals, []string{"/dev/sdb"})
+	c.Assert(req.Form["BlockDeviceMapping.2.NoDevice"], check.DeepEquals, []string{""})
+	c.Assert(req.Form["BlockDeviceMapping.2.Ebs.Encrypted"], check.IsNil)
+}
 
+func (s *S) TestRunInstancesBlockDeviceThroughput(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
+
+	options := ec2.RunInstancesOptions{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{
+			{DeviceName: "/dev/xvda", VolumeType: "gp3", VolumeSize: 100, IOPS: 4000, Throughput: 250},
+		},
+	}
+	_, err := s.ec2.RunInstances(&options)
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.StateChanges, check.HasLen, 1)
-	c.Assert(resp.StateChanges[0].InstanceId, check.Equals, "i-3ea74257")
-	c.Assert(resp.StateChanges[0].CurrentState.Code, check.Equals, 32)
-	c.Assert(resp.StateChanges[0].CurrentState.Name, check.Equals, "shutting-down")
-	c.Assert(resp.StateChanges[0].PreviousState.Code, check.Equals, 16)
-	c.Assert(resp.StateChanges[0].PreviousState.Name, check.Equals, "running")
-}
 
-func (s *S) TestDescribeInstancesExample1(c *check.C) {
-	testServer.Response(200, nil, DescribeInstancesExample1)
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.VolumeType"], check.DeepEquals, []string{"gp3"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.Iops"], check.DeepEquals, []string{"4000"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.Throughput"], check.DeepEquals, []string{"250"})
+}
 
-	filter := ec2.NewFilter()
-	filter.Add("key1", "value1")
-	filter.Add("key2", "value2", "value3")
+func (s *S) TestRunInstancesBlockDeviceMappingIndex(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	resp, err := s.ec2.DescribeInstances([]string{"i-1", "i-2"}, nil)
+	options := ec2.RunInstancesOptions{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{
+			{DeviceName: "/dev/sda1", SnapshotId: "snap-12345678", VolumeSize: 8, DeleteOnTermination: true},
+			{DeviceName: "/dev/sdb", VirtualName: "ephemeral0"},
+		},
+	}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
-	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-1"})
-	c.Assert(req.Form["InstanceId.2"], check.DeepEquals, []string{"i-2"})
+	c.Assert(req.Form["BlockDeviceMapping.0.DeviceName"], check.IsNil)
+	c.Assert(req.Form["BlockDeviceMapping.1.DeviceName"], check.DeepEquals, []string{"/dev/sda1"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.SnapshotId"], check.DeepEquals, []string{"snap-12345678"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.VolumeSize"], check.DeepEquals, []string{"8"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.DeleteOnTermination"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["BlockDeviceMapping.2.DeviceName"], check.DeepEquals, []string{"/dev/sdb"})
+	c.Assert(req.Form["BlockDeviceMapping.2.VirtualName"], check.DeepEquals, []string{"ephemeral0"})
+}
 
-	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "98e3c9a4-848c-4d6d-8e8a-b1bdEXAMPLE")
-	c.Assert(resp.Reservations, check.HasLen, 2)
+func (s *S) TestRunInstancesNetworkInterfaces(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	r0 := resp.Reservations[0]
-	c.Assert(r0.ReservationId, check.Equals, "r-b27e30d9")
-	c.Assert(r0.OwnerId, check.Equals, "999988887777")
-	c.Assert(r0.RequesterId, check.Equals, "854251627541")
-	c.Assert(r0.SecurityGroups, check.DeepEquals, []ec2.SecurityGroup{{Name: "default", Id: "sg-67ad940e"}})
-	c.Assert(r0.Instances, check.HasLen, 1)
+	options := ec2.RunInstancesOptions{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		NetworkInterfaces: []ec2.NetworkInterface{{
+			SubnetId:                 "subnet-1a2b3c4d",
+			AssociatePublicIpAddress: true,
+			DeleteOnTermination:      true,
+			SecurityGroups:           []ec2.SecurityGroup{{Id: "sg-1a2b3c4d"}, {Id: "sg-2b3c4d5e"}},
+			PrivateIpAddresses: []ec2.InstancePrivateIpAddress{
+				{PrivateIPAddress: "10.0.0.10", Primary: true},
+				{PrivateIPAddress: "10.0.0.11"},
+			},
+		}, {
+			NetworkInterfaceId: "eni-1a2b3c4d",
+			DeviceIndex:        3,
+		}, {
+			SubnetId:                       "subnet-2b3c4d5e",
+			SecondaryPrivateIpAddressCount: 2,
+		}},
+	}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
-	r0i := r0.Instances[0]
-	c.Assert(r0i.InstanceId, check.Equals, "i-c5cd56af")
-	c.Assert(r0i.PrivateDNSName, check.Equals, "domU-12-31-39-10-56-34.compute-1.internal")
-	c.Assert(r0i.DNSName, check.Equals, "ec2-174-129-165-232.compute-1.amazonaws.com")
-	c.Assert(r0i.AvailabilityZone, check.Equals, "us-east-1b")
-	c.Assert(r0i.IPAddress, check.Equals, "174.129.165.232")
-	c.Assert(r0i.PrivateIPAddress, check.Equals, "10.198.85.190")
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["SubnetId"], check.IsNil)
+	c.Assert(req.Form["NetworkInterface.1.DeviceIndex"], check.DeepEquals, []string{"0"})
+	c.Assert(req.Form["NetworkInterface.1.SubnetId"], check.DeepEquals, []string{"subnet-1a2b3c4d"})
+	c.Assert(req.Form["NetworkInterface.1.AssociatePublicIpAddress"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["NetworkInterface.1.DeleteOnTermination"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["NetworkInterface.1.SecurityGroupId.1"], check.DeepEquals, []string{"sg-1a2b3c4d"})
+	c.Assert(req.Form["NetworkInterface.1.SecurityGroupId.2"], check.DeepEquals, []string{"sg-2b3c4d5e"})
+	c.Assert(req.Form["NetworkInterface.1.PrivateIpAddresses.1.PrivateIpAddress"], check.DeepEquals, []string{"10.0.0.10"})
+	c.Assert(req.Form["NetworkInterface.1.PrivateIpAddresses.1.Primary"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["NetworkInterface.1.PrivateIpAddresses.2.PrivateIpAddress"], check.DeepEquals, []string{"10.0.0.11"})
+	c.Assert(req.Form["NetworkInterface.1.PrivateIpAddresses.2.Primary"], check.IsNil)
+	c.Assert(req.Form["NetworkInterface.2.DeviceIndex"], check.DeepEquals, []string{"3"})
+	c.Assert(req.Form["NetworkInterface.2.NetworkInterfaceId"], check.DeepEquals, []string{"eni-1a2b3c4d"})
+	c.Assert(req.Form["NetworkInterface.2.SubnetId"], check.IsNil)
+	c.Assert(req.Form["NetworkInterface.3.DeviceIndex"], check.DeepEquals, []string{"2"})
+	c.Assert(req.Form["NetworkInterface.3.SecondaryPrivateIpAddressCount"], check.DeepEquals, []string{"2"})
 }
 
-func (s *S) TestDescribeInstancesExample2(c *check.C) {
-	testServer.Response(200, nil, DescribeInstancesExample2)
-
-	filter := ec2.NewFilter()
-	filter.Add("key1", "value1")
-	filter.Add("key2", "value2", "value3")
+func (s *S) TestRunInstancesEbsOptimizedTenancy(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	resp, err := s.ec2.DescribeInstances([]string{"i-1", "i-2"}, filter)
+	options := ec2.RunInstancesOptions{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		EbsOptimized: true,
+		Tenancy:      "dedicated",
+	}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
-	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-1"})
-	c.Assert(req.Form["InstanceId.2"], check.DeepEquals, []string{"i-2"})
-	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
-	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
-	c.Assert(req.Form["Filter.1.Value.2"], check.IsNil)
-	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
-	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"value2"})
-	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"value3"})
+	c.Assert(req.Form["EbsOptimized"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["Placement.Tenancy"], check.DeepEquals, []string{"dedicated"})
 
+	testServer.Response(200, nil, RunInstancesExample)
+	_, err = s.ec2.RunInstances(&ec2.RunInstancesOptions{ImageId: "image-id"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Reservations, check.HasLen, 1)
 
-	r0 := resp.Reservations[0]
-	r0i := r0.Instances[0]
-	c.Assert(r0i.State.Code, check.Equals, 16)
-	c.Assert(r0i.State.Name, check.Equals, "running")
-
-	r0t0 := r0i.Tags[0]
-	r0t1 := r0i.Tags[1]
-	c.Assert(r0t0.Key, check.Equals, "webserver")
-	c.Assert(r0t0.Value, check.Equals, "")
-	c.Assert(r0t1.Key, check.Equals, "stack")
-	c.Assert(r0t1.Value, check.Equals, "Production")
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["EbsOptimized"], check.IsNil)
+	c.Assert(req.Form["Placement.Tenancy"], check.IsNil)
 }
 
-func (s *S) TestDescribeAddressesPublicIPExample(c *check.C) {
-	testServer.Response(200, nil, DescribeAddressesExample)
-
-	filter := ec2.NewFilter()
-	filter.Add("key1", "value1")
-	filter.Add("key2", "value2", "value3")
+func (s *S) TestRunInstancesMarketOptions(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	resp, err := s.ec2.DescribeAddresses([]string{"192.0.2.1", "198.51.100.2", "203.0.113.41"}, []string{}, nil)
+	options := ec2.RunInstancesOptions{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		InstanceMarketOptions: &ec2.MarketOptions{
+			MaxPrice:                     "0.05",
+			SpotInstanceType:             "persistent",
+			ValidUntil:                   "2017-01-01T00:00:00Z",
+			InstanceInterruptionBehavior: "stop",
+		},
+	}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeAddresses"})
-	c.Assert(req.Form["PublicIp.1"], check.DeepEquals, []string{"192.0.2.1"})
-	c.Assert(req.Form["PublicIp.2"], check.DeepEquals, []string{"198.51.100.2"})
-	c.Assert(req.Form["PublicIp.3"], check.DeepEquals, []string{"203.0.113.41"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["InstanceMarketOptions.MarketType"], check.DeepEquals, []string{"spot"})
+	c.Assert(req.Form["InstanceMarketOptions.SpotOptions.MaxPrice"], check.DeepEquals, []string{"0.05"})
+	c.Assert(req.Form["InstanceMarketOptions.SpotOptions.SpotInstanceType"], check.DeepEquals, []string{"persistent"})
+	c.Assert(req.Form["InstanceMarketOptions.SpotOptions.ValidUntil"], check.DeepEquals, []string{"2017-01-01T00:00:00Z"})
+	c.Assert(req.Form["InstanceMarketOptions.SpotOptions.InstanceInterruptionBehavior"], check.DeepEquals, []string{"stop"})
 
+	testServer.Response(200, nil, RunInstancesExample)
+	_, err = s.ec2.RunInstances(&ec2.RunInstancesOptions{ImageId: "image-id"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Addresses, check.HasLen, 3)
 
-	r0 := resp.Addresses[0]
-	c.Assert(r0.PublicIp, check.Equals, "192.0.2.1")
-	c.Assert(r0.Domain, check.Equals, "standard")
-	c.Assert(r0.InstanceId, check.Equals, "i-f15ebb98")
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["InstanceMarketOptions.MarketType"], check.IsNil)
+}
 
-	r0i := resp.Addresses[1]
-	c.Assert(r0i.PublicIp, check.Equals, "198.51.100.2")
-	c.Assert(r0i.Domain, check.Equals, "standard")
-	c.Assert(r0i.InstanceId, check.Equals, "")
+func (s *S) TestRunInstancesTagSpecifications(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	r0ii := resp.Addresses[2]
-	c.Assert(r0ii.PublicIp, check.Equals, "203.0.113.41")
-	c.Assert(r0ii.Domain, check.Equals, "vpc")
-	c.Assert(r0ii.InstanceId, check.Equals, "i-64600030")
-	c.Assert(r0ii.AssociationId, check.Equals, "eipassoc-f0229899")
-	c.Assert(r0ii.AllocationId, check.Equals, "eipalloc-08229861")
-	c.Assert(r0ii.NetworkInterfaceOwnerId, check.Equals, "053230519467")
-	c.Assert(r0ii.NetworkInterfaceId, check.Equals, "eni-ef229886")
-	c.Assert(r0ii.PrivateIpAddress, check.Equals, "10.0.0.228")
-}
+	options := ec2.RunInstancesOptions{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		TagSpecifications: []ec2.TagSpecification{
+			{ResourceType: "instance", Tags: []ec2.Tag{{"Name", "web"}, {"env", "prod"}}},
+			{ResourceType: "volume", Tags: []ec2.Tag{{"env", "prod"}}},
+		},
+	}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
-func (s *S) TestDescribeAddressesAllocationIDExample(c *check.C) {
-	testServer.Response(200, nil, DescribeAddressesAllocationIdExample)
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["TagSpecification.1.ResourceType"], check.DeepEquals, []string{"instance"})
+	c.Assert(req.Form["TagSpecification.1.Tag.1.Key"], check.DeepEquals, []string{"Name"})
+	c.Assert(req.Form["TagSpecification.1.Tag.1.Value"], check.DeepEquals, []string{"web"})
+	c.Assert(req.Form["TagSpecification.1.Tag.2.Key"], check.DeepEquals, []string{"env"})
+	c.Assert(req.Form["TagSpecification.1.Tag.2.Value"], check.DeepEquals, []string{"prod"})
+	c.Assert(req.Form["TagSpecification.2.ResourceType"], check.DeepEquals, []string{"volume"})
+	c.Assert(req.Form["TagSpecification.2.Tag.1.Key"], check.DeepEquals, []string{"env"})
+	c.Assert(req.Form["TagSpecification.2.Tag.1.Value"], check.DeepEquals, []string{"prod"})
+}
 
-	filter := ec2.NewFilter()
-	filter.Add("key1", "value1")
-	filter.Add("key2", "value2", "value3")
+func (s *S) TestRunInstancesWithLaunchTemplate(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	resp, err := s.ec2.DescribeAddresses([]string{}, []string{"eipalloc-08229861", "eipalloc-08364752"}, nil)
+	options := ec2.RunInstancesOptions{
+		LaunchTemplate: &ec2.LaunchTemplateSpec{LaunchTemplateId: "lt-0a20c965061f64abc", Version: "2"},
+		SubnetId:       "subnet-7b16de0c",
+	}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeAddresses"})
-	c.Assert(req.Form["AllocationId.1"], check.DeepEquals, []string{"eipalloc-08229861"})
-	c.Assert(req.Form["AllocationId.2"], check.DeepEquals, []string{"eipalloc-08364752"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["LaunchTemplate.LaunchTemplateId"], check.DeepEquals, []string{"lt-0a20c965061f64abc"})
+	c.Assert(req.Form["LaunchTemplate.LaunchTemplateName"], check.IsNil)
+	c.Assert(req.Form["LaunchTemplate.Version"], check.DeepEquals, []string{"2"})
+	c.Assert(req.Form["SubnetId"], check.DeepEquals, []string{"subnet-7b16de0c"})
+	c.Assert(req.Form["ImageId"], check.IsNil)
+	c.Assert(req.Form["InstanceType"], check.IsNil)
+	c.Assert(req.Form["MinCount"], check.DeepEquals, []string{"1"})
+}
 
-	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Addresses, check.HasLen, 2)
+func (s *S) TestRunInstancesOverridingLaunchTemplate(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	r0 := resp.Addresses[0]
-	c.Assert(r0.PublicIp, check.Equals, "203.0.113.41")
-	c.Assert(r0.AllocationId, check.Equals, "eipalloc-08229861")
-	c.Assert(r0.Domain, check.Equals, "vpc")
-	c.Assert(r0.InstanceId, check.Equals, "i-64600030")
-	c.Assert(r0.AssociationId, check.Equals, "eipassoc-f0229899")
-	c.Assert(r0.NetworkInterfaceId, check.Equals, "eni-ef229886")
-	c.Assert(r0.NetworkInterfaceOwnerId, check.Equals, "053230519467")
-	c.Assert(r0.PrivateIpAddress, check.Equals, "10.0.0.228")
+	options := ec2.RunInstancesOptions{
+		LaunchTemplate: &ec2.LaunchTemplateSpec{LaunchTemplateName: "MyLaunchTemplate"},
+		InstanceType:   "m5.large",
+	}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
-	r1 := resp.Addresses[1]
-	c.Assert(r1.PublicIp, check.Equals, "146.54.2.230")
-	c.Assert(r1.AllocationId, check.Equals, "eipalloc-08364752")
-	c.Assert(r1.Domain, check.Equals, "vpc")
-	c.Assert(r1.InstanceId, check.Equals, "i-64693456")
-	c.Assert(r1.AssociationId, check.Equals, "eipassoc-f0348693")
-	c.Assert(r1.NetworkInterfaceId, check.Equals, "eni-da764039")
-	c.Assert(r1.NetworkInterfaceOwnerId, check.Equals, "053230519467")
-	c.Assert(r1.PrivateIpAddress, check.Equals, "10.0.0.102")
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["LaunchTemplate.LaunchTemplateName"], check.DeepEquals, []string{"MyLaunchTemplate"})
+	c.Assert(req.Form["LaunchTemplate.Version"], check.IsNil)
+	c.Assert(req.Form["InstanceType"], check.DeepEquals, []string{"m5.large"})
+	c.Assert(req.Form["ImageId"], check.IsNil)
 }
 
-func (s *S) TestAllocateAddressExample(c *check.C) {
-	testServer.Response(200, nil, AllocateAddressExample)
+func (s *S) TestRunInstancesLargeUserData(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	resp, err := s.ec2.AllocateAddress("vpc")
+	userData := bytes.Repeat([]byte("#"), ec2.MaxUserDataSize)
+	options := ec2.RunInstancesOptions{ImageId: "image-id", UserData: userData}
+	_, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AllocateAddress"})
-	c.Assert(req.Form["Domain"], check.DeepEquals, []string{"vpc"})
+	c.Assert(req.Method, check.Equals, "POST")
+	c.Assert(req.URL.RawQuery, check.Equals, "")
+	c.Assert(req.Form["UserData"], check.DeepEquals, []string{base64.StdEncoding.EncodeToString(userData)})
+}
 
-	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.PublicIp, check.Equals, "198.51.100.1")
-	c.Assert(resp.Domain, check.Equals, "vpc")
-	c.Assert(resp.AllocationId, check.Equals, "eipalloc-5723d13e")
+func (s *S) TestRunInstancesUserDataTooLarge(c *check.C) {
+	options := ec2.RunInstancesOptions{
+		ImageId:  "image-id",
+		UserData: make([]byte, ec2.MaxUserDataSize+1),
+	}
+	resp, err := s.ec2.RunInstances(&options)
+	c.Assert(resp, check.IsNil)
+	c.Assert(err, check.Equals, ec2.ErrUserDataTooLarge)
 }
 
-func (s *S) TestReleaseAddressExample(c *check.C) {
-	testServer.Response(200, nil, ReleaseAddressExample)
+func (s *S) TestRunInstancesGzipUserData(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	resp, err := s.ec2.ReleaseAddress("192.0.2.1", "")
+	script := []byte("#cloud-config\n" + strings.Repeat("runcmd: [echo, hello]\n", 2000))
+	userData, err := ec2.GzipUserData(script)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(script) > ec2.MaxUserDataSize, check.Equals, true)
+	c.Assert(len(userData) < ec2.MaxUserDataSize, check.Equals, true)
 
-	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ReleaseAddress"})
-	c.Assert(req.Form["PublicIp"], check.DeepEquals, []string{"192.0.2.1"})
+	_, err = s.ec2.RunInstances(&ec2.RunInstancesOptions{ImageId: "image-id", UserData: userData})
+	c.Assert(err, check.IsNil)
 
+	req := testServer.WaitRequest()
+	decoded, err := base64.StdEncoding.DecodeString(req.Form.Get("UserData"))
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Return, check.Equals, true)
+	r, err := gzip.NewReader(bytes.NewReader(decoded))
+	c.Assert(err, check.IsNil)
+	content, err := ioutil.ReadAll(r)
+	c.Assert(err, check.IsNil)
+	c.Assert(content, check.DeepEquals, script)
 }
 
-func (s *S) TestAssociateAddressExample(c *check.C) {
-	testServer.Response(200, nil, AssociateAddressExample)
-
-	options := ec2.AssociateAddressOptions{
-		PublicIp:   "192.0.2.1",
-		InstanceId: "i-2ea64347",
+func (s *S) TestRunInstancesPlacement(c *check.C) {
+	tests := []struct {
+		about     string
+		zone      string
+		group     string
+		tenancy   string
+		placement map[string]string // expected Placement.* parameters.
+	}{{
+		about:     "no placement",
+		placement: map[string]string{},
+	}, {
+		about:     "zone only",
+		zone:      "us-east-1a",
+		placement: map[string]string{"Placement.AvailabilityZone": "us-east-1a"},
+	}, {
+		about:     "group only",
+		group:     "cluster",
+		placement: map[string]string{"Placement.GroupName": "cluster"},
+	}, {
+		about:     "tenancy only",
+		tenancy:   "dedicated",
+		placement: map[string]string{"Placement.Tenancy": "dedicated"},
+	}, {
+		about: "zone and group",
+		zone:  "us-east-1a",
+		group: "cluster",
+		placement: map[string]string{
+			"Placement.AvailabilityZone": "us-east-1a",
+			"Placement.GroupName":        "cluster",
+		},
+	}, {
+		about:   "zone and tenancy",
+		zone:    "us-east-1a",
+		tenancy: "dedicated",
+		placement: map[string]string{
+			"Placement.AvailabilityZone": "us-east-1a",
+			"Placement.Tenancy":          "dedicated",
+		},
+	}, {
+		about:   "group and tenancy",
+		group:   "cluster",
+		tenancy: "dedicated",
+		placement: map[string]string{
+			"Placement.GroupName": "cluster",
+			"Placement.Tenancy":   "dedicated",
+		},
+	}, {
+		about:   "zone, group and tenancy",
+		zone:    "us-east-1a",
+		group:   "cluster",
+		tenancy: "dedicated",
+		placement: map[string]string{
+			"Placement.AvailabilityZone": "us-east-1a",
+			"Placement.GroupName":        "cluster",
+			"Placement.Tenancy":          "dedicated",
+		},
+	}}
+	for i, t := range tests {
+		c.Logf("%d. %s", i, t.about)
+		testServer.Response(200, nil, RunInstancesExample)
+
+		_, err := s.ec2.RunInstances(&ec2.RunInstancesOptions{
+			ImageId:            "image-id",
+			AvailabilityZone:   t.zone,
+			PlacementGroupName: t.group,
+			Tenancy:            t.tenancy,
+		})
+		c.Assert(err, check.IsNil)
+
+		req := testServer.WaitRequest()
+		placement := make(map[string]string)
+		for k, v := range req.Form {
+			if strings.HasPrefix(k, "Placement.") {
+				placement[k] = v[0]
+			}
+		}
+		c.Assert(placement, check.DeepEquals, t.placement)
 	}
+}
 
-	resp, err := s.ec2.AssociateAddress(&options)
+func (s *S) TestRunInstancesClientToken(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AssociateAddress"})
-	c.Assert(req.Form["PublicIp"], check.DeepEquals, []string{"192.0.2.1"})
-	c.Assert(req.Form["InstanceId"], check.DeepEquals, []string{"i-2ea64347"})
+	options := ec2.RunInstancesOptions{ImageId: "image-id", ClientToken: "my-token"}
+	resp, err := s.ec2.RunInstances(&options)
 
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["ClientToken"], check.DeepEquals, []string{"my-token"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Return, check.Equals, true)
-	c.Assert(resp.AssociationId, check.Equals, "eipassoc-fc5ca095")
+	c.Assert(resp.ClientToken, check.Equals, "my-token")
 }
 
-func (s *S) TestDiassociateAddressExample(c *check.C) {
-	testServer.Response(200, nil, DiassociateAddressExample)
+func (s *S) TestRunInstancesGeneratedClientToken(c *check.C) {
+	testServer.Response(200, nil, RunInstancesExample)
 
-	resp, err := s.ec2.DiassociateAddress("192.0.2.1", "")
+	options := ec2.RunInstancesOptions{ImageId: "image-id"}
+	resp, err := s.ec2.RunInstances(&options)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DiassociateAddress"})
-	c.Assert(req.Form["PublicIp"], check.DeepEquals, []string{"192.0.2.1"})
-
+	c.Assert(req.Form["ClientToken"], check.HasLen, 1)
+	c.Assert(req.Form["ClientToken"][0], check.Matches, "[0-9a-f]{64}")
+	c.Assert(options.ClientToken, check.Equals, "")
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Return, check.Equals, true)
+	c.Assert(resp.ClientToken, check.Equals, req.Form["ClientToken"][0])
 }
 
-func (s *S) TestDescribeImagesExample(c *check.C) {
-	testServer.Response(200, nil, DescribeImagesExample)
+func (s *S) TestRunInstancesRetryWithClientToken(c *check.C) {
+	testServer.Response(500, nil, "")
+	testServer.Response(200, nil, RunInstancesExample)
 
-	filter := ec2.NewFilter()
-	filter.Add("key1", "value1")
-	filter.Add("key2", "value2", "value3")
+	options := ec2.RunInstancesOptions{ImageId: "image-id", ClientToken: "retry-token"}
+	resp, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.ErrorMatches, "500 Internal Server Error")
+	c.Assert(resp, check.IsNil)
 
-	resp, err := s.ec2.Images([]string{"ami-1", "ami-2"}, filter)
+	retry, err := s.ec2.RunInstances(&options)
+	c.Assert(err, check.IsNil)
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["ClientToken"], check.DeepEquals, []string{"retry-token"})
+	c.Assert(reqs[1].Form["ClientToken"], check.DeepEquals, []string{"retry-token"})
+	c.Assert(retry.ClientToken, check.Equals, "retry-token")
+	c.Assert(retry.Instances, check.HasLen, 3)
+}
+
+func (s *S) TestTerminateInstancesExample(c *check.C) {
+	testServer.Response(200, nil, TerminateInstancesExample)
+
+	resp, err := s.ec2.TerminateInstances([]string{"i-1", "i-2"})
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeImages"})
-	c.Assert(req.Form["ImageId.1"], check.DeepEquals, []string{"ami-1"})
-	c.Assert(req.Form["ImageId.2"], check.DeepEquals, []string{"ami-2"})
-	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
-	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
-	c.Assert(req.Form["Filter.1.Value.2"], check.IsNil)
-	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
-	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"value2"})
-	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"value3"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"TerminateInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-1"})
+	c.Assert(req.Form["InstanceId.2"], check.DeepEquals, []string{"i-2"})
+	c.Assert(req.Form["UserData"], check.IsNil)
+	c.Assert(req.Form["KernelId"], check.IsNil)
+	c.Assert(req.Form["RamdiskId"], check.IsNil)
+	c.Assert(req.Form["Placement.AvailabilityZone"], check.IsNil)
+	c.Assert(req.Form["Placement.GroupName"], check.IsNil)
+	c.Assert(req.Form["Monitoring.Enabled"], check.IsNil)
+	c.Assert(req.Form["SubnetId"], check.IsNil)
+	c.Assert(req.Form["DisableApiTermination"], check.IsNil)
+	c.Assert(req.Form["InstanceInitiatedShutdownBehavior"], check.IsNil)
+	c.Assert(req.Form["PrivateIpAddress"], check.IsNil)
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "4a4a27a2-2e7c-475d-b35b-ca822EXAMPLE")
-	c.Assert(resp.Images, check.HasLen, 1)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.StateChanges, check.HasLen, 1)
+	c.Assert(resp.StateChanges[0].InstanceId, check.Equals, "i-3ea74257")
+	c.Assert(resp.StateChanges[0].CurrentState.Code, check.Equals, 32)
+	c.Assert(resp.StateChanges[0].CurrentState.Name, check.Equals, "shutting-down")
+	c.Assert(resp.StateChanges[0].PreviousState.Code, check.Equals, 16)
+	c.Assert(resp.StateChanges[0].PreviousState.Name, check.Equals, "running")
+}
 
-	i0 := resp.Images[0]
-	c.Assert(i0.Id, check.Equals, "ami-a2469acf")
-	c.Assert(i0.Type, check.Equals, "machine")
-	c.Assert(i0.Name, check.Equals, "example-marketplace-amzn-ami.1")
-	c.Assert(i0.Description, check.Equals, "Amazon Linux AMI i386 EBS")
-	c.Assert(i0.Location, check.Equals, "aws-marketplace/example-marketplace-amzn-ami.1")
-	c.Assert(i0.State, check.Equals, "available")
-	c.Assert(i0.Public, check.Equals, true)
-	c.Assert(i0.OwnerId, check.Equals, "123456789999")
-	c.Assert(i0.OwnerAlias, check.Equals, "aws-marketplace")
-	c.Assert(i0.Architecture, check.Equals, "i386")
-	c.Assert(i0.KernelId, check.Equals, "aki-805ea7e9")
-	c.Assert(i0.RootDeviceType, check.Equals, "ebs")
-	c.Assert(i0.RootDeviceName, check.Equals, "/dev/sda1")
-	c.Assert(i0.VirtualizationType, check.Equals, "paravirtual")
-	c.Assert(i0.Hypervisor, check.Equals, "xen")
+func (s *S) TestTerminateInstancesPartiallyNotFound(c *check.C) {
+	testServer.Response(400, nil, InstancesNotFoundDump)
+	testServer.Response(200, nil, TerminateInstancesExample)
 
-	c.Assert(i0.Tags, check.HasLen, 1)
-	c.Assert(i0.Tags[0].Key, check.Equals, "Purpose")
-	c.Assert(i0.Tags[0].Value, check.Equals, "EXAMPLE")
+	resp, err := s.ec2.TerminateInstances([]string{"i-00000001", "i-3ea74257", "i-00000002"})
 
-	c.Assert(i0.BlockDevices, check.HasLen, 1)
-	c.Assert(i0.BlockDevices[0].DeviceName, check.Equals, "/dev/sda1")
-	c.Assert(i0.BlockDevices[0].SnapshotId, check.Equals, "snap-787e9403")
-	c.Assert(i0.BlockDevices[0].VolumeSize, check.Equals, int64(8))
-	c.Assert(i0.BlockDevices[0].DeleteOnTermination, check.Equals, true)
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["InstanceId.3"], check.DeepEquals, []string{"i-00000002"})
+	c.Assert(reqs[1].Form["InstanceId.1"], check.DeepEquals, []string{"i-3ea74257"})
+	c.Assert(reqs[1].Form["InstanceId.2"], check.IsNil)
+
+	notFound, ok := err.(*ec2.InstancesNotFoundError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(notFound.InstanceIds, check.DeepEquals, []string{"i-00000001", "i-00000002"})
+	c.Assert(notFound.Err.Code, check.Equals, "InvalidInstanceID.NotFound")
+	c.Assert(ec2.IsErrorCode(err, "InvalidInstanceID.NotFound"), check.Equals, true)
+	c.Assert(err, check.ErrorMatches, "The instance IDs 'i-00000001, i-00000002' do not exist.*")
+	c.Assert(resp.StateChanges, check.HasLen, 1)
+	c.Assert(resp.StateChanges[0].InstanceId, check.Equals, "i-3ea74257")
 }
 
-func (s *S) TestCreateSnapshotExample(c *check.C) {
-	testServer.Response(200, nil, CreateSnapshotExample)
+func (s *S) TestTerminateInstancesAllNotFound(c *check.C) {
+	testServer.Response(400, nil, InstancesNotFoundDump)
 
-	resp, err := s.ec2.CreateSnapshot("vol-4d826724", "Daily Backup")
+	resp, err := s.ec2.TerminateInstances([]string{"i-00000001", "i-00000002"})
 
-	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateSnapshot"})
-	c.Assert(req.Form["VolumeId"], check.DeepEquals, []string{"vol-4d826724"})
-	c.Assert(req.Form["Description"], check.DeepEquals, []string{"Daily Backup"})
+	testServer.WaitRequest()
+	c.Assert(resp, check.IsNil)
+	c.Assert(err.(*ec2.Error).Code, check.Equals, "InvalidInstanceID.NotFound")
+	c.Assert(ec2.IsErrorCode(err, "InvalidInstanceID.NotFound"), check.Equals, true)
+}
 
-	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Snapshot.Id, check.Equals, "snap-78a54011")
-	c.Assert(resp.Snapshot.VolumeId, check.Equals, "vol-4d826724")
-	c.Assert(resp.Snapshot.Status, check.Equals, "pending")
-	c.Assert(resp.Snapshot.StartTime, check.Equals, "2008-05-07T12:51:50.000Z")
-	c.Assert(resp.Snapshot.Progress, check.Equals, "60%")
-	c.Assert(resp.Snapshot.OwnerId, check.Equals, "111122223333")
-	c.Assert(resp.Snapshot.VolumeSize, check.Equals, "10")
-	c.Assert(resp.Snapshot.Description, check.Equals, "Daily Backup")
+func (s *S) TestTerminateInstancesUnknownNotFound(c *check.C) {
+	testServer.Response(400, nil, InstancesNotFoundDump)
+
+	resp, err := s.ec2.TerminateInstances([]string{"i-3ea74257"})
+
+	testServer.WaitRequest()
+	c.Assert(resp, check.IsNil)
+	c.Assert(err.(*ec2.Error).Code, check.Equals, "InvalidInstanceID.NotFound")
 }
 
-func (s *S) TestDeleteSnapshotsExample(c *check.C) {
-	testServer.Response(200, nil, DeleteSnapshotExample)
+func (s *S) TestInstancesByTag(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
 
-	resp, err := s.ec2.DeleteSnapshots("snap-78a54011")
+	resp, err := s.ec2.InstancesByTag("Name", "web-1")
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteSnapshot"})
-	c.Assert(req.Form["SnapshotId.1"], check.DeepEquals, []string{"snap-78a54011"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.IsNil)
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"tag:Name"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"web-1"})
+	c.Assert(req.Form["Filter.2.Name"], check.IsNil)
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Reservations[0].Instances[0].InstanceId, check.Equals, "i-c5cd56af")
 }
 
-func (s *S) TestDescribeSnapshotsExample(c *check.C) {
-	testServer.Response(200, nil, DescribeSnapshotsExample)
+func (s *S) TestRunningInstances(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
 
 	filter := ec2.NewFilter()
-	filter.Add("key1", "value1")
-	filter.Add("key2", "value2", "value3")
-
-	resp, err := s.ec2.Snapshots([]string{"snap-1", "snap-2"}, filter)
+	filter.Add("tag:Role", "web")
+	filter.Add("instance-state-name", "pending")
+	_, err := s.ec2.RunningInstances(filter)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSnapshots"})
-	c.Assert(req.Form["SnapshotId.1"], check.DeepEquals, []string{"snap-1"})
-	c.Assert(req.Form["SnapshotId.2"], check.DeepEquals, []string{"snap-2"})
-	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
-	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"instance-state-name"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"running"})
 	c.Assert(req.Form["Filter.1.Value.2"], check.IsNil)
-	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
-	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"value2"})
-	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"value3"})
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"tag:Role"})
+	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"web"})
+	c.Assert(err, check.IsNil)
 
+	// The caller's filter is left untouched.
+	testServer.Response(200, nil, DescribeInstancesExample1)
+	_, err = s.ec2.DescribeInstances(nil, filter)
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Snapshots, check.HasLen, 1)
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"pending"})
+}
 
-	s0 := resp.Snapshots[0]
-	c.Assert(s0.Id, check.Equals, "snap-1a2b3c4d")
-	c.Assert(s0.VolumeId, check.Equals, "vol-8875daef")
-	c.Assert(s0.VolumeSize, check.Equals, "15")
-	c.Assert(s0.Status, check.Equals, "pending")
-	c.Assert(s0.StartTime, check.Equals, "2010-07-29T04:12:01.000Z")
-	c.Assert(s0.Progress, check.Equals, "30%")
-	c.Assert(s0.OwnerId, check.Equals, "111122223333")
-	c.Assert(s0.Description, check.Equals, "Daily Backup")
+func (s *S) TestStoppedInstances(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
 
-	c.Assert(s0.Tags, check.HasLen, 1)
-	c.Assert(s0.Tags[0].Key, check.Equals, "Purpose")
-	c.Assert(s0.Tags[0].Value, check.Equals, "demo_db_14_backup")
+	_, err := s.ec2.StoppedInstances(nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"instance-state-name"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"stopped"})
+	c.Assert(req.Form["Filter.2.Name"], check.IsNil)
+	c.Assert(err, check.IsNil)
 }
 
-func (s *S) TestDescribeSubnetsExample(c *check.C) {
-	testServer.Response(200, nil, DescribeSubnetsExample)
+func (s *S) TestSpotInstances(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
 
 	filter := ec2.NewFilter()
-	filter.Add("key1", "value1")
-	filter.Add("key2", "value2", "value3")
-
-	resp, err := s.ec2.Subnets([]string{"subnet-1", "subnet-2"}, filter)
+	filter.Add("tag:Fleet", "batch")
+	_, err := s.ec2.SpotInstances(filter)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSubnets"})
-	c.Assert(req.Form["SubnetId.1"], check.DeepEquals, []string{"subnet-1"})
-	c.Assert(req.Form["SubnetId.2"], check.DeepEquals, []string{"subnet-2"})
-	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"instance-lifecycle"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"spot"})
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"tag:Fleet"})
+	c.Assert(filter.Names(), check.DeepEquals, []string{"tag:Fleet"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestIsSpotInstance(c *check.C) {
+	c.Assert(ec2.Instance{InstanceLifecycle: "spot"}.IsSpotInstance(), check.Equals, true)
+	c.Assert(ec2.Instance{}.IsSpotInstance(), check.Equals, false)
+}
+
+func (s *S) TestDescribeInstancesEventuallyConsistent(c *check.C) {
+	var delays []time.Duration
+	ec2.SetSleep(func(d time.Duration) { delays = append(delays, d) })
+	defer ec2.SetSleep(nil)
+
+	testServer.Response(400, nil, InstanceNotFoundDump)
+	testServer.Response(400, nil, InstanceNotFoundDump)
+	testServer.Response(200, nil, DescribeInstancesExample2)
+
+	resp, err := s.ec2.DescribeInstancesEventuallyConsistent([]string{"i-c7cd56ad"}, nil, nil)
+
+	reqs := testServer.WaitRequests(3)
+	c.Assert(reqs[2].Form["InstanceId.1"], check.DeepEquals, []string{"i-c7cd56ad"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.AllInstances(), check.HasLen, 1)
+	c.Assert(delays, check.DeepEquals, []time.Duration{500 * time.Millisecond, time.Second})
+}
+
+func (s *S) TestDescribeInstancesEventuallyConsistentTimeout(c *check.C) {
+	var delays []time.Duration
+	ec2.SetSleep(func(d time.Duration) { delays = append(delays, d) })
+	defer ec2.SetSleep(nil)
+
+	testServer.Responses(5, 400, nil, InstanceNotFoundDump)
+
+	_, err := s.ec2.DescribeInstancesEventuallyConsistent([]string{"i-00000000"}, nil, &ec2.ConsistencyOptions{
+		Timeout:   time.Second,
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  300 * time.Millisecond,
+	})
+
+	testServer.WaitRequests(5)
+	c.Assert(err.(*ec2.Error).Code, check.Equals, "InvalidInstanceID.NotFound")
+	c.Assert(delays, check.DeepEquals, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		300 * time.Millisecond,
+		300 * time.Millisecond,
+	})
+}
+
+func (s *S) TestDescribeInstancesEventuallyConsistentOtherError(c *check.C) {
+	testServer.Response(400, nil, ErrorDump)
+
+	_, err := s.ec2.DescribeInstancesEventuallyConsistent([]string{"i-00000000"}, nil, nil)
+
+	testServer.WaitRequest()
+	c.Assert(err.(*ec2.Error).Code, check.Equals, "UnsupportedOperation")
+}
+
+func (s *S) TestInstance(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample2)
+
+	inst, err := s.ec2.Instance("i-c7cd56ad")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-c7cd56ad"})
+	c.Assert(req.Form["InstanceId.2"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(inst.InstanceId, check.Equals, "i-c7cd56ad")
+	c.Assert(inst.OwnerId, check.Equals, "999988887777")
+}
+
+func (s *S) TestInstanceNotFound(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesEmptyExample)
+
+	inst, err := s.ec2.Instance("i-00000000")
+	testServer.WaitRequest()
+	c.Assert(inst, check.IsNil)
+	c.Assert(err, check.Equals, ec2.ErrInstanceNotFound)
+
+	testServer.Response(400, nil, InstanceNotFoundDump)
+
+	inst, err = s.ec2.Instance("i-00000000")
+	testServer.WaitRequest()
+	c.Assert(inst, check.IsNil)
+	c.Assert(err, check.Equals, ec2.ErrInstanceNotFound)
+}
+
+func (s *S) TestInstanceMultiple(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	inst, err := s.ec2.Instance("i-c5cd56af")
+	testServer.WaitRequest()
+	c.Assert(inst, check.IsNil)
+	c.Assert(err, check.ErrorMatches, `2 instances found with id "i-c5cd56af"`)
+}
+
+func (s *S) TestDescribeInstancesExample1(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+	filter.Add("key2", "value2", "value3")
+
+	resp, err := s.ec2.DescribeInstances([]string{"i-1", "i-2"}, nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-1"})
+	c.Assert(req.Form["InstanceId.2"], check.DeepEquals, []string{"i-2"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "98e3c9a4-848c-4d6d-8e8a-b1bdEXAMPLE")
+	c.Assert(resp.Reservations, check.HasLen, 2)
+
+	r0 := resp.Reservations[0]
+	c.Assert(r0.ReservationId, check.Equals, "r-b27e30d9")
+	c.Assert(r0.OwnerId, check.Equals, "999988887777")
+	c.Assert(r0.RequesterId, check.Equals, "854251627541")
+	c.Assert(r0.SecurityGroups, check.DeepEquals, []ec2.SecurityGroup{{Name: "default", Id: "sg-67ad940e"}})
+	c.Assert(r0.Instances, check.HasLen, 1)
+
+	r0i := r0.Instances[0]
+	c.Assert(r0i.InstanceId, check.Equals, "i-c5cd56af")
+	c.Assert(r0i.PrivateDNSName, check.Equals, "domU-12-31-39-10-56-34.compute-1.internal")
+	c.Assert(r0i.DNSName, check.Equals, "ec2-174-129-165-232.compute-1.amazonaws.com")
+	c.Assert(r0i.AvailabilityZone, check.Equals, "us-east-1b")
+	c.Assert(r0i.IPAddress, check.Equals, "174.129.165.232")
+	c.Assert(r0i.PrivateIPAddress, check.Equals, "10.198.85.190")
+}
+
+func (s *S) TestDescribeInstancesExample2(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample2)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+	filter.Add("key2", "value2", "value3")
+
+	resp, err := s.ec2.DescribeInstances([]string{"i-1", "i-2"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-1"})
+	c.Assert(req.Form["InstanceId.2"], check.DeepEquals, []string{"i-2"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
 	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
 	c.Assert(req.Form["Filter.1.Value.2"], check.IsNil)
 	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
@@ -547,627 +1089,4099 @@ func (s *S) TestDescribeSubnetsExample(c *check.C) {
 	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"value3"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "a5266c3e-2b7a-4434-971e-317b6EXAMPLE")
-	c.Assert(resp.Subnets, check.HasLen, 3)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Reservations, check.HasLen, 1)
+
+	r0 := resp.Reservations[0]
+	r0i := r0.Instances[0]
+	c.Assert(r0i.State.Code, check.Equals, 16)
+	c.Assert(r0i.State.Name, check.Equals, "running")
+
+	r0t0 := r0i.Tags[0]
+	r0t1 := r0i.Tags[1]
+	c.Assert(r0t0.Key, check.Equals, "webserver")
+	c.Assert(r0t0.Value, check.Equals, "")
+	c.Assert(r0t1.Key, check.Equals, "stack")
+	c.Assert(r0t1.Value, check.Equals, "Production")
+}
+
+func (s *S) TestDescribeInstancesStream(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+
+	var streamed []ec2.Instance
+	err := s.ec2.DescribeInstancesStream([]string{"i-1", "i-2"}, filter, func(inst ec2.Instance) error {
+		streamed = append(streamed, inst)
+		return nil
+	})
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-1"})
+	c.Assert(req.Form["InstanceId.2"], check.DeepEquals, []string{"i-2"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
+
+	resp, err := s.ec2.DescribeInstances(nil, nil)
+	c.Assert(err, check.IsNil)
+	var all []ec2.Instance
+	for _, rsv := range resp.Reservations {
+		all = append(all, rsv.Instances...)
+	}
+	c.Assert(streamed, check.HasLen, len(all))
+	c.Assert(streamed, check.DeepEquals, all)
+}
+
+func (s *S) TestDescribeInstancesStreamStops(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	stop := errors.New("stop")
+	calls := 0
+	err := s.ec2.DescribeInstancesStream(nil, nil, func(inst ec2.Instance) error {
+		calls++
+		return stop
+	})
+	testServer.WaitRequest()
+	c.Assert(err, check.Equals, stop)
+	c.Assert(calls, check.Equals, 1)
+}
+
+func (s *S) TestDescribeInstancesPages(c *check.C) {
+	firstPage := strings.Replace(DescribeInstancesExample1, "</reservationSet>", "</reservationSet><nextToken>token-1</nextToken>", 1)
+	testServer.Response(200, nil, firstPage)
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	var pages []*ec2.DescribeInstancesResp
+	err := s.ec2.DescribeInstancesPages(&ec2.DescribeInstancesPagesOptions{MaxResults: 100}, func(page *ec2.DescribeInstancesResp) error {
+		pages = append(pages, page)
+		return nil
+	})
+	c.Assert(err, check.IsNil)
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
+	c.Assert(reqs[0].Form["MaxResults"], check.DeepEquals, []string{"100"})
+	c.Assert(reqs[0].Form["NextToken"], check.IsNil)
+	c.Assert(reqs[1].Form["NextToken"], check.DeepEquals, []string{"token-1"})
+	c.Assert(pages, check.HasLen, 2)
+	c.Assert(pages[0].NextToken, check.Equals, "token-1")
+	c.Assert(pages[0].Reservations, check.HasLen, 2)
+	c.Assert(pages[0].Reservations[0].Instances[0].RequesterId, check.Equals, "854251627541")
+	c.Assert(pages[1].NextToken, check.Equals, "")
+}
+
+func (s *S) TestDescribeInstancesPagesLimit(c *check.C) {
+	page := strings.Replace(DescribeInstancesExample1, "</reservationSet>", "</reservationSet><nextToken>token-1</nextToken>", 1)
+	testServer.Responses(2, 200, nil, page)
+
+	var ids []string
+	err := s.ec2.DescribeInstancesPages(&ec2.DescribeInstancesPagesOptions{Limit: 3}, func(page *ec2.DescribeInstancesResp) error {
+		for _, rsv := range page.Reservations {
+			for _, inst := range rsv.Instances {
+				ids = append(ids, inst.InstanceId)
+			}
+		}
+		return nil
+	})
+	c.Assert(err, check.IsNil)
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["MaxResults"], check.DeepEquals, []string{"5"})
+	c.Assert(ids, check.DeepEquals, []string{"i-c5cd56af", "i-d9cd56b3", "i-c5cd56af"})
+}
+
+func (s *S) TestDescribeInstancesPagesStops(c *check.C) {
+	page := strings.Replace(DescribeInstancesExample1, "</reservationSet>", "</reservationSet><nextToken>token-1</nextToken>", 1)
+	testServer.Response(200, nil, page)
+
+	stop := errors.New("stop")
+	err := s.ec2.DescribeInstancesPages(nil, func(page *ec2.DescribeInstancesResp) error {
+		return stop
+	})
+	testServer.WaitRequest()
+	c.Assert(err, check.Equals, stop)
+}
+
+func (s *S) TestDescribeAddressesPublicIPExample(c *check.C) {
+	testServer.Response(200, nil, DescribeAddressesExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+	filter.Add("key2", "value2", "value3")
+
+	resp, err := s.ec2.DescribeAddresses([]string{"192.0.2.1", "198.51.100.2", "203.0.113.41"}, []string{}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeAddresses"})
+	c.Assert(req.Form["PublicIp.1"], check.DeepEquals, []string{"192.0.2.1"})
+	c.Assert(req.Form["PublicIp.2"], check.DeepEquals, []string{"198.51.100.2"})
+	c.Assert(req.Form["PublicIp.3"], check.DeepEquals, []string{"203.0.113.41"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
+	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"value3"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Addresses, check.HasLen, 3)
+
+	r0 := resp.Addresses[0]
+	c.Assert(r0.PublicIp, check.Equals, "192.0.2.1")
+	c.Assert(r0.Domain, check.Equals, "standard")
+	c.Assert(r0.InstanceId, check.Equals, "i-f15ebb98")
+
+	r0i := resp.Addresses[1]
+	c.Assert(r0i.PublicIp, check.Equals, "198.51.100.2")
+	c.Assert(r0i.Domain, check.Equals, "standard")
+	c.Assert(r0i.InstanceId, check.Equals, "")
+
+	r0ii := resp.Addresses[2]
+	c.Assert(r0ii.PublicIp, check.Equals, "203.0.113.41")
+	c.Assert(r0ii.Domain, check.Equals, "vpc")
+	c.Assert(r0ii.InstanceId, check.Equals, "i-64600030")
+	c.Assert(r0ii.AssociationId, check.Equals, "eipassoc-f0229899")
+	c.Assert(r0ii.AllocationId, check.Equals, "eipalloc-08229861")
+	c.Assert(r0ii.NetworkInterfaceOwnerId, check.Equals, "053230519467")
+	c.Assert(r0ii.NetworkInterfaceId, check.Equals, "eni-ef229886")
+	c.Assert(r0ii.PrivateIpAddress, check.Equals, "10.0.0.228")
+}
+
+func (s *S) TestDescribeAddressesParamNames(c *check.C) {
+	testServer.Response(200, nil, DescribeAddressesAllocationIdExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("instance-id", "i-64600030")
+	_, err := s.ec2.DescribeAddresses([]string{"203.0.113.41"}, []string{"eipalloc-08229861"}, filter)
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["PublicIp.1"], check.DeepEquals, []string{"203.0.113.41"})
+	c.Assert(req.Form["AllocationId.1"], check.DeepEquals, []string{"eipalloc-08229861"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"instance-id"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"i-64600030"})
+	c.Assert(req.Form["InstanceId.1"], check.IsNil)
+}
+
+func (s *S) TestAddressesForInterface(c *check.C) {
+	testServer.Response(200, nil, DescribeAddressesInterfaceExample)
+
+	resp, err := s.ec2.AddressesForInterface("eni-ef229886")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeAddresses"})
+	c.Assert(req.Form["PublicIp.1"], check.IsNil)
+	c.Assert(req.Form["AllocationId.1"], check.IsNil)
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"network-interface-id"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"eni-ef229886"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Addresses, check.HasLen, 1)
+	c.Assert(resp.Addresses[0].PublicIp, check.Equals, "203.0.113.41")
+	c.Assert(resp.Addresses[0].NetworkInterfaceId, check.Equals, "eni-ef229886")
+	c.Assert(resp.Addresses[0].PrivateIpAddress, check.Equals, "10.0.0.228")
+}
+
+func (s *S) TestDescribeAddressesAllocationIDExample(c *check.C) {
+	testServer.Response(200, nil, DescribeAddressesAllocationIdExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+	filter.Add("key2", "value2", "value3")
+
+	resp, err := s.ec2.DescribeAddresses([]string{}, []string{"eipalloc-08229861", "eipalloc-08364752"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeAddresses"})
+	c.Assert(req.Form["AllocationId.1"], check.DeepEquals, []string{"eipalloc-08229861"})
+	c.Assert(req.Form["AllocationId.2"], check.DeepEquals, []string{"eipalloc-08364752"})
+	c.Assert(req.Form["PublicIp.1"], check.IsNil)
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Addresses, check.HasLen, 2)
+
+	r0 := resp.Addresses[0]
+	c.Assert(r0.PublicIp, check.Equals, "203.0.113.41")
+	c.Assert(r0.AllocationId, check.Equals, "eipalloc-08229861")
+	c.Assert(r0.Domain, check.Equals, "vpc")
+	c.Assert(r0.InstanceId, check.Equals, "i-64600030")
+	c.Assert(r0.AssociationId, check.Equals, "eipassoc-f0229899")
+	c.Assert(r0.NetworkInterfaceId, check.Equals, "eni-ef229886")
+	c.Assert(r0.NetworkInterfaceOwnerId, check.Equals, "053230519467")
+	c.Assert(r0.PrivateIpAddress, check.Equals, "10.0.0.228")
+
+	r1 := resp.Addresses[1]
+	c.Assert(r1.PublicIp, check.Equals, "146.54.2.230")
+	c.Assert(r1.AllocationId, check.Equals, "eipalloc-08364752")
+	c.Assert(r1.Domain, check.Equals, "vpc")
+	c.Assert(r1.InstanceId, check.Equals, "i-64693456")
+	c.Assert(r1.AssociationId, check.Equals, "eipassoc-f0348693")
+	c.Assert(r1.NetworkInterfaceId, check.Equals, "eni-da764039")
+	c.Assert(r1.NetworkInterfaceOwnerId, check.Equals, "053230519467")
+	c.Assert(r1.PrivateIpAddress, check.Equals, "10.0.0.102")
+}
+
+func (s *S) TestAllocateAddressExample(c *check.C) {
+	testServer.Response(200, nil, AllocateAddressExample)
+
+	resp, err := s.ec2.AllocateAddress("vpc")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AllocateAddress"})
+	c.Assert(req.Form["Domain"], check.DeepEquals, []string{"vpc"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.PublicIp, check.Equals, "198.51.100.1")
+	c.Assert(resp.Domain, check.Equals, "vpc")
+	c.Assert(resp.AllocationId, check.Equals, "eipalloc-5723d13e")
+}
+
+func (s *S) TestReleaseAddressExample(c *check.C) {
+	testServer.Response(200, nil, ReleaseAddressExample)
+
+	resp, err := s.ec2.ReleaseAddress("192.0.2.1", "")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ReleaseAddress"})
+	c.Assert(req.Form["PublicIp"], check.DeepEquals, []string{"192.0.2.1"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Return, check.Equals, true)
+}
+
+func (s *S) TestAssociateAddressExample(c *check.C) {
+	testServer.Response(200, nil, AssociateAddressExample)
+
+	options := ec2.AssociateAddressOptions{
+		PublicIp:   "192.0.2.1",
+		InstanceId: "i-2ea64347",
+	}
+
+	resp, err := s.ec2.AssociateAddress(&options)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AssociateAddress"})
+	c.Assert(req.Form["PublicIp"], check.DeepEquals, []string{"192.0.2.1"})
+	c.Assert(req.Form["InstanceId"], check.DeepEquals, []string{"i-2ea64347"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Return, check.Equals, true)
+	c.Assert(resp.AssociationId, check.Equals, "eipassoc-fc5ca095")
+}
+
+func (s *S) TestAssociateAddressAlreadyAssociated(c *check.C) {
+	testServer.Response(400, nil, AlreadyAssociatedDump)
+	testServer.Response(200, nil, AssociateAddressExample)
+
+	options := ec2.AssociateAddressOptions{
+		AllocationId: "eipalloc-5723d13e",
+		InstanceId:   "i-2ea64347",
+	}
+	_, err := s.ec2.AssociateAddress(&options)
+	c.Assert(ec2.IsErrorCode(err, "Resource.AlreadyAssociated"), check.Equals, true)
+	c.Assert(ec2.IsErrorCode(err, "InvalidInstanceID.NotFound"), check.Equals, false)
+
+	options.AllowReassociation = true
+	_, err = s.ec2.AssociateAddress(&options)
+	c.Assert(err, check.IsNil)
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["AllowReassociation"], check.IsNil)
+	c.Assert(reqs[1].Form["AllowReassociation"], check.DeepEquals, []string{"true"})
+}
+
+func (s *S) TestIsErrorCode(c *check.C) {
+	c.Assert(ec2.IsErrorCode(&ec2.Error{Code: "RequestExpired"}, "RequestExpired"), check.Equals, true)
+	c.Assert(ec2.IsErrorCode(errors.New("RequestExpired"), "RequestExpired"), check.Equals, false)
+	c.Assert(ec2.IsErrorCode(nil, ""), check.Equals, false)
+	notFound := &ec2.InstancesNotFoundError{Err: &ec2.Error{Code: "InvalidInstanceID.NotFound"}}
+	c.Assert(ec2.IsErrorCode(notFound, "InvalidInstanceID.NotFound"), check.Equals, true)
+	c.Assert(ec2.IsErrorCode(notFound, "RequestExpired"), check.Equals, false)
+}
+
+func (s *S) TestDiassociateAddressExample(c *check.C) {
+	testServer.Response(200, nil, DiassociateAddressExample)
+
+	resp, err := s.ec2.DiassociateAddress("192.0.2.1", "")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DiassociateAddress"})
+	c.Assert(req.Form["PublicIp"], check.DeepEquals, []string{"192.0.2.1"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Return, check.Equals, true)
+}
+
+func (s *S) TestDescribeImagesExample(c *check.C) {
+	testServer.Response(200, nil, DescribeImagesExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+	filter.Add("key2", "value2", "value3")
+
+	resp, err := s.ec2.Images([]string{"ami-1", "ami-2"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeImages"})
+	c.Assert(req.Form["ImageId.1"], check.DeepEquals, []string{"ami-1"})
+	c.Assert(req.Form["ImageId.2"], check.DeepEquals, []string{"ami-2"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
+	c.Assert(req.Form["Filter.1.Value.2"], check.IsNil)
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
+	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"value2"})
+	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"value3"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "4a4a27a2-2e7c-475d-b35b-ca822EXAMPLE")
+	c.Assert(resp.Images, check.HasLen, 1)
+
+	i0 := resp.Images[0]
+	c.Assert(i0.Id, check.Equals, "ami-a2469acf")
+	c.Assert(i0.Type, check.Equals, "machine")
+	c.Assert(i0.Name, check.Equals, "example-marketplace-amzn-ami.1")
+	c.Assert(i0.Description, check.Equals, "Amazon Linux AMI i386 EBS")
+	c.Assert(i0.Location, check.Equals, "aws-marketplace/example-marketplace-amzn-ami.1")
+	c.Assert(i0.State, check.Equals, "available")
+	c.Assert(i0.Public, check.Equals, true)
+	c.Assert(i0.OwnerId, check.Equals, "123456789999")
+	c.Assert(i0.OwnerAlias, check.Equals, "aws-marketplace")
+	c.Assert(i0.Architecture, check.Equals, "i386")
+	c.Assert(i0.KernelId, check.Equals, "aki-805ea7e9")
+	c.Assert(i0.RootDeviceType, check.Equals, "ebs")
+	c.Assert(i0.RootDeviceName, check.Equals, "/dev/sda1")
+	c.Assert(i0.VirtualizationType, check.Equals, "paravirtual")
+	c.Assert(i0.Hypervisor, check.Equals, "xen")
+
+	c.Assert(i0.Tags, check.HasLen, 1)
+	c.Assert(i0.Tags[0].Key, check.Equals, "Purpose")
+	c.Assert(i0.Tags[0].Value, check.Equals, "EXAMPLE")
+
+	c.Assert(i0.BlockDevices, check.HasLen, 1)
+	c.Assert(i0.BlockDevices[0].DeviceName, check.Equals, "/dev/sda1")
+	c.Assert(i0.BlockDevices[0].SnapshotId, check.Equals, "snap-787e9403")
+	c.Assert(i0.BlockDevices[0].VolumeSize, check.Equals, int64(8))
+	c.Assert(i0.BlockDevices[0].DeleteOnTermination, check.Equals, true)
+}
+
+func (s *S) TestDescribeImagesManyIdsUsesPost(c *check.C) {
+	testServer.Response(200, nil, DescribeImagesExample)
+
+	ids := make([]string, 500)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("ami-%08d", i)
+	}
+	_, err := s.ec2.Images(ids, nil)
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "POST")
+	c.Assert(req.URL.RawQuery, check.Equals, "")
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeImages"})
+	c.Assert(req.Form["ImageId.1"], check.DeepEquals, []string{"ami-00000000"})
+	c.Assert(req.Form["ImageId.500"], check.DeepEquals, []string{"ami-00000499"})
+}
+
+func (s *S) TestDescribeImagesFewIdsUsesGet(c *check.C) {
+	testServer.Response(200, nil, DescribeImagesExample)
+
+	_, err := s.ec2.Images([]string{"ami-1", "ami-2"}, nil)
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "GET")
+	c.Assert(req.Form["ImageId.2"], check.DeepEquals, []string{"ami-2"})
+}
+
+func (s *S) TestImagesWithOptions(c *check.C) {
+	testServer.Response(200, nil, DescribeImagesExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("architecture", "x86_64")
+	resp, err := s.ec2.ImagesWithOptions(&ec2.ImagesOptions{
+		Owners:       []string{"self", "amazon"},
+		ExecutableBy: []string{"123456789012"},
+		Filter:       filter,
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeImages"})
+	c.Assert(req.Form["ImageId.1"], check.IsNil)
+	c.Assert(req.Form["Owner.1"], check.DeepEquals, []string{"self"})
+	c.Assert(req.Form["Owner.2"], check.DeepEquals, []string{"amazon"})
+	c.Assert(req.Form["ExecutableBy.1"], check.DeepEquals, []string{"123456789012"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"architecture"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Images, check.HasLen, 1)
+}
+
+func (s *S) TestImagesWithOptionsNil(c *check.C) {
+	testServer.Response(200, nil, DescribeImagesExample)
+
+	resp, err := s.ec2.ImagesWithOptions(nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeImages"})
+	c.Assert(req.Form["ImageId.1"], check.IsNil)
+	c.Assert(req.Form["Owner.1"], check.IsNil)
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Images, check.HasLen, 1)
+}
+
+func (s *S) TestLatestImage(c *check.C) {
+	testServer.Response(200, nil, DescribeImagesLatestExample)
+
+	image, err := s.ec2.LatestImage([]string{"self"}, "myapp-*")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeImages"})
+	c.Assert(req.Form["Owner.1"], check.DeepEquals, []string{"self"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"name"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"myapp-*"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(image.Id, check.Equals, "ami-33333333")
+	c.Assert(image.CreationDate, check.Equals, "2016-08-20T10:00:00.000Z")
+	c.Assert(image.EnaSupport, check.Equals, true)
+	c.Assert(image.SriovNetSupport, check.Equals, "simple")
+	c.Assert(image.DeprecationTime, check.Equals, "2018-08-20T10:00:00.000Z")
+	c.Assert(image.Tags, check.DeepEquals, []ec2.Tag{{"Release", "2016-08-20"}})
+}
+
+func (s *S) TestLatestImageNotFound(c *check.C) {
+	testServer.Response(200, nil, DescribeImagesEmptyExample)
+
+	image, err := s.ec2.LatestImage([]string{"self"}, "missing-*")
+
+	testServer.WaitRequest()
+	c.Assert(err, check.Equals, ec2.ErrImageNotFound)
+	c.Assert(image, check.IsNil)
+}
+
+func (s *S) TestRegisterImage(c *check.C) {
+	testServer.Response(200, nil, RegisterImageExample)
+
+	resp, err := s.ec2.RegisterImage(&ec2.RegisterImageOptions{
+		Name:               "my-image",
+		Description:        "restored",
+		Architecture:       "x86_64",
+		RootDeviceName:     "/dev/xvda",
+		VirtualizationType: "hvm",
+		SriovNetSupport:    "simple",
+		EnaSupport:         true,
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{
+			{DeviceName: "/dev/xvda", SnapshotId: "snap-1234567890abcdef0"},
+			{DeviceName: "/dev/sdb", VirtualName: "ephemeral0"},
+		},
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"RegisterImage"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["Name"], check.DeepEquals, []string{"my-image"})
+	c.Assert(req.Form["Description"], check.DeepEquals, []string{"restored"})
+	c.Assert(req.Form["Architecture"], check.DeepEquals, []string{"x86_64"})
+	c.Assert(req.Form["RootDeviceName"], check.DeepEquals, []string{"/dev/xvda"})
+	c.Assert(req.Form["VirtualizationType"], check.DeepEquals, []string{"hvm"})
+	c.Assert(req.Form["SriovNetSupport"], check.DeepEquals, []string{"simple"})
+	c.Assert(req.Form["EnaSupport"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["ImageLocation"], check.IsNil)
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.SnapshotId"], check.DeepEquals, []string{"snap-1234567890abcdef0"})
+	c.Assert(req.Form["BlockDeviceMapping.2.VirtualName"], check.DeepEquals, []string{"ephemeral0"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.ImageId, check.Equals, "ami-1a2b3c4d")
+}
+
+func (s *S) TestRegisterImageFromSnapshot(c *check.C) {
+	testServer.Response(200, nil, RegisterImageExample)
+
+	resp, err := s.ec2.RegisterImageFromSnapshot("backup", "snap-1234567890abcdef0", "x86_64", "/dev/xvda")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"RegisterImage"})
+	c.Assert(req.Form["Name"], check.DeepEquals, []string{"backup"})
+	c.Assert(req.Form["Architecture"], check.DeepEquals, []string{"x86_64"})
+	c.Assert(req.Form["RootDeviceName"], check.DeepEquals, []string{"/dev/xvda"})
+	c.Assert(req.Form["VirtualizationType"], check.DeepEquals, []string{"hvm"})
+	c.Assert(req.Form["BlockDeviceMapping.1.DeviceName"], check.DeepEquals, []string{"/dev/xvda"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.SnapshotId"], check.DeepEquals, []string{"snap-1234567890abcdef0"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.DeleteOnTermination"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["BlockDeviceMapping.2.DeviceName"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.ImageId, check.Equals, "ami-1a2b3c4d")
+}
+
+func (s *S) TestCreateSnapshotExample(c *check.C) {
+	testServer.Response(200, nil, CreateSnapshotExample)
+
+	resp, err := s.ec2.CreateSnapshot("vol-4d826724", "Daily Backup")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateSnapshot"})
+	c.Assert(req.Form["VolumeId"], check.DeepEquals, []string{"vol-4d826724"})
+	c.Assert(req.Form["Description"], check.DeepEquals, []string{"Daily Backup"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Snapshot.Id, check.Equals, "snap-78a54011")
+	c.Assert(resp.Snapshot.VolumeId, check.Equals, "vol-4d826724")
+	c.Assert(resp.Snapshot.Status, check.Equals, "pending")
+	c.Assert(resp.Snapshot.StartTime, check.Equals, "2008-05-07T12:51:50.000Z")
+	c.Assert(resp.Snapshot.Progress, check.Equals, "60%")
+	c.Assert(resp.Snapshot.OwnerId, check.Equals, "111122223333")
+	c.Assert(resp.Snapshot.VolumeSize, check.Equals, "10")
+	c.Assert(resp.Snapshot.Description, check.Equals, "Daily Backup")
+}
+
+func (s *S) TestDeleteSnapshotsExample(c *check.C) {
+	testServer.Response(200, nil, DeleteSnapshotExample)
+
+	resp, err := s.ec2.DeleteSnapshots("snap-78a54011")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteSnapshot"})
+	c.Assert(req.Form["SnapshotId.1"], check.DeepEquals, []string{"snap-78a54011"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestDescribeSnapshotsExample(c *check.C) {
+	testServer.Response(200, nil, DescribeSnapshotsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+	filter.Add("key2", "value2", "value3")
+
+	resp, err := s.ec2.Snapshots([]string{"snap-1", "snap-2"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSnapshots"})
+	c.Assert(req.Form["SnapshotId.1"], check.DeepEquals, []string{"snap-1"})
+	c.Assert(req.Form["SnapshotId.2"], check.DeepEquals, []string{"snap-2"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
+	c.Assert(req.Form["Filter.1.Value.2"], check.IsNil)
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
+	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"value2"})
+	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"value3"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Snapshots, check.HasLen, 1)
+
+	s0 := resp.Snapshots[0]
+	c.Assert(s0.Id, check.Equals, "snap-1a2b3c4d")
+	c.Assert(s0.VolumeId, check.Equals, "vol-8875daef")
+	c.Assert(s0.VolumeSize, check.Equals, "15")
+	size, err := s0.VolumeSizeGiB()
+	c.Assert(err, check.IsNil)
+	c.Assert(size, check.Equals, int64(15))
+	c.Assert(s0.Status, check.Equals, "pending")
+	c.Assert(s0.StartTime, check.Equals, "2010-07-29T04:12:01.000Z")
+	c.Assert(s0.Progress, check.Equals, "30%")
+	c.Assert(s0.OwnerId, check.Equals, "111122223333")
+	c.Assert(s0.Description, check.Equals, "Daily Backup")
+
+	c.Assert(s0.Tags, check.HasLen, 1)
+	c.Assert(s0.Tags[0].Key, check.Equals, "Purpose")
+	c.Assert(s0.Tags[0].Value, check.Equals, "demo_db_14_backup")
+}
+
+func (s *S) TestSnapshotsWithOptions(c *check.C) {
+	testServer.Response(200, nil, DescribeSnapshotsExample)
+
+	resp, err := s.ec2.SnapshotsWithOptions(&ec2.SnapshotsOptions{
+		Owners:       []string{"self"},
+		RestorableBy: []string{"123456789012", "all"},
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSnapshots"})
+	c.Assert(req.Form["SnapshotId.1"], check.IsNil)
+	c.Assert(req.Form["Owner.1"], check.DeepEquals, []string{"self"})
+	c.Assert(req.Form["RestorableBy.1"], check.DeepEquals, []string{"123456789012"})
+	c.Assert(req.Form["RestorableBy.2"], check.DeepEquals, []string{"all"})
+	c.Assert(req.Form["Filter.1.Name"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Snapshots, check.HasLen, 1)
+}
+
+func (s *S) TestSnapshotsWithOptionsNil(c *check.C) {
+	testServer.Response(200, nil, DescribeSnapshotsExample)
+
+	resp, err := s.ec2.SnapshotsWithOptions(nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSnapshots"})
+	c.Assert(req.Form["SnapshotId.1"], check.IsNil)
+	c.Assert(req.Form["Owner.1"], check.IsNil)
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Snapshots, check.HasLen, 1)
+}
+
+func (s *S) TestDescribeSubnetsExample(c *check.C) {
+	testServer.Response(200, nil, DescribeSubnetsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+	filter.Add("key2", "value2", "value3")
+
+	resp, err := s.ec2.Subnets([]string{"subnet-1", "subnet-2"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSubnets"})
+	c.Assert(req.Form["SubnetId.1"], check.DeepEquals, []string{"subnet-1"})
+	c.Assert(req.Form["SubnetId.2"], check.DeepEquals, []string{"subnet-2"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
+	c.Assert(req.Form["Filter.1.Value.2"], check.IsNil)
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"key2"})
+	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"value2"})
+	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"value3"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "a5266c3e-2b7a-4434-971e-317b6EXAMPLE")
+	c.Assert(resp.Subnets, check.HasLen, 3)
+
+	s0 := resp.Subnets[0]
+	c.Assert(s0.Id, check.Equals, "subnet-3e993755")
+	c.Assert(s0.State, check.Equals, "available")
+	c.Assert(s0.VpcId, check.Equals, "vpc-f84a9b93")
+	c.Assert(s0.CidrBlock, check.Equals, "10.0.12.0/24")
+	c.Assert(s0.AvailableIpAddressCount, check.Equals, 249)
+	c.Assert(s0.AvailabilityZone, check.Equals, "us-west-2c")
+	c.Assert(s0.DefaultForAz, check.Equals, false)
+	c.Assert(s0.MapPublicIpOnLaunch, check.Equals, false)
+
+	c.Assert(s0.Tags, check.HasLen, 2)
+	c.Assert(s0.Tags[0].Key, check.Equals, "visibility")
+	c.Assert(s0.Tags[0].Value, check.Equals, "private")
+	c.Assert(s0.Tags[1].Key, check.Equals, "Name")
+	c.Assert(s0.Tags[1].Value, check.Equals, "application")
+}
+
+func (s *S) TestCreateSecurityGroupExample(c *check.C) {
+	testServer.Response(200, nil, CreateSecurityGroupExample)
+
+	resp, err := s.ec2.CreateSecurityGroup("websrv", "Web Servers")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateSecurityGroup"})
+	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
+	c.Assert(req.Form["GroupDescription"], check.DeepEquals, []string{"Web Servers"})
+	c.Assert(req.Form["VpcId"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Name, check.Equals, "websrv")
+	c.Assert(resp.Id, check.Equals, "sg-67ad940e")
+}
+
+func (s *S) TestCreateSecurityGroupVPC(c *check.C) {
+	testServer.Response(200, nil, CreateSecurityGroupVPCExample)
+
+	resp, err := s.ec2.CreateSecurityGroupVPC("websrv", "Web Servers", "vpc-3325caf2")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateSecurityGroup"})
+	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
+	c.Assert(req.Form["GroupDescription"], check.DeepEquals, []string{"Web Servers"})
+	c.Assert(req.Form["VpcId"], check.DeepEquals, []string{"vpc-3325caf2"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "0f4e4d1e-8cbd-4d0f-8b0e-5c1dEXAMPLE")
+	c.Assert(resp.SecurityGroup, check.DeepEquals, ec2.SecurityGroup{Id: "sg-0a1b2c3d4e5f67890", Name: "websrv"})
+}
+
+func (s *S) TestDescribeSecurityGroupsExample(c *check.C) {
+	testServer.Response(200, nil, DescribeSecurityGroupsExample)
+
+	resp, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{{Name: "WebServers"}, {Name: "RangedPortsBySource"}}, nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSecurityGroups"})
+	c.Assert(req.Form["GroupName.1"], check.DeepEquals, []string{"WebServers"})
+	c.Assert(req.Form["GroupName.2"], check.DeepEquals, []string{"RangedPortsBySource"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Groups, check.HasLen, 2)
+
+	g0 := resp.Groups[0]
+	c.Assert(g0.OwnerId, check.Equals, "999988887777")
+	c.Assert(g0.Name, check.Equals, "WebServers")
+	c.Assert(g0.Id, check.Equals, "sg-67ad940e")
+	c.Assert(g0.Description, check.Equals, "Web Servers")
+	c.Assert(g0.IPPerms, check.HasLen, 1)
+
+	g0ipp := g0.IPPerms[0]
+	c.Assert(g0ipp.Protocol, check.Equals, "tcp")
+	c.Assert(g0ipp.FromPort, check.Equals, 80)
+	c.Assert(g0ipp.ToPort, check.Equals, 80)
+	c.Assert(g0ipp.SourceIPs, check.DeepEquals, []string{"0.0.0.0/0"})
+
+	g1 := resp.Groups[1]
+	c.Assert(g1.OwnerId, check.Equals, "999988887777")
+	c.Assert(g1.Name, check.Equals, "RangedPortsBySource")
+	c.Assert(g1.Id, check.Equals, "sg-76abc467")
+	c.Assert(g1.Description, check.Equals, "Group A")
+	c.Assert(g1.IPPerms, check.HasLen, 1)
+
+	g1ipp := g1.IPPerms[0]
+	c.Assert(g1ipp.Protocol, check.Equals, "tcp")
+	c.Assert(g1ipp.FromPort, check.Equals, 6000)
+	c.Assert(g1ipp.ToPort, check.Equals, 7000)
+	c.Assert(g1ipp.SourceIPs, check.IsNil)
+}
+
+func (s *S) TestDescribeSecurityGroups(c *check.C) {
+	testServer.Response(200, nil, SecurityGroupsVPCExample)
+
+	expected := ec2.SecurityGroupsResp{
+		RequestId: "59dbff89-35bd-4eac-99ed-be587EXAMPLE",
+		Groups: []ec2.SecurityGroupInfo{
+			ec2.SecurityGroupInfo{
+				SecurityGroup: ec2.SecurityGroup{
+					Id:   "sg-67ad940e",
+					Name: "WebServers",
+				},
+				OwnerId:     "999988887777",
+				Description: "Web Servers",
+				IPPerms: []ec2.IPPerm{
+					ec2.IPPerm{
+						Protocol:     "tcp",
+						FromPort:     80,
+						ToPort:       80,
+						SourceIPs:    []string{"0.0.0.0/0"},
+						IPRanges:     []ec2.IPRange{{CIDR: "0.0.0.0/0"}},
+						SourceGroups: nil,
+					},
+				},
+				IPPermsEgress: []ec2.IPPerm{
+					ec2.IPPerm{
+						Protocol:     "tcp",
+						FromPort:     22,
+						ToPort:       22,
+						SourceIPs:    []string{"10.0.0.0/8"},
+						IPRanges:     []ec2.IPRange{{CIDR: "10.0.0.0/8"}},
+						SourceGroups: nil,
+					},
+				},
+			},
+			ec2.SecurityGroupInfo{
+				SecurityGroup: ec2.SecurityGroup{
+					Id:   "sg-76abc467",
+					Name: "RangedPortsBySource",
+				},
+				OwnerId:     "999988887777",
+				Description: "Group A",
+				IPPerms: []ec2.IPPerm{
+					ec2.IPPerm{
+						Protocol: "tcp",
+						FromPort: 6000,
+						ToPort:   7000,
+					},
+				},
+				VpcId: "vpc-12345678",
+				Tags: []ec2.Tag{
+					ec2.Tag{
+						Key:   "key",
+						Value: "value",
+					},
+				},
+			},
+		},
+	}
+
+	resp, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{{Name: "WebServers"}, {Name: "RangedPortsBySource"}}, nil)
+	values := testServer.WaitRequest().URL.Query()
+	c.Assert(values.Get("Action"), check.Equals, "DescribeSecurityGroups")
+	c.Assert(values.Get("GroupName.1"), check.Equals, "WebServers")
+	c.Assert(values.Get("GroupName.2"), check.Equals, "RangedPortsBySource")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(*resp, check.DeepEquals, expected)
+}
+
+func (s *S) TestDescribeSecurityGroupsExampleWithFilter(c *check.C) {
+	testServer.Response(200, nil, DescribeSecurityGroupsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("ip-permission.protocol", "tcp")
+	filter.Add("ip-permission.from-port", "22")
+	filter.Add("ip-permission.to-port", "22")
+	filter.Add("ip-permission.group-name", "app_server_group", "database_group")
+
+	_, err := s.ec2.SecurityGroups(nil, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSecurityGroups"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"ip-permission.from-port"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"22"})
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"ip-permission.group-name"})
+	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"app_server_group"})
+	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"database_group"})
+	c.Assert(req.Form["Filter.3.Name"], check.DeepEquals, []string{"ip-permission.protocol"})
+	c.Assert(req.Form["Filter.3.Value.1"], check.DeepEquals, []string{"tcp"})
+	c.Assert(req.Form["Filter.4.Name"], check.DeepEquals, []string{"ip-permission.to-port"})
+	c.Assert(req.Form["Filter.4.Value.1"], check.DeepEquals, []string{"22"})
+
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestDescribeSecurityGroupsDumpWithGroup(c *check.C) {
+	testServer.Response(200, nil, DescribeSecurityGroupsDump)
+
+	resp, err := s.ec2.SecurityGroups(nil, nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSecurityGroups"})
+	c.Assert(err, check.IsNil)
+	c.Check(resp.Groups, check.HasLen, 1)
+	c.Check(resp.Groups[0].IPPerms, check.HasLen, 2)
+
+	ipp0 := resp.Groups[0].IPPerms[0]
+	c.Assert(ipp0.SourceIPs, check.IsNil)
+	c.Check(ipp0.Protocol, check.Equals, "icmp")
+	c.Assert(ipp0.SourceGroups, check.HasLen, 1)
+	c.Check(ipp0.SourceGroups[0].OwnerId, check.Equals, "12345")
+	c.Check(ipp0.SourceGroups[0].Name, check.Equals, "default")
+	c.Check(ipp0.SourceGroups[0].Id, check.Equals, "sg-67ad940e")
+
+	ipp1 := resp.Groups[0].IPPerms[1]
+	c.Check(ipp1.Protocol, check.Equals, "tcp")
+	c.Assert(ipp0.SourceIPs, check.IsNil)
+	c.Assert(ipp0.SourceGroups, check.HasLen, 1)
+	c.Check(ipp1.SourceGroups[0].Id, check.Equals, "sg-76abc467")
+	c.Check(ipp1.SourceGroups[0].OwnerId, check.Equals, "12345")
+	c.Check(ipp1.SourceGroups[0].Name, check.Equals, "other")
+}
+
+func (s *S) TestDeleteSecurityGroupExample(c *check.C) {
+	testServer.Response(200, nil, DeleteSecurityGroupExample)
+
+	resp, err := s.ec2.DeleteSecurityGroup(ec2.SecurityGroup{Name: "websrv"})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteSecurityGroup"})
+	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
+	c.Assert(req.Form["GroupId"], check.IsNil)
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestDeleteSecurityGroupExampleWithId(c *check.C) {
+	testServer.Response(200, nil, DeleteSecurityGroupExample)
+
+	// ignore return and error - we're only want to check the parameter handling.
+	s.ec2.DeleteSecurityGroup(ec2.SecurityGroup{Id: "sg-67ad940e", Name: "ignored"})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["GroupName"], check.IsNil)
+	c.Assert(req.Form["GroupId"], check.DeepEquals, []string{"sg-67ad940e"})
+}
+
+func (s *S) TestAuthorizeSecurityGroupExample1(c *check.C) {
+	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
+
+	perms := []ec2.IPPerm{{
+		Protocol:  "tcp",
+		FromPort:  80,
+		ToPort:    80,
+		SourceIPs: []string{"205.192.0.0/16", "205.159.0.0/16"},
+	}}
+	resp, err := s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Name: "websrv"}, perms)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AuthorizeSecurityGroupIngress"})
+	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
+	c.Assert(req.Form["IpPermissions.1.IpProtocol"], check.DeepEquals, []string{"tcp"})
+	c.Assert(req.Form["IpPermissions.1.FromPort"], check.DeepEquals, []string{"80"})
+	c.Assert(req.Form["IpPermissions.1.ToPort"], check.DeepEquals, []string{"80"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.1.CidrIp"], check.DeepEquals, []string{"205.192.0.0/16"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.2.CidrIp"], check.DeepEquals, []string{"205.159.0.0/16"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestAuthorizeSecurityGroupNormalizesProtocol(c *check.C) {
+	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
+
+	perms := []ec2.IPPerm{
+		{Protocol: "TCP", FromPort: 22, ToPort: 22, SourceIPs: []string{"10.0.0.0/8"}},
+		{Protocol: "50", SourceIPs: []string{"10.0.0.0/8"}},
+		{Protocol: ec2.ProtocolAll, SourceIPs: []string{"10.0.0.0/8"}},
+	}
+	_, err := s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Id: "sg-67ad940e"}, perms)
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["IpPermissions.1.IpProtocol"], check.DeepEquals, []string{"tcp"})
+	c.Assert(req.Form["IpPermissions.2.IpProtocol"], check.DeepEquals, []string{"50"})
+	c.Assert(req.Form["IpPermissions.3.IpProtocol"], check.DeepEquals, []string{"-1"})
+}
+
+func (s *S) TestAuthorizeSecurityGroupInvalidProtocol(c *check.C) {
+	for _, protocol := range []string{"", "sctp", "256", "-2"} {
+		perms := []ec2.IPPerm{{Protocol: protocol, FromPort: 80, ToPort: 80}}
+		_, err := s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Id: "sg-67ad940e"}, perms)
+		c.Assert(err, check.ErrorMatches, "invalid IP protocol .*")
+	}
+}
+
+func (s *S) TestAuthorizeSecurityGroupExample1WithId(c *check.C) {
+	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
+
+	perms := []ec2.IPPerm{{
+		Protocol:  "tcp",
+		FromPort:  80,
+		ToPort:    80,
+		SourceIPs: []string{"205.192.0.0/16", "205.159.0.0/16"},
+	}}
+	// ignore return and error - we're only want to check the parameter handling.
+	s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Id: "sg-67ad940e", Name: "ignored"}, perms)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["GroupName"], check.IsNil)
+	c.Assert(req.Form["GroupId"], check.DeepEquals, []string{"sg-67ad940e"})
+}
+
+func (s *S) TestAuthorizeSecurityGroupExample2(c *check.C) {
+	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
+
+	perms := []ec2.IPPerm{{
+		Protocol: "tcp",
+		FromPort: 80,
+		ToPort:   81,
+		SourceGroups: []ec2.UserSecurityGroup{
+			{OwnerId: "999988887777", Name: "OtherAccountGroup"},
+			{Id: "sg-67ad940e"},
+		},
+	}}
+	resp, err := s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Name: "websrv"}, perms)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AuthorizeSecurityGroupIngress"})
+	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
+	c.Assert(req.Form["IpPermissions.1.IpProtocol"], check.DeepEquals, []string{"tcp"})
+	c.Assert(req.Form["IpPermissions.1.FromPort"], check.DeepEquals, []string{"80"})
+	c.Assert(req.Form["IpPermissions.1.ToPort"], check.DeepEquals, []string{"81"})
+	c.Assert(req.Form["IpPermissions.1.Groups.1.UserId"], check.DeepEquals, []string{"999988887777"})
+	c.Assert(req.Form["IpPermissions.1.Groups.1.GroupName"], check.DeepEquals, []string{"OtherAccountGroup"})
+	c.Assert(req.Form["IpPermissions.1.Groups.2.UserId"], check.IsNil)
+	c.Assert(req.Form["IpPermissions.1.Groups.2.GroupName"], check.IsNil)
+	c.Assert(req.Form["IpPermissions.1.Groups.2.GroupId"], check.DeepEquals, []string{"sg-67ad940e"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestAuthorizeSecurityGroupAllIcmp(c *check.C) {
+	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
+
+	perm := ec2.IcmpPerm(-1, 8, "0.0.0.0/0")
+	c.Assert(perm, check.DeepEquals, ec2.IPPerm{Protocol: "icmp", FromPort: -1, ToPort: -1, SourceIPs: []string{"0.0.0.0/0"}})
+
+	_, err := s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Id: "sg-67ad940e"}, []ec2.IPPerm{perm})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["IpPermissions.1.IpProtocol"], check.DeepEquals, []string{"icmp"})
+	c.Assert(req.Form["IpPermissions.1.FromPort"], check.DeepEquals, []string{"-1"})
+	c.Assert(req.Form["IpPermissions.1.ToPort"], check.DeepEquals, []string{"-1"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.1.CidrIp"], check.DeepEquals, []string{"0.0.0.0/0"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestIcmpPerm(c *check.C) {
+	echo := ec2.IcmpPerm(8, 0, "10.0.0.0/8")
+	c.Assert(echo.FromPort, check.Equals, 8)
+	c.Assert(echo.ToPort, check.Equals, 0)
+
+	unreachable := ec2.IcmpPerm(3, -5)
+	c.Assert(unreachable.FromPort, check.Equals, 3)
+	c.Assert(unreachable.ToPort, check.Equals, -1)
+	c.Assert(unreachable.SourceIPs, check.HasLen, 0)
+}
+
+func (s *S) TestAuthorizeSecurityGroupWithIPRanges(c *check.C) {
+	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
+
+	perms := []ec2.IPPerm{{
+		Protocol:  "tcp",
+		FromPort:  22,
+		ToPort:    22,
+		SourceIPs: []string{"205.192.0.0/16"},
+		IPRanges: []ec2.IPRange{
+			{CIDR: "10.0.0.0/8", Description: "office"},
+			{CIDR: "192.168.0.0/16"},
+		},
+	}}
+	_, err := s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Name: "websrv"}, perms)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["IpPermissions.1.IpRanges.1.CidrIp"], check.DeepEquals, []string{"205.192.0.0/16"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.1.Description"], check.IsNil)
+	c.Assert(req.Form["IpPermissions.1.IpRanges.2.CidrIp"], check.DeepEquals, []string{"10.0.0.0/8"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.2.Description"], check.DeepEquals, []string{"office"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.3.CidrIp"], check.DeepEquals, []string{"192.168.0.0/16"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.3.Description"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestDescribeSecurityGroupsWithRangeDescriptions(c *check.C) {
+	testServer.Response(200, nil, DescribeSecurityGroupsWithDescriptionsExample)
+
+	resp, err := s.ec2.SecurityGroups(nil, nil)
+	testServer.WaitRequest()
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Groups, check.HasLen, 1)
+	c.Assert(resp.Groups[0].IPPerms, check.HasLen, 1)
+
+	perm := resp.Groups[0].IPPerms[0]
+	c.Assert(perm.SourceIPs, check.DeepEquals, []string{"10.0.0.0/8", "0.0.0.0/0"})
+	c.Assert(perm.IPRanges, check.DeepEquals, []ec2.IPRange{
+		{CIDR: "10.0.0.0/8", Description: "office"},
+		{CIDR: "0.0.0.0/0"},
+	})
+}
+
+func (s *S) TestRevokeSecurityGroupExample(c *check.C) {
+	// RevokeSecurityGroup is implemented by the same code as AuthorizeSecurityGroup
+	// so there's no need to duplicate all the tests.
+	testServer.Response(200, nil, RevokeSecurityGroupIngressExample)
+
+	resp, err := s.ec2.RevokeSecurityGroup(ec2.SecurityGroup{Name: "websrv"}, nil)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"RevokeSecurityGroupIngress"})
+	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestTagValue(c *check.C) {
+	tags := []ec2.Tag{{"Name", "web"}, {"Env", ""}}
+
+	value, ok := ec2.TagValue(tags, "Name")
+	c.Assert(value, check.Equals, "web")
+	c.Assert(ok, check.Equals, true)
+
+	value, ok = ec2.TagValue(tags, "Env")
+	c.Assert(value, check.Equals, "")
+	c.Assert(ok, check.Equals, true)
+
+	value, ok = ec2.TagValue(tags, "Owner")
+	c.Assert(value, check.Equals, "")
+	c.Assert(ok, check.Equals, false)
+
+	_, ok = ec2.TagValue(nil, "Name")
+	c.Assert(ok, check.Equals, false)
+}
+
+func (s *S) TestCreateTags(c *check.C) {
+	testServer.Response(200, nil, CreateTagsExample)
+
+	resp, err := s.ec2.CreateTags([]string{"ami-1a2b3c4d", "i-7f4d3a2b"}, []ec2.Tag{{"webserver", ""}, {"stack", "Production"}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["ResourceId.1"], check.DeepEquals, []string{"ami-1a2b3c4d"})
+	c.Assert(req.Form["ResourceId.2"], check.DeepEquals, []string{"i-7f4d3a2b"})
+	c.Assert(req.Form["Tag.1.Key"], check.DeepEquals, []string{"webserver"})
+	c.Assert(req.Form["Tag.1.Value"], check.DeepEquals, []string{""})
+	c.Assert(req.Form["Tag.2.Key"], check.DeepEquals, []string{"stack"})
+	c.Assert(req.Form["Tag.2.Value"], check.DeepEquals, []string{"Production"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestCreateTagsManyResources(c *check.C) {
+	testServer.Response(200, nil, CreateTagsExample)
+
+	ids := make([]string, 500)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("i-%017d", i)
+	}
+	_, err := s.ec2.CreateTags(ids, []ec2.Tag{{"stack", "Production"}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "POST")
+	c.Assert(req.URL.RawQuery, check.Equals, "")
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateTags"})
+	c.Assert(req.Form["Signature"], check.HasLen, 1)
+	c.Assert(req.Form["ResourceId.1"], check.DeepEquals, []string{ids[0]})
+	c.Assert(req.Form["ResourceId.500"], check.DeepEquals, []string{ids[499]})
+	c.Assert(req.Form["Tag.1.Key"], check.DeepEquals, []string{"stack"})
+	c.Assert(req.Form["Tag.1.Value"], check.DeepEquals, []string{"Production"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestCreateTagsBatches(c *check.C) {
+	testServer.Response(200, nil, CreateTagsExample)
+	testServer.Response(200, nil, CreateTagsExample)
+
+	ids := make([]string, 1500)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("i-%017d", i)
+	}
+	resp, err := s.ec2.CreateTags(ids, []ec2.Tag{{"stack", "Production"}})
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["ResourceId.1"], check.DeepEquals, []string{ids[0]})
+	c.Assert(reqs[0].Form["ResourceId.1000"], check.DeepEquals, []string{ids[999]})
+	c.Assert(reqs[0].Form["ResourceId.1001"], check.IsNil)
+	c.Assert(reqs[1].Form["ResourceId.1"], check.DeepEquals, []string{ids[1000]})
+	c.Assert(reqs[1].Form["ResourceId.500"], check.DeepEquals, []string{ids[1499]})
+	c.Assert(reqs[1].Form["ResourceId.501"], check.IsNil)
+	c.Assert(reqs[1].Form["Tag.1.Key"], check.DeepEquals, []string{"stack"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestCreateTagsPostV4(c *check.C) {
+	testServer.Response(200, nil, CreateTagsExample)
+
+	region := aws.Region{Name: "eu-central-1", EC2Endpoint: aws.ServiceInfo{Endpoint: testServer.URL, Signer: aws.V4Signature}}
+	_, err := ec2.New(s.ec2.Auth, region).CreateTags([]string{"i-7f4d3a2b"}, []ec2.Tag{{"stack", "Production"}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "POST")
+	c.Assert(req.URL.RawQuery, check.Equals, "")
+	c.Assert(req.Form["ResourceId.1"], check.DeepEquals, []string{"i-7f4d3a2b"})
+	c.Assert(req.Header.Get("Authorization"), check.Matches, ".*SignedHeaders=content-type;host;x-amz-date,.*")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestDeleteTags(c *check.C) {
+	testServer.Response(200, nil, DeleteTagsExample)
+
+	resp, err := s.ec2.DeleteTags([]string{"ami-1a2b3c4d", "i-7f4d3a2b"}, []ec2.Tag{{"webserver", ""}, {"stack", ""}})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["ResourceId.1"], check.DeepEquals, []string{"ami-1a2b3c4d"})
+	c.Assert(req.Form["ResourceId.2"], check.DeepEquals, []string{"i-7f4d3a2b"})
+	c.Assert(req.Form["Tag.1.Key"], check.DeepEquals, []string{"webserver"})
+	c.Assert(req.Form["Tag.2.Key"], check.DeepEquals, []string{"stack"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+}
+
+func (s *S) TestDescribeTags(c *check.C) {
+	testServer.Response(200, nil, DescribeTagsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key1", "value1")
+
+	resp, err := s.ec2.DescribeTags(filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeTags"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+	c.Assert(resp.Tags, check.HasLen, 6)
+
+	r0 := resp.Tags[0]
+	c.Assert(r0.Key, check.Equals, "webserver")
+	c.Assert(r0.Value, check.Equals, "")
+	c.Assert(r0.ResourceId, check.Equals, "ami-1a2b3c4d")
+	c.Assert(r0.ResourceType, check.Equals, "image")
+
+	r1 := resp.Tags[1]
+	c.Assert(r1.Key, check.Equals, "stack")
+	c.Assert(r1.Value, check.Equals, "Production")
+	c.Assert(r1.ResourceId, check.Equals, "ami-1a2b3c4d")
+	c.Assert(r1.ResourceType, check.Equals, "image")
+}
+
+func (s *S) TestStartInstances(c *check.C) {
+	testServer.Response(200, nil, StartInstancesExample)
+
+	resp, err := s.ec2.StartInstances("i-10a64379")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"StartInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-10a64379"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+
+	s0 := resp.StateChanges[0]
+	c.Assert(s0.InstanceId, check.Equals, "i-10a64379")
+	c.Assert(s0.CurrentState.Code, check.Equals, 0)
+	c.Assert(s0.CurrentState.Name, check.Equals, "pending")
+	c.Assert(s0.PreviousState.Code, check.Equals, 80)
+	c.Assert(s0.PreviousState.Name, check.Equals, "stopped")
+}
+
+func (s *S) TestStopInstances(c *check.C) {
+	testServer.Response(200, nil, StopInstancesExample)
+
+	resp, err := s.ec2.StopInstances("i-10a64379")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"StopInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-10a64379"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+
+	s0 := resp.StateChanges[0]
+	c.Assert(s0.InstanceId, check.Equals, "i-10a64379")
+	c.Assert(s0.CurrentState.Code, check.Equals, 64)
+	c.Assert(s0.CurrentState.Name, check.Equals, "stopping")
+	c.Assert(s0.PreviousState.Code, check.Equals, 16)
+	c.Assert(s0.PreviousState.Name, check.Equals, "running")
+	c.Assert(s0.StateReason, check.Equals, ec2.InstanceStateReason{})
+}
+
+func (s *S) TestStopInstancesStateReason(c *check.C) {
+	testServer.Response(200, nil, StopInstancesStateReasonExample)
+
+	resp, err := s.ec2.StopInstances("i-10a64379")
+	testServer.WaitRequest()
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.StateChanges, check.HasLen, 1)
+
+	s0 := resp.StateChanges[0]
+	c.Assert(s0.CurrentState.Name, check.Equals, "running")
+	c.Assert(s0.StateReason, check.DeepEquals, ec2.InstanceStateReason{
+		Code:    "Client.UserInitiatedShutdown",
+		Message: "Client.UserInitiatedShutdown: User initiated shutdown",
+	})
+}
+
+func (s *S) TestRebootInstances(c *check.C) {
+	testServer.Response(200, nil, RebootInstancesExample)
+
+	resp, err := s.ec2.RebootInstances("i-10a64379")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"RebootInstances"})
+	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-10a64379"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestSignatureWithEndpointPath(c *check.C) {
+	ec2.FakeTime(true)
+	defer ec2.FakeTime(false)
+
+	testServer.Response(200, nil, RebootInstancesExample)
+
+	region := aws.Region{EC2Endpoint: aws.ServiceInfo{Endpoint: testServer.URL + "/services/Cloud", Signer: aws.V2Signature}}
+	ec2 := ec2.New(s.ec2.Auth, region)
+
+	_, err := ec2.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Signature"], check.DeepEquals, []string{"VVoC6Y6xfES+KvZo+789thP8+tye4F6fOKBiKmXk4S4="})
+}
+
+func (s *S) TestSignatureV4(c *check.C) {
+	ec2.FakeTime(true)
+	defer ec2.FakeTime(false)
+
+	testServer.Response(200, nil, RebootInstancesExample)
+
+	region := aws.Region{Name: "eu-central-1", EC2Endpoint: aws.ServiceInfo{Endpoint: testServer.URL, Signer: aws.V4Signature}}
+	ec2 := ec2.New(s.ec2.Auth, region)
+
+	_, err := ec2.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Signature"], check.IsNil)
+	c.Assert(req.Form["AWSAccessKeyId"], check.IsNil)
+	c.Assert(req.Header.Get("X-Amz-Date"), check.Equals, "20120101T000000Z")
+	c.Assert(req.Header.Get("Authorization"), check.Equals, "AWS4-HMAC-SHA256 Credential=abc/20120101/eu-central-1/ec2/aws4_request, SignedHeaders=host;x-amz-date, Signature=dda0fbff68f22330d5e9d4425eb60c8dc531bcaa9568ad25d4aed218956db82c")
+}
+
+func (s *S) TestSecurityTokenV2(c *check.C) {
+	testServer.Response(200, nil, RebootInstancesExample)
+
+	auth := aws.NewAuth("abc", "123", "session-token", time.Now().Add(time.Hour))
+	ec2 := ec2.New(*auth, s.ec2.Region)
+
+	_, err := ec2.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["SecurityToken"], check.DeepEquals, []string{"session-token"})
+}
+
+func (s *S) TestSecurityTokenV4(c *check.C) {
+	testServer.Response(200, nil, RebootInstancesExample)
+
+	auth := aws.NewAuth("abc", "123", "session-token", time.Now().Add(time.Hour))
+	region := aws.Region{Name: "eu-central-1", EC2Endpoint: aws.ServiceInfo{Endpoint: testServer.URL, Signer: aws.V4Signature}}
+	ec2 := ec2.New(*auth, region)
+
+	_, err := ec2.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("X-Amz-Security-Token"), check.Equals, "session-token")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, ".*SignedHeaders=host;x-amz-date;x-amz-security-token,.*")
+}
+
+func (s *S) TestDescribeReservedInstancesiExample(c *check.C) {
+	testServer.Response(200, nil, DescribeReservedInstancesExample)
+
+	resp, err := s.ec2.DescribeReservedInstances([]string{"i-1", "i-2"}, nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeReservedInstances"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.ReservedInstances, check.HasLen, 1)
+
+	r0 := resp.ReservedInstances[0]
+	c.Assert(r0.ReservedInstanceId, check.Equals, "e5a2ff3b-7d14-494f-90af-0b5d0EXAMPLE")
+
+}
+
+func (s *S) TestReservedInstances(c *check.C) {
+	testServer.Response(200, nil, DescribeReservedInstancesScopeExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("state", "active")
+	resp, err := s.ec2.ReservedInstances([]string{"af9f760e-6f91-4559-85f7-4980eexample"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeReservedInstances"})
+	c.Assert(req.Form["ReservedInstancesId.1"], check.DeepEquals, []string{"af9f760e-6f91-4559-85f7-4980eexample"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"state"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.ReservedInstances, check.DeepEquals, []ec2.ReservedInstance{{
+		ReservedInstancesId: "af9f760e-6f91-4559-85f7-4980eexample",
+		InstanceType:        "m4.large",
+		Start:               "2017-01-01T00:00:00.000Z",
+		End:                 "2018-01-01T00:00:00.000Z",
+		Duration:            31536000,
+		FixedPrice:          0,
+		UsagePrice:          0,
+		InstanceCount:       2,
+		ProductDescription:  "Linux/UNIX",
+		State:               "active",
+		OfferingType:        "No Upfront",
+		OfferingClass:       "standard",
+		Scope:               "Region",
+		InstanceTenancy:     "default",
+		CurrencyCode:        "USD",
+		RecurringCharges:    []ec2.RecurringCharge{{Frequency: "Hourly", Amount: 0.065}},
+		Tags:                []ec2.Tag{{Key: "team", Value: "web"}},
+	}})
+}
+
+func (s *S) TestReservedInstancesOfferings(c *check.C) {
+	testServer.Response(200, nil, DescribeReservedInstancesOfferingsPage1Example)
+	testServer.Response(200, nil, DescribeReservedInstancesOfferingsPage2Example)
+
+	filter := ec2.NewFilter()
+	filter.Add("instance-type", "m4.large")
+	resp, err := s.ec2.ReservedInstancesOfferings(filter)
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["Action"], check.DeepEquals, []string{"DescribeReservedInstancesOfferings"})
+	c.Assert(reqs[0].Form["Filter.1.Name"], check.DeepEquals, []string{"instance-type"})
+	c.Assert(reqs[0].Form["NextToken"], check.IsNil)
+	c.Assert(reqs[1].Form["Filter.1.Name"], check.DeepEquals, []string{"instance-type"})
+	c.Assert(reqs[1].Form["NextToken"], check.DeepEquals, []string{"page2token"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.NextToken, check.Equals, "")
+	c.Assert(resp.Offerings, check.HasLen, 2)
+	o := resp.Offerings[0]
+	c.Assert(o.ReservedInstancesOfferingId, check.Equals, "438012d3-4967-4ba9-aa40-cbb1dEXAMPLE")
+	c.Assert(o.InstanceType, check.Equals, "m4.large")
+	c.Assert(o.Duration, check.Equals, int64(31536000))
+	c.Assert(o.FixedPrice, check.Equals, 0.0)
+	c.Assert(o.OfferingType, check.Equals, "No Upfront")
+	c.Assert(o.Scope, check.Equals, "Region")
+	c.Assert(o.RecurringCharges, check.DeepEquals, []ec2.RecurringCharge{{Frequency: "Hourly", Amount: 0.065}})
+	c.Assert(resp.Offerings[1].ReservedInstancesOfferingId, check.Equals, "649fd0c8-7846-46b8-8f84-a6400EXAMPLE")
+	c.Assert(resp.Offerings[1].FixedPrice, check.Equals, 345.0)
+}
+
+func (s *S) TestReservedInstancesOfferingsPages(c *check.C) {
+	testServer.Response(200, nil, DescribeReservedInstancesOfferingsPage1Example)
+	testServer.Response(200, nil, DescribeReservedInstancesOfferingsPage2Example)
+
+	var ids []string
+	opts := &ec2.ReservedInstancesOfferingsPagesOptions{MaxResults: 1}
+	err := s.ec2.ReservedInstancesOfferingsPages(opts, func(page *ec2.ReservedInstancesOfferingsResp) error {
+		for _, o := range page.Offerings {
+			ids = append(ids, o.ReservedInstancesOfferingId)
+		}
+		return nil
+	})
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["MaxResults"], check.DeepEquals, []string{"1"})
+	c.Assert(reqs[0].Form["NextToken"], check.IsNil)
+	c.Assert(reqs[1].Form["MaxResults"], check.DeepEquals, []string{"1"})
+	c.Assert(reqs[1].Form["NextToken"], check.DeepEquals, []string{"page2token"})
+	c.Assert(err, check.IsNil)
+	c.Assert(ids, check.DeepEquals, []string{"438012d3-4967-4ba9-aa40-cbb1dEXAMPLE", "649fd0c8-7846-46b8-8f84-a6400EXAMPLE"})
+}
+
+func (s *S) TestReservedInstancesOfferingsPagesStop(c *check.C) {
+	testServer.Response(200, nil, DescribeReservedInstancesOfferingsPage1Example)
+
+	stop := errors.New("stop")
+	pages := 0
+	err := s.ec2.ReservedInstancesOfferingsPages(nil, func(page *ec2.ReservedInstancesOfferingsResp) error {
+		pages++
+		return stop
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["MaxResults"], check.IsNil)
+	c.Assert(err, check.Equals, stop)
+	c.Assert(pages, check.Equals, 1)
+}
+
+func (s *S) TestPurchaseReservedInstancesOffering(c *check.C) {
+	testServer.Response(200, nil, PurchaseReservedInstancesOfferingExample)
+
+	limit := 400.5
+	resp, err := s.ec2.PurchaseReservedInstancesOffering("649fd0c8-7846-46b8-8f84-a6400EXAMPLE", 2, &limit)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"PurchaseReservedInstancesOffering"})
+	c.Assert(req.Form["ReservedInstancesOfferingId"], check.DeepEquals, []string{"649fd0c8-7846-46b8-8f84-a6400EXAMPLE"})
+	c.Assert(req.Form["InstanceCount"], check.DeepEquals, []string{"2"})
+	c.Assert(req.Form["LimitPrice.Amount"], check.DeepEquals, []string{"400.5"})
+	c.Assert(req.Form["LimitPrice.CurrencyCode"], check.DeepEquals, []string{"USD"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.ReservedInstancesId, check.Equals, "e5a2ff3b-7d14-494f-90af-0b5d0EXAMPLE")
+}
+
+func (s *S) TestPurchaseReservedInstancesOfferingNoLimit(c *check.C) {
+	testServer.Response(200, nil, PurchaseReservedInstancesOfferingExample)
+
+	_, err := s.ec2.PurchaseReservedInstancesOffering("649fd0c8-7846-46b8-8f84-a6400EXAMPLE", 1, nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["LimitPrice.Amount"], check.IsNil)
+	c.Assert(req.Form["LimitPrice.CurrencyCode"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestDeregisterImage(c *check.C) {
+	testServer.Response(200, nil, DeregisterImageExample)
+
+	resp, err := s.ec2.DeregisterImage("i-1")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeregisterImage"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Response, check.Equals, true)
+
+}
+
+func (s *S) TestDescribeInstanceStatus(c *check.C) {
+	testServer.Response(200, nil, DescribeInstanceStatusExample)
+
+	resp, err := s.ec2.DescribeInstanceStatus([]string{"i-1a2b3c4d", "i-2a2b3c4d"}, nil)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstanceStatus"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "3be1508e-c444-4fef-89cc-0b1223c4f02fEXAMPLE")
+	c.Assert(resp.InstanceStatuses, check.HasLen, 4)
+	r0 := resp.InstanceStatuses[0]
+	c.Assert(r0.InstanceId, check.Equals, "i-1a2b3c4d")
+	c.Assert(r0.InstanceState, check.Equals, "running")
+	c.Assert(r0.SystemStatus.StatusName, check.Equals, "impaired")
+	c.Assert(r0.SystemStatus.Status, check.Equals, "failed")
+	c.Assert(r0.InstanceStatus.StatusName, check.Equals, "impaired")
+}
+
+func (s *S) TestDescribeVolumes(c *check.C) {
+	testServer.Response(200, nil, DescribeVolumesExample)
+
+	resp, err := s.ec2.DescribeVolumes([]string{"vol-1a2b3c4d"}, nil)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVolumes"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Volumes, check.HasLen, 1)
+	v0 := resp.Volumes[0]
+	c.Assert(v0.AvailabilityZone, check.Equals, "us-east-1a")
+	c.Assert(v0.Size, check.Equals, 80)
+	c.Assert(v0.Status, check.Equals, "in-use")
+	c.Assert(v0.AttachmentSet.VolumeId, check.Equals, "vol-1a2b3c4d")
+	c.Assert(v0.AttachmentSet.InstanceId, check.Equals, "i-1a2b3c4d")
+	c.Assert(v0.AttachmentSet.Device, check.Equals, "/dev/sdh")
+	c.Assert(v0.AttachmentSet.Status, check.Equals, "attached")
+	c.Assert(v0.Tags, check.DeepEquals, []ec2.Tag{{"Name", "data"}})
+}
+
+func (s *S) TestAttachVolume(c *check.C) {
+	testServer.Response(200, nil, AttachVolumeExample)
+
+	resp, err := s.ec2.AttachVolume("v-1", "i-1", "/dev/sdz")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AttachVolume"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestCreateVolume(c *check.C) {
+	testServer.Response(200, nil, CreateVolumeExample)
+
+	resp, err := s.ec2.CreateVolume(ec2.CreateVolumeOptions{
+		Size:             "1",
+		AvailabilityZone: "us-east-1a",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateVolume"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "0c67a4c9-d7ec-45ef-8016-bf666EXAMPLE")
+	c.Assert(resp.Size, check.Equals, "1")
+	c.Assert(resp.VolumeId, check.Equals, "vol-2a21e543")
+	c.Assert(resp.AvailabilityZone, check.Equals, "us-east-1a")
+	c.Assert(resp.SnapshotId, check.Equals, "")
+	c.Assert(resp.Status, check.Equals, "creating")
+	c.Assert(resp.CreateTime, check.Equals, "2009-12-28T05:42:53.000Z")
+	c.Assert(resp.VolumeType, check.Equals, "standard")
+	c.Assert(resp.IOPS, check.Equals, 0)
+	c.Assert(resp.Encrypted, check.Equals, false)
+}
+
+func (s *S) TestCreateVolumeClientToken(c *check.C) {
+	testServer.Response(200, nil, CreateVolumeExample)
+
+	resp, err := s.ec2.CreateVolume(ec2.CreateVolumeOptions{
+		Size:             "1",
+		AvailabilityZone: "us-east-1a",
+		ClientToken:      "my-token",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["ClientToken"], check.DeepEquals, []string{"my-token"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.ClientToken, check.Equals, "my-token")
+
+	testServer.Response(200, nil, CreateVolumeExample)
+
+	resp, err = s.ec2.CreateVolume(ec2.CreateVolumeOptions{Size: "1", AvailabilityZone: "us-east-1a"})
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["ClientToken"], check.HasLen, 1)
+	c.Assert(req.Form["ClientToken"][0], check.Matches, "[0-9a-f]{64}")
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.ClientToken, check.Equals, req.Form["ClientToken"][0])
+}
+
+func (s *S) TestCreateVolumeGp3(c *check.C) {
+	testServer.Response(200, nil, CreateVolumeExample)
+
+	_, err := s.ec2.CreateVolume(ec2.CreateVolumeOptions{
+		Size:             "500",
+		AvailabilityZone: "us-east-1a",
+		VolumeType:       "gp3",
+		IOPS:             6000,
+		Throughput:       500,
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["VolumeType"], check.DeepEquals, []string{"gp3"})
+	c.Assert(req.Form["Iops"], check.DeepEquals, []string{"6000"})
+	c.Assert(req.Form["Throughput"], check.DeepEquals, []string{"500"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestCreateVolumeInvalidType(c *check.C) {
+	_, err := s.ec2.CreateVolume(ec2.CreateVolumeOptions{
+		AvailabilityZone: "us-east-1a",
+		VolumeType:       "gp",
+	})
+	c.Assert(err, check.ErrorMatches, `invalid volume type "gp"`)
+
+	_, err = s.ec2.CreateVolume(ec2.CreateVolumeOptions{
+		AvailabilityZone: "us-east-1a",
+		VolumeType:       ec2.VolumeTypeGp2,
+		IOPS:             3000,
+	})
+	c.Assert(err, check.ErrorMatches, "IOPS can't be set for gp2 volumes")
+}
+
+func (s *S) TestModifyVolumeNilOptions(c *check.C) {
+	resp, err := s.ec2.ModifyVolume("vol-1", nil)
+	c.Assert(err, check.ErrorMatches, "no volume modification given")
+	c.Assert(resp, check.IsNil)
+}
+
+func (s *S) TestModifyVolumeInvalidType(c *check.C) {
+	_, err := s.ec2.ModifyVolume("vol-1", &ec2.ModifyVolumeOptions{
+		VolumeType: ec2.VolumeTypeSt1,
+		IOPS:       500,
+	})
+	c.Assert(err, check.ErrorMatches, "IOPS can't be set for st1 volumes")
+}
+
+func (s *S) TestRunInstancesInvalidVolumeType(c *check.C) {
+	_, err := s.ec2.RunInstances(&ec2.RunInstancesOptions{
+		ImageId: "image-id",
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{
+			{DeviceName: "/dev/sdb", VolumeType: ec2.VolumeTypeIo2, IOPS: 1000},
+			{DeviceName: "/dev/sdc", VolumeType: ec2.VolumeTypeSc1, IOPS: 100},
+		},
+	})
+	c.Assert(err, check.ErrorMatches, "block device /dev/sdc: IOPS can't be set for sc1 volumes")
+}
+
+func (s *S) TestDescribeVolumesGp3(c *check.C) {
+	testServer.Response(200, nil, DescribeVolumesGp3Example)
+
+	resp, err := s.ec2.DescribeVolumes([]string{"vol-0a1b2c3d4e5f67890"}, nil)
+	testServer.WaitRequest()
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Volumes, check.HasLen, 1)
+	v0 := resp.Volumes[0]
+	c.Assert(v0.VolumeType, check.Equals, "gp3")
+	c.Assert(v0.IOPS, check.Equals, 6000)
+	c.Assert(v0.Throughput, check.Equals, int64(500))
+}
+
+func (s *S) TestModifyVolume(c *check.C) {
+	testServer.Response(200, nil, ModifyVolumeExample)
+
+	resp, err := s.ec2.ModifyVolume("vol-0b3c2c4e0b3c2c4e0", &ec2.ModifyVolumeOptions{
+		Size:       200,
+		VolumeType: "io1",
+		IOPS:       10000,
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ModifyVolume"})
+	c.Assert(req.Form["VolumeId"], check.DeepEquals, []string{"vol-0b3c2c4e0b3c2c4e0"})
+	c.Assert(req.Form["Size"], check.DeepEquals, []string{"200"})
+	c.Assert(req.Form["VolumeType"], check.DeepEquals, []string{"io1"})
+	c.Assert(req.Form["Iops"], check.DeepEquals, []string{"10000"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "5jkdf074-37ed-4004-8671-a78ee82bf1cbEXAMPLE")
+	c.Assert(resp.VolumeModification, check.DeepEquals, ec2.VolumeModification{
+		VolumeId:           "vol-0b3c2c4e0b3c2c4e0",
+		State:              "modifying",
+		Progress:           0,
+		TargetSize:         200,
+		TargetVolumeType:   "io1",
+		TargetIOPS:         10000,
+		OriginalSize:       100,
+		OriginalVolumeType: "io1",
+		OriginalIOPS:       300,
+		StartTime:          "2017-01-19T22:21:02.959Z",
+	})
+}
+
+func (s *S) TestModifyVolumeSizeOnly(c *check.C) {
+	testServer.Response(200, nil, ModifyVolumeExample)
+
+	_, err := s.ec2.ModifyVolume("vol-0b3c2c4e0b3c2c4e0", &ec2.ModifyVolumeOptions{Size: 200})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Size"], check.DeepEquals, []string{"200"})
+	c.Assert(req.Form["VolumeType"], check.IsNil)
+	c.Assert(req.Form["Iops"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestVolumeStatus(c *check.C) {
+	testServer.Response(200, nil, DescribeVolumeStatusExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("volume-status.status", "impaired")
+	resp, err := s.ec2.VolumeStatus([]string{"vol-1111111111111111a", "vol-2222222222222222b"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVolumeStatus"})
+	c.Assert(req.Form["VolumeId.1"], check.DeepEquals, []string{"vol-1111111111111111a"})
+	c.Assert(req.Form["VolumeId.2"], check.DeepEquals, []string{"vol-2222222222222222b"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"volume-status.status"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "5jkdf074-37ed-4004-8671-a78ee82bf1cbEXAMPLE")
+	c.Assert(resp.Volumes, check.HasLen, 2)
+
+	v0 := resp.Volumes[0]
+	c.Assert(v0.VolumeId, check.Equals, "vol-1111111111111111a")
+	c.Assert(v0.AvailabilityZone, check.Equals, "us-east-1a")
+	c.Assert(v0.Status, check.Equals, "ok")
+	c.Assert(v0.Details, check.DeepEquals, []ec2.VolumeStatusDetail{
+		{Name: "io-enabled", Status: "passed"},
+		{Name: "io-performance", Status: "not-applicable"},
+	})
+	c.Assert(v0.Actions, check.HasLen, 0)
+	c.Assert(v0.Events, check.HasLen, 0)
+
+	v1 := resp.Volumes[1]
+	c.Assert(v1.Status, check.Equals, "impaired")
+	c.Assert(v1.Details, check.DeepEquals, []ec2.VolumeStatusDetail{
+		{Name: "io-enabled", Status: "failed"},
+	})
+	c.Assert(v1.Events, check.DeepEquals, []ec2.VolumeStatusEvent{{
+		EventId:     "evol-61a54008",
+		EventType:   "potential-data-inconsistency",
+		Description: "THIS IS AN EXAMPLE",
+		NotBefore:   "2011-12-01T14:00:00.000Z",
+		NotAfter:    "2011-12-01T15:00:00.000Z",
+	}})
+	c.Assert(v1.Actions, check.DeepEquals, []ec2.VolumeStatusAction{{
+		Code:        "enable-volume-io",
+		EventId:     "evol-61a54008",
+		EventType:   "potential-data-inconsistency",
+		Description: "THIS IS AN EXAMPLE",
+	}})
+}
+
+func (s *S) TestEnableVolumeIO(c *check.C) {
+	testServer.Response(200, nil, EnableVolumeIOExample)
+
+	resp, err := s.ec2.EnableVolumeIO("vol-8888888888888888d")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"EnableVolumeIO"})
+	c.Assert(req.Form["VolumeId"], check.DeepEquals, []string{"vol-8888888888888888d"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "5jkdf074-37ed-4004-8671-a78ee82bf1cbEXAMPLE")
+}
+
+func (s *S) TestDescribeVpcs(c *check.C) {
+	testServer.Response(200, nil, DescribeVpcsExample)
+
+	resp, err := s.ec2.DescribeVpcs([]string{"vpc-1a2b3c4d"}, nil)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpcs"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+	c.Assert(resp.Vpcs, check.HasLen, 1)
+	v0 := resp.Vpcs[0]
+	c.Assert(v0.VpcId, check.Equals, "vpc-1a2b3c4d")
+	c.Assert(v0.State, check.Equals, "available")
+	c.Assert(v0.CidrBlock, check.Equals, "10.0.0.0/23")
+	c.Assert(v0.DhcpOptionsId, check.Equals, "dopt-7a8b9c2d")
+	c.Assert(v0.InstanceTenancy, check.Equals, "default")
+	c.Assert(v0.IsDefault, check.Equals, false)
+	c.Assert(v0.Tags, check.DeepEquals, []ec2.Tag{{"Name", "main"}})
+}
+
+func (s *S) TestDescribeVpnConnections(c *check.C) {
+	testServer.Response(200, nil, DescribeVpnConnectionsExample)
+
+	resp, err := s.ec2.DescribeVpnConnections([]string{"vpn-44a8938f"}, nil)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpnConnections"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+	c.Assert(resp.VpnConnections, check.HasLen, 1)
+	v0 := resp.VpnConnections[0]
+	c.Assert(v0.VpnConnectionId, check.Equals, "vpn-44a8938f")
+	c.Assert(v0.State, check.Equals, "available")
+	c.Assert(v0.Type, check.Equals, "ipsec.1")
+	c.Assert(v0.CustomerGatewayId, check.Equals, "cgw-b4dc3961")
+	c.Assert(v0.VpnGatewayId, check.Equals, "vgw-8db04f81")
+}
+
+func (s *S) TestDescribeVpnGateways(c *check.C) {
+	testServer.Response(200, nil, DescribeVpnGatewaysExample)
+
+	resp, err := s.ec2.DescribeVpnGateways([]string{"vgw-8db04f81"}, nil)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpnGateways"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+	c.Assert(resp.VpnGateway, check.HasLen, 1)
+	g0 := resp.VpnGateway[0]
+	c.Assert(g0.VpnGatewayId, check.Equals, "vgw-8db04f81")
+	c.Assert(g0.State, check.Equals, "available")
+	c.Assert(g0.Type, check.Equals, "ipsec.1")
+	c.Assert(g0.AvailabilityZone, check.Equals, "us-east-1a")
+	c.Assert(g0.AttachedVpcId, check.Equals, "vpc-1a2b3c4d")
+	c.Assert(g0.AttachState, check.Equals, "attached")
+}
+
+func (s *S) TestDescribeInternetGateways(c *check.C) {
+	testServer.Response(200, nil, DescribeInternetGatewaysExample)
+
+	resp, err := s.ec2.DescribeInternetGateways([]string{"igw-eaad4883EXAMPLE"}, nil)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInternetGateways"})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.InternetGateway, check.HasLen, 1)
+	g0 := resp.InternetGateway[0]
+	c.Assert(g0.InternetGatewayId, check.Equals, "igw-eaad4883EXAMPLE")
+	c.Assert(g0.AttachedVpcId, check.Equals, "vpc-11ad4878")
+	c.Assert(g0.AttachState, check.Equals, "available")
+}
+
+func (s *S) TestCreatePlacementGroup(c *check.C) {
+	testServer.Response(200, nil, CreatePlacementGroupExample)
+
+	resp, err := s.ec2.CreatePlacementGroup("XYZ-cluster", "cluster")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreatePlacementGroup"})
+	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"XYZ-cluster"})
+	c.Assert(req.Form["Strategy"], check.DeepEquals, []string{"cluster"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE")
+}
+
+func (s *S) TestCreatePlacementGroupSpread(c *check.C) {
+	testServer.Response(200, nil, CreatePlacementGroupExample)
+
+	_, err := s.ec2.CreatePlacementGroup("XYZ-spread", "spread")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Strategy"], check.DeepEquals, []string{"spread"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestDeletePlacementGroup(c *check.C) {
+	testServer.Response(200, nil, DeletePlacementGroupExample)
+
+	resp, err := s.ec2.DeletePlacementGroup("XYZ-cluster")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeletePlacementGroup"})
+	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"XYZ-cluster"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE")
+}
+
+func (s *S) TestPlacementGroups(c *check.C) {
+	testServer.Response(200, nil, DescribePlacementGroupsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("strategy", "cluster")
+
+	resp, err := s.ec2.PlacementGroups([]string{"XYZ-cluster", "ABC-cluster"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribePlacementGroups"})
+	c.Assert(req.Form["GroupName.1"], check.DeepEquals, []string{"XYZ-cluster"})
+	c.Assert(req.Form["GroupName.2"], check.DeepEquals, []string{"ABC-cluster"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"strategy"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"cluster"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE")
+	c.Assert(resp.PlacementGroups, check.DeepEquals, []ec2.PlacementGroup{
+		{Name: "XYZ-cluster", State: "available", Strategy: "cluster"},
+		{Name: "ABC-cluster", State: "available", Strategy: "cluster"},
+	})
+}
+
+func (s *S) TestInstanceTypeOfferings(c *check.C) {
+	testServer.Response(200, nil, DescribeInstanceTypeOfferingsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("location", "us-east-1a")
+
+	resp, err := s.ec2.InstanceTypeOfferings("availability-zone", filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstanceTypeOfferings"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["LocationType"], check.DeepEquals, []string{"availability-zone"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"location"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"us-east-1a"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7ce5e8c9-5bc7-4c2e-9a54-4b2dEXAMPLE")
+	c.Assert(resp.Offerings, check.DeepEquals, []ec2.InstanceTypeOffering{
+		{InstanceType: "c5.xlarge", LocationType: "availability-zone", Location: "us-east-1a"},
+		{InstanceType: "m5.large", LocationType: "availability-zone", Location: "us-east-1a"},
+	})
+	c.Assert(resp.NextToken, check.Equals, "")
+}
+
+func (s *S) TestInstanceTypeOfferingsDefaultLocationType(c *check.C) {
+	testServer.Response(200, nil, DescribeInstanceTypeOfferingsExample)
+
+	_, err := s.ec2.InstanceTypeOfferings("", nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["LocationType"], check.IsNil)
+	c.Assert(req.Form["Filter.1.Name"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestNilFilter(c *check.C) {
+	calls := []func() error{
+		func() error { _, err := s.ec2.DescribeInstances(nil, nil); return err },
+		func() error { _, err := s.ec2.Images(nil, nil); return err },
+		func() error { _, err := s.ec2.Snapshots(nil, nil); return err },
+		func() error { _, err := s.ec2.SecurityGroups(nil, nil); return err },
+		func() error { _, err := s.ec2.DescribeAddresses(nil, nil, nil); return err },
+	}
+	for _, call := range calls {
+		testServer.Response(200, nil, SimpleResponseExample)
+		err := call()
+		req := testServer.WaitRequest()
+		c.Assert(err, check.IsNil)
+		for name := range req.Form {
+			c.Assert(name, check.Not(check.Matches), `Filter\..*`)
+		}
+	}
+}
+
+func (s *S) TestZeroValueFilter(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	var filter ec2.Filter
+	filter.Add("instance-state-name", "running")
+	_, err := s.ec2.DescribeInstances(nil, &filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"instance-state-name"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"running"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestFilterMerge(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	other := ec2.NewFilter()
+	other.Add("instance-state-name", "stopped")
+	other.Add("tag:Role", "web")
+
+	var filter ec2.Filter
+	filter.Add("instance-state-name", "running")
+	filter.Merge(other)
+	filter.Merge(nil)
+	_, err := s.ec2.DescribeInstances(nil, &filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"instance-state-name"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"running"})
+	c.Assert(req.Form["Filter.1.Value.2"], check.DeepEquals, []string{"stopped"})
+	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"tag:Role"})
+	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"web"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestFilterGetNames(c *check.C) {
+	filter := ec2.NewFilter()
+	filter.Add("tag:Role", "web")
+	filter.Add("instance-state-name", "running", "stopped")
+
+	c.Assert(filter.Names(), check.DeepEquals, []string{"instance-state-name", "tag:Role"})
+	c.Assert(filter.Get("instance-state-name"), check.DeepEquals, []string{"running", "stopped"})
+	c.Assert(filter.Get("vpc-id"), check.IsNil)
+
+	var nilFilter *ec2.Filter
+	c.Assert(nilFilter.Names(), check.HasLen, 0)
+	c.Assert(nilFilter.Get("vpc-id"), check.IsNil)
+}
+
+func (s *S) TestRunInstancesDryRun(c *check.C) {
+	testServer.Response(412, nil, DryRunOperationDump)
+
+	options := ec2.RunInstancesOptions{ImageId: "image-id", DryRun: true}
+	resp, err := s.ec2.RunInstances(&options)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["DryRun"], check.DeepEquals, []string{"true"})
+	c.Assert(resp, check.IsNil)
+
+	permitted, err := ec2.CheckDryRun(err)
+	c.Assert(err, check.IsNil)
+	c.Assert(permitted, check.Equals, true)
+}
+
+func (s *S) TestCreateVolumeDryRunUnauthorized(c *check.C) {
+	testServer.Response(403, nil, UnauthorizedOperationDump)
+
+	_, err := s.ec2.CreateVolume(ec2.CreateVolumeOptions{AvailabilityZone: "us-east-1a", DryRun: true})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["DryRun"], check.DeepEquals, []string{"true"})
+
+	permitted, err := ec2.CheckDryRun(err)
+	c.Assert(err, check.IsNil)
+	c.Assert(permitted, check.Equals, false)
+}
+
+func (s *S) TestAssociateAddressDryRun(c *check.C) {
+	testServer.Response(412, nil, DryRunOperationDump)
+
+	_, err := s.ec2.AssociateAddress(&ec2.AssociateAddressOptions{InstanceId: "i-4fd2431a", PublicIp: "192.0.2.1", DryRun: true})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["DryRun"], check.DeepEquals, []string{"true"})
+
+	permitted, err := ec2.CheckDryRun(err)
+	c.Assert(err, check.IsNil)
+	c.Assert(permitted, check.Equals, true)
+}
+
+func (s *S) TestCheckDryRunOtherErrors(c *check.C) {
+	testServer.Response(400, nil, ErrorDump)
+
+	_, err := s.ec2.RunInstances(&ec2.RunInstancesOptions{ImageId: "image-id", DryRun: true})
+	testServer.WaitRequest()
+
+	permitted, rerr := ec2.CheckDryRun(err)
+	c.Assert(permitted, check.Equals, false)
+	c.Assert(rerr, check.Equals, err)
+
+	permitted, rerr = ec2.CheckDryRun(nil)
+	c.Assert(permitted, check.Equals, false)
+	c.Assert(rerr, check.ErrorMatches, "request was not a dry run")
+}
+
+func (s *S) TestAssociateIamInstanceProfile(c *check.C) {
+	testServer.Response(200, nil, AssociateIamInstanceProfileExample)
+
+	resp, err := s.ec2.AssociateIamInstanceProfile("i-123456789abcde123", ec2.IamInstanceProfile{Name: "admin-role"})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AssociateIamInstanceProfile"})
+	c.Assert(req.Form["InstanceId"], check.DeepEquals, []string{"i-123456789abcde123"})
+	c.Assert(req.Form["IamInstanceProfile.Name"], check.DeepEquals, []string{"admin-role"})
+	c.Assert(req.Form["IamInstanceProfile.Arn"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "e10deeaf-7cda-48e7-950b-example")
+	c.Assert(resp.Association, check.DeepEquals, ec2.IamInstanceProfileAssociation{
+		AssociationId: "iip-assoc-0e7736511a163c209",
+		InstanceId:    "i-123456789abcde123",
+		IamInstanceProfile: ec2.IamInstanceProfile{
+			ARN: "arn:aws:iam::123456789012:instance-profile/admin-role",
+			Id:  "AIPAJBLK7RKJKWDXVHIEC",
+		},
+		State: "associating",
+	})
+}
+
+func (s *S) TestAssociateIamInstanceProfileByArn(c *check.C) {
+	testServer.Response(200, nil, AssociateIamInstanceProfileExample)
+
+	arn := "arn:aws:iam::123456789012:instance-profile/admin-role"
+	_, err := s.ec2.AssociateIamInstanceProfile("i-123456789abcde123", ec2.IamInstanceProfile{ARN: arn, Name: "ignored"})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["IamInstanceProfile.Arn"], check.DeepEquals, []string{arn})
+	c.Assert(req.Form["IamInstanceProfile.Name"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestDisassociateIamInstanceProfile(c *check.C) {
+	testServer.Response(200, nil, DisassociateIamInstanceProfileExample)
+
+	resp, err := s.ec2.DisassociateIamInstanceProfile("iip-assoc-0e7736511a163c209")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DisassociateIamInstanceProfile"})
+	c.Assert(req.Form["AssociationId"], check.DeepEquals, []string{"iip-assoc-0e7736511a163c209"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Association.AssociationId, check.Equals, "iip-assoc-0e7736511a163c209")
+	c.Assert(resp.Association.State, check.Equals, "disassociating")
+}
+
+func (s *S) TestReplaceIamInstanceProfileAssociation(c *check.C) {
+	testServer.Response(200, nil, ReplaceIamInstanceProfileAssociationExample)
+
+	resp, err := s.ec2.ReplaceIamInstanceProfileAssociation("iip-assoc-0e7736511a163c209", ec2.IamInstanceProfile{Name: "user1-role"})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ReplaceIamInstanceProfileAssociation"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["AssociationId"], check.DeepEquals, []string{"iip-assoc-0e7736511a163c209"})
+	c.Assert(req.Form["IamInstanceProfile.Name"], check.DeepEquals, []string{"user1-role"})
+	c.Assert(req.Form["IamInstanceProfile.Arn"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Association.AssociationId, check.Equals, "iip-assoc-08049da59357d598c")
+	c.Assert(resp.Association.State, check.Equals, "associating")
+}
+
+func (s *S) TestIamInstanceProfileAssociations(c *check.C) {
+	testServer.Response(200, nil, DescribeIamInstanceProfileAssociationsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("instance-id", "i-09eb09efa73ec1dee")
+	resp, err := s.ec2.IamInstanceProfileAssociations(filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeIamInstanceProfileAssociations"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"instance-id"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"i-09eb09efa73ec1dee"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Associations, check.HasLen, 1)
+	a0 := resp.Associations[0]
+	c.Assert(a0.AssociationId, check.Equals, "iip-assoc-0db249b1f25fa24b8")
+	c.Assert(a0.InstanceId, check.Equals, "i-09eb09efa73ec1dee")
+	c.Assert(a0.IamInstanceProfile.ARN, check.Equals, "arn:aws:iam::123456789012:instance-profile/admin-role")
+	c.Assert(a0.State, check.Equals, "associated")
+}
+
+func (s *S) TestRequestSpotFleet(c *check.C) {
+	testServer.Response(200, nil, RequestSpotFleetExample)
+
+	config := &ec2.SpotFleetRequestConfig{
+		IamFleetRole:       "arn:aws:iam::123456789011:role/spot-fleet-role",
+		TargetCapacity:     5,
+		SpotPrice:          "0.04",
+		AllocationStrategy: "diversified",
+		ClientToken:        "fleet-token",
+		LaunchSpecifications: []ec2.SpotFleetLaunchSpecification{{
+			ImageId:        "ami-1a2b3c4d",
+			InstanceType:   "m4.large",
+			KeyName:        "my-keys",
+			SecurityGroups: []ec2.SecurityGroup{{Id: "sg-1a2b3c4d"}},
+			SubnetId:       "subnet-1a2b3c4d",
+			UserData:       []byte("1234"),
+			BlockDeviceMappings: []ec2.BlockDeviceMapping{
+				{DeviceName: "/dev/sdb", VolumeSize: 20, VolumeType: "gp2"},
+			},
+		}, {
+			ImageId:          "ami-1a2b3c4d",
+			InstanceType:     "c4.xlarge",
+			AvailabilityZone: "us-east-1a",
+			WeightedCapacity: 2,
+		}},
+	}
+	resp, err := s.ec2.RequestSpotFleet(config)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"RequestSpotFleet"})
+	c.Assert(req.Form["SpotFleetRequestConfig.IamFleetRole"], check.DeepEquals, []string{"arn:aws:iam::123456789011:role/spot-fleet-role"})
+	c.Assert(req.Form["SpotFleetRequestConfig.TargetCapacity"], check.DeepEquals, []string{"5"})
+	c.Assert(req.Form["SpotFleetRequestConfig.SpotPrice"], check.DeepEquals, []string{"0.04"})
+	c.Assert(req.Form["SpotFleetRequestConfig.AllocationStrategy"], check.DeepEquals, []string{"diversified"})
+	c.Assert(req.Form["SpotFleetRequestConfig.ClientToken"], check.DeepEquals, []string{"fleet-token"})
+	c.Assert(req.Form["SpotFleetRequestConfig.TerminateInstancesWithExpiration"], check.IsNil)
+	spec := "SpotFleetRequestConfig.LaunchSpecifications.1."
+	c.Assert(req.Form[spec+"ImageId"], check.DeepEquals, []string{"ami-1a2b3c4d"})
+	c.Assert(req.Form[spec+"InstanceType"], check.DeepEquals, []string{"m4.large"})
+	c.Assert(req.Form[spec+"KeyName"], check.DeepEquals, []string{"my-keys"})
+	c.Assert(req.Form[spec+"GroupSet.1.GroupId"], check.DeepEquals, []string{"sg-1a2b3c4d"})
+	c.Assert(req.Form[spec+"SubnetId"], check.DeepEquals, []string{"subnet-1a2b3c4d"})
+	c.Assert(req.Form[spec+"UserData"], check.DeepEquals, []string{"MTIzNA=="})
+	c.Assert(req.Form[spec+"BlockDeviceMapping.1.DeviceName"], check.DeepEquals, []string{"/dev/sdb"})
+	c.Assert(req.Form[spec+"BlockDeviceMapping.1.Ebs.VolumeSize"], check.DeepEquals, []string{"20"})
+	c.Assert(req.Form[spec+"BlockDeviceMapping.1.Ebs.VolumeType"], check.DeepEquals, []string{"gp2"})
+	c.Assert(req.Form[spec+"WeightedCapacity"], check.IsNil)
+	spec = "SpotFleetRequestConfig.LaunchSpecifications.2."
+	c.Assert(req.Form[spec+"InstanceType"], check.DeepEquals, []string{"c4.xlarge"})
+	c.Assert(req.Form[spec+"Placement.AvailabilityZone"], check.DeepEquals, []string{"us-east-1a"})
+	c.Assert(req.Form[spec+"WeightedCapacity"], check.DeepEquals, []string{"2"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "60262cc5-2bd4-4c8d-98ed-example")
+	c.Assert(resp.SpotFleetRequestId, check.Equals, "sfr-123f8fc2-cb31-425e-abcd-example2710")
+}
+
+func (s *S) TestRequestSpotFleetGeneratedClientToken(c *check.C) {
+	testServer.Response(200, nil, RequestSpotFleetExample)
+
+	resp, err := s.ec2.RequestSpotFleet(&ec2.SpotFleetRequestConfig{
+		IamFleetRole:   "arn:aws:iam::123456789011:role/spot-fleet-role",
+		TargetCapacity: 1,
+	})
+
+	req := testServer.WaitRequest()
+	token := req.Form["SpotFleetRequestConfig.ClientToken"]
+	c.Assert(token, check.HasLen, 1)
+	c.Assert(token[0], check.Matches, "[0-9a-f]{64}")
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.ClientToken, check.Equals, token[0])
+}
+
+func (s *S) TestDescribeSpotFleetRequests(c *check.C) {
+	testServer.Response(200, nil, DescribeSpotFleetRequestsExample)
+
+	resp, err := s.ec2.DescribeSpotFleetRequests([]string{"sfr-123f8fc2-cb31-425e-abcd-example2710"})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSpotFleetRequests"})
+	c.Assert(req.Form["SpotFleetRequestId.1"], check.DeepEquals, []string{"sfr-123f8fc2-cb31-425e-abcd-example2710"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.SpotFleetRequests, check.DeepEquals, []ec2.SpotFleetRequest{{
+		SpotFleetRequestId: "sfr-123f8fc2-cb31-425e-abcd-example2710",
+		State:              "active",
+		ActivityStatus:     "fulfilled",
+		CreateTime:         "2017-09-01T16:20:35.000Z",
+		IamFleetRole:       "arn:aws:iam::123456789011:role/spot-fleet-role",
+		TargetCapacity:     5,
+		SpotPrice:          "0.04",
+		AllocationStrategy: "diversified",
+	}})
+}
+
+func (s *S) TestCancelSpotFleetRequests(c *check.C) {
+	testServer.Response(200, nil, CancelSpotFleetRequestsExample)
+
+	resp, err := s.ec2.CancelSpotFleetRequests([]string{"sfr-123f8fc2-cb31-425e-abcd-example2710", "sfr-0b1e2d3c-4f5a-6b7c-8d9e-example0000"}, true)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CancelSpotFleetRequests"})
+	c.Assert(req.Form["SpotFleetRequestId.1"], check.DeepEquals, []string{"sfr-123f8fc2-cb31-425e-abcd-example2710"})
+	c.Assert(req.Form["SpotFleetRequestId.2"], check.DeepEquals, []string{"sfr-0b1e2d3c-4f5a-6b7c-8d9e-example0000"})
+	c.Assert(req.Form["TerminateInstances"], check.DeepEquals, []string{"true"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Successful, check.DeepEquals, []ec2.CancelledSpotFleetRequest{{
+		SpotFleetRequestId: "sfr-123f8fc2-cb31-425e-abcd-example2710",
+		CurrentState:       "cancelled_terminating",
+		PreviousState:      "active",
+	}})
+	c.Assert(resp.Unsuccessful, check.DeepEquals, []ec2.UncancelledSpotFleetRequest{{
+		SpotFleetRequestId: "sfr-0b1e2d3c-4f5a-6b7c-8d9e-example0000",
+		Code:               "fleetRequestIdDoesNotExist",
+		Message:            "The spot fleet request Id does not exist.",
+	}})
+}
+
+func (s *S) TestKeyPairs(c *check.C) {
+	testServer.Response(200, nil, DescribeKeyPairsExample)
+
+	resp, err := s.ec2.KeyPairs([]string{"my-key-pair"}, nil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeKeyPairs"})
+	c.Assert(req.Form["KeyName.1"], check.DeepEquals, []string{"my-key-pair"})
+	c.Assert(req.Form["Filter.1.Name"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Keys, check.DeepEquals, []ec2.KeyPair{{
+		Name:        "my-key-pair",
+		Fingerprint: "1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca:9f:f5:f1:6f",
+	}})
+}
+
+func (s *S) TestKeyPairsByFingerprint(c *check.C) {
+	testServer.Response(200, nil, DescribeKeyPairsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key-fingerprint", "1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca:9f:f5:f1:6f")
+	resp, err := s.ec2.KeyPairs(nil, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeKeyPairs"})
+	c.Assert(req.Form["KeyName.1"], check.IsNil)
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key-fingerprint"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca:9f:f5:f1:6f"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Keys, check.HasLen, 1)
+	c.Assert(resp.Keys[0].Name, check.Equals, "my-key-pair")
+}
+
+func (s *S) TestDebugDumpsResponse(c *check.C) {
+	testServer.Response(200, nil, DescribeKeyPairsExample)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	ec2.Debug = true
+	defer func() {
+		ec2.Debug = false
+		log.SetOutput(os.Stderr)
+	}()
+
+	_, err := s.ec2.KeyPairs(nil, nil)
+	testServer.WaitRequest()
+
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Matches, "(?s).*response:.*HTTP/1.1 200 OK.*<keyName>my-key-pair</keyName>.*")
+}
+
+func (s *S) TestDebugRedactsCredentials(c *check.C) {
+	testServer.Response(200, nil, RebootInstancesExample)
+	testServer.Response(200, nil, CreateTagsExample)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	ec2.Debug = true
+	defer func() {
+		ec2.Debug = false
+		log.SetOutput(os.Stderr)
+	}()
+
+	auth := aws.NewAuth("abc", "123", "session-token", time.Now().Add(time.Hour))
+	e := ec2.New(*auth, s.ec2.Region)
+
+	_, err := e.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Signature"], check.HasLen, 1)
+	signature := req.Form["Signature"][0]
+
+	_, err = e.CreateTags([]string{"i-10a64379"}, []ec2.Tag{{"stack", "Production"}})
+	c.Assert(err, check.IsNil)
+	testServer.WaitRequest()
+
+	out := buf.String()
+	c.Assert(out, check.Matches, "(?s).*request: GET .*AWSAccessKeyId=REDACTED.*")
+	c.Assert(out, check.Matches, "(?s).*request: GET .*SecurityToken=REDACTED.*")
+	c.Assert(out, check.Matches, "(?s).*body: .*Action=CreateTags.*")
+	c.Assert(strings.Contains(out, "session-token"), check.Equals, false)
+	c.Assert(strings.Contains(out, "AWSAccessKeyId=abc"), check.Equals, false)
+	c.Assert(strings.Contains(out, url.QueryEscape(signature)), check.Equals, false)
+}
+
+func (s *S) TestResponseRequestID(c *check.C) {
+	testServer.Response(200, nil, CreateSnapshotExample)
+	testServer.Response(200, nil, CreateSecurityGroupExample)
+	testServer.Response(200, nil, RebootInstancesExample)
+
+	var responses []ec2.Response
+	snap, err := s.ec2.CreateSnapshot("vol-4d826724", "Daily Backup")
+	c.Assert(err, check.IsNil)
+	responses = append(responses, snap)
+	group, err := s.ec2.CreateSecurityGroup("websrv", "Web Servers")
+	c.Assert(err, check.IsNil)
+	responses = append(responses, group)
+	reboot, err := s.ec2.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+	responses = append(responses, reboot)
+	testServer.WaitRequests(3)
+
+	for _, resp := range responses {
+		c.Assert(resp.RequestID(), check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	}
+}
+
+func (s *S) TestEndpointOverride(c *check.C) {
+	testServer.Response(200, nil, RebootInstancesExample)
+
+	region := aws.Region{EC2Endpoint: aws.ServiceInfo{Endpoint: "https://ec2.invalid", Signer: aws.V2Signature}}
+	e := ec2.New(s.ec2.Auth, region)
+	e.Endpoint = testServer.URL
+
+	_, err := e.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"RebootInstances"})
+	c.Assert(req.Form["Signature"], check.HasLen, 1)
+}
+
+func (s *S) TestVersionOverride(c *check.C) {
+	testServer.Response(200, nil, RebootInstancesExample)
+	testServer.Response(200, nil, RebootInstancesExample)
+	testServer.Response(200, nil, DescribeInstanceTypeOfferingsExample)
+
+	_, err := s.ec2.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+
+	e := ec2.New(s.ec2.Auth, s.ec2.Region)
+	e.Version = "2015-10-01"
+	_, err = e.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+	_, err = e.InstanceTypeOfferings("", nil)
+	c.Assert(err, check.IsNil)
+
+	reqs := testServer.WaitRequests(3)
+	c.Assert(reqs[0].Form["Version"], check.DeepEquals, []string{"2014-02-01"})
+	c.Assert(reqs[1].Form["Version"], check.DeepEquals, []string{"2015-10-01"})
+	c.Assert(reqs[2].Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+}
+
+func (s *S) TestInstanceStateNormalized(c *check.C) {
+	c.Assert(ec2.InstanceState{Code: 16, Name: "running"}.Normalized(), check.Equals, ec2.InstanceStateRunning)
+	c.Assert(ec2.InstanceState{Code: 0x1250, Name: "stopped"}.Normalized(), check.Equals, ec2.InstanceStateStopped)
+	c.Assert(ec2.InstanceState{Code: 0}.Normalized(), check.Equals, ec2.InstanceStatePending)
+}
+
+func (s *S) TestInstanceRunOptions(c *check.C) {
+	inst := &ec2.Instance{
+		InstanceId:         "i-2ba64342",
+		ImageId:            "ami-a1b2c3d4",
+		InstanceType:       "m4.large",
+		KeyName:            "my-keys",
+		AvailabilityZone:   "us-east-1b",
+		SubnetId:           "subnet-1a2b3c4d",
+		Monitoring:         "enabled",
+		EbsOptimized:       true,
+		IamInstanceProfile: ec2.IamInstanceProfile{ARN: "arn:aws:iam::123456789012:instance-profile/web", Id: "AIPAJQ5ABCDEFGHIJKLMN"},
+		SecurityGroups:     []ec2.SecurityGroup{{Id: "sg-1a2b3c4d", Name: "web"}},
+		BlockDevices: []ec2.BlockDevice{
+			{DeviceName: "/dev/xvda", EBS: ec2.EBS{VolumeId: "vol-1a2b3c4d", DeleteOnTermination: true}},
+		},
+	}
+
+	options := inst.RunOptions()
+	c.Assert(options, check.DeepEquals, &ec2.RunInstancesOptions{
+		ImageId:             "ami-a1b2c3d4",
+		InstanceType:        "m4.large",
+		KeyName:             "my-keys",
+		AvailabilityZone:    "us-east-1b",
+		SubnetId:            "subnet-1a2b3c4d",
+		Monitoring:          true,
+		EbsOptimized:        true,
+		IamInstanceProfile:  ec2.IamInstanceProfile{ARN: "arn:aws:iam::123456789012:instance-profile/web"},
+		SecurityGroups:      []ec2.SecurityGroup{{Id: "sg-1a2b3c4d", Name: "web"}},
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{DeviceName: "/dev/xvda", DeleteOnTermination: true}},
+	})
+
+	options.SecurityGroups[0].Id = "sg-changed"
+	c.Assert(inst.SecurityGroups[0].Id, check.Equals, "sg-1a2b3c4d")
+}
+
+func (s *S) TestVpcAttribute(c *check.C) {
+	testServer.Response(200, nil, DescribeVpcAttributeExample)
+
+	resp, err := s.ec2.VpcAttribute("vpc-1a2b3c4d", "enableDnsHostnames")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpcAttribute"})
+	c.Assert(req.Form["VpcId"], check.DeepEquals, []string{"vpc-1a2b3c4d"})
+	c.Assert(req.Form["Attribute"], check.DeepEquals, []string{"enableDnsHostnames"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.VpcId, check.Equals, "vpc-1a2b3c4d")
+	c.Assert(resp.EnableDnsHostnames, check.Equals, true)
+	c.Assert(resp.EnableDnsSupport, check.Equals, false)
+}
+
+func (s *S) TestModifyVpcAttribute(c *check.C) {
+	testServer.Response(200, nil, ModifyVpcAttributeExample)
+	testServer.Response(200, nil, ModifyVpcAttributeExample)
+
+	support, hostnames := true, false
+	resp, err := s.ec2.ModifyVpcAttribute("vpc-1a2b3c4d", &ec2.VpcAttributeChange{
+		EnableDnsSupport:   &support,
+		EnableDnsHostnames: &hostnames,
+	})
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["Action"], check.DeepEquals, []string{"ModifyVpcAttribute"})
+	c.Assert(reqs[0].Form["VpcId"], check.DeepEquals, []string{"vpc-1a2b3c4d"})
+	c.Assert(reqs[0].Form["EnableDnsSupport.Value"], check.DeepEquals, []string{"true"})
+	c.Assert(reqs[0].Form["EnableDnsHostnames.Value"], check.IsNil)
+	c.Assert(reqs[1].Form["EnableDnsHostnames.Value"], check.DeepEquals, []string{"false"})
+	c.Assert(reqs[1].Form["EnableDnsSupport.Value"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+}
+
+func (s *S) TestModifyVpcAttributeNothingToChange(c *check.C) {
+	_, err := s.ec2.ModifyVpcAttribute("vpc-1a2b3c4d", &ec2.VpcAttributeChange{})
+	c.Assert(err, check.ErrorMatches, "no VPC attribute to change")
+
+	_, err = s.ec2.ModifyVpcAttribute("vpc-1a2b3c4d", nil)
+	c.Assert(err, check.ErrorMatches, "no VPC attribute to change")
+}
+
+func (s *S) TestDescribeInstancesAllInstances(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesMultiExample)
+
+	resp, err := s.ec2.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+	c.Assert(err, check.IsNil)
+
+	instances := resp.AllInstances()
+	c.Assert(instances, check.HasLen, 3)
+
+	c.Assert(instances[0].InstanceId, check.Equals, "i-1a2b3c4d")
+	c.Assert(instances[0].OwnerId, check.Equals, "111122223333")
+	c.Assert(instances[0].RequesterId, check.Equals, "226008221399")
+	c.Assert(instances[0].SecurityGroups, check.DeepEquals, []ec2.SecurityGroup{{Id: "sg-1a2b3c4d", Name: "default"}})
+
+	c.Assert(instances[1].InstanceId, check.Equals, "i-2b3c4d5e")
+	c.Assert(instances[1].OwnerId, check.Equals, "111122223333")
+	c.Assert(instances[1].SecurityGroups, check.DeepEquals, []ec2.SecurityGroup{{Id: "sg-2b3c4d5e", Name: "web"}})
+
+	c.Assert(instances[2].InstanceId, check.Equals, "i-3c4d5e6f")
+	c.Assert(instances[2].OwnerId, check.Equals, "444455556666")
+	c.Assert(instances[2].RequesterId, check.Equals, "")
+	c.Assert(instances[2].SecurityGroups, check.HasLen, 0)
+}
+
+func (s *S) TestDescribeInstancesPropagatesOwner(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesMultiExample)
+
+	resp, err := s.ec2.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Reservations, check.HasLen, 2)
+
+	r0 := resp.Reservations[0]
+	c.Assert(r0.Instances, check.HasLen, 2)
+	for _, inst := range r0.Instances {
+		c.Assert(inst.OwnerId, check.Equals, "111122223333")
+		c.Assert(inst.RequesterId, check.Equals, "226008221399")
+	}
+	r1 := resp.Reservations[1]
+	c.Assert(r1.Instances, check.HasLen, 1)
+	c.Assert(r1.Instances[0].OwnerId, check.Equals, "444455556666")
+	c.Assert(r1.Instances[0].RequesterId, check.Equals, "")
+}
+
+func (s *S) TestSecurityGroupRules(c *check.C) {
+	testServer.Response(200, nil, DescribeSecurityGroupRulesExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("group-id", "sg-0a1b2c3d4e5f67890")
+	resp, err := s.ec2.SecurityGroupRules([]string{"sgr-0123456789abcdef0"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSecurityGroupRules"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["SecurityGroupRuleId.1"], check.DeepEquals, []string{"sgr-0123456789abcdef0"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"group-id"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "6f8e1c2d-9b3a-4c5d-8e7f-0a1bEXAMPLE")
+	c.Assert(resp.Rules, check.DeepEquals, []ec2.SecurityGroupRule{{
+		SecurityGroupRuleId: "sgr-0123456789abcdef0",
+		GroupId:             "sg-0a1b2c3d4e5f67890",
+		GroupOwnerId:        "123456789012",
+		IsEgress:            false,
+		IpProtocol:          "tcp",
+		FromPort:            443,
+		ToPort:              443,
+		CidrIpv4:            "203.0.113.0/24",
+		Description:         "HTTPS from the office",
+	}, {
+		SecurityGroupRuleId: "sgr-0fedcba9876543210",
+		GroupId:             "sg-0a1b2c3d4e5f67890",
+		GroupOwnerId:        "123456789012",
+		IsEgress:            true,
+		IpProtocol:          "-1",
+		FromPort:            -1,
+		ToPort:              -1,
+		ReferencedGroupId:   "sg-0f1e2d3c4b5a69780",
+		Tags:                []ec2.Tag{{Key: "Name", Value: "to-db"}},
+	}})
+}
+
+func (s *S) TestInstanceJSON(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesMultiExample)
+
+	resp, err := s.ec2.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+	c.Assert(err, check.IsNil)
+
+	data, err := json.Marshal(resp.Reservations[0].Instances[1])
+	c.Assert(err, check.IsNil)
+	var fields map[string]interface{}
+	c.Assert(json.Unmarshal(data, &fields), check.IsNil)
+	c.Assert(fields["instanceId"], check.Equals, "i-2b3c4d5e")
+	c.Assert(fields["ownerId"], check.Equals, "111122223333")
+	c.Assert(fields["state"], check.DeepEquals, map[string]interface{}{"code": 16.0, "name": "running"})
+	c.Assert(fields["securityGroups"], check.DeepEquals, []interface{}{map[string]interface{}{"id": "sg-2b3c4d5e", "name": "web"}})
+	c.Assert(fields["InstanceId"], check.IsNil)
+
+	data, err = json.Marshal(resp)
+	c.Assert(err, check.IsNil)
+	var decoded ec2.DescribeInstancesResp
+	c.Assert(json.Unmarshal(data, &decoded), check.IsNil)
+	c.Assert(&decoded, check.DeepEquals, resp)
+}
+
+func (s *S) TestSecurityGroupInfoJSON(c *check.C) {
+	info := ec2.SecurityGroupInfo{
+		SecurityGroup: ec2.SecurityGroup{Id: "sg-67ad940e", Name: "websrv"},
+		OwnerId:       "999988887777",
+		IPPerms: []ec2.IPPerm{{
+			Protocol: "tcp",
+			FromPort: 80,
+			ToPort:   80,
+			IPRanges: []ec2.IPRange{{CIDR: "0.0.0.0/0"}},
+		}},
+	}
+	data, err := json.Marshal(info)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Matches, `\{"id":"sg-67ad940e","name":"websrv","ownerId":"999988887777",.*`)
+	c.Assert(string(data), check.Matches, `.*"ipPerms":\[\{"protocol":"tcp","fromPort":80,"toPort":80,"ipRanges":\[\{"cidr":"0.0.0.0/0"\}\].*`)
+}
+
+func (s *S) TestAssignPrivateIpAddresses(c *check.C) {
+	testServer.Response(200, nil, AssignPrivateIpAddressesExample)
+
+	resp, err := s.ec2.AssignPrivateIpAddresses("eni-d83388b1", []string{"10.0.2.1", "10.0.2.11"}, 0, true)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AssignPrivateIpAddresses"})
+	c.Assert(req.Form["NetworkInterfaceId"], check.DeepEquals, []string{"eni-d83388b1"})
+	c.Assert(req.Form["PrivateIpAddress.1"], check.DeepEquals, []string{"10.0.2.1"})
+	c.Assert(req.Form["PrivateIpAddress.2"], check.DeepEquals, []string{"10.0.2.11"})
+	c.Assert(req.Form["SecondaryPrivateIpAddressCount"], check.IsNil)
+	c.Assert(req.Form["AllowReassignment"], check.DeepEquals, []string{"true"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestAssignPrivateIpAddressesCount(c *check.C) {
+	testServer.Response(200, nil, AssignPrivateIpAddressesExample)
+
+	_, err := s.ec2.AssignPrivateIpAddresses("eni-d83388b1", nil, 2, false)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["PrivateIpAddress.1"], check.IsNil)
+	c.Assert(req.Form["SecondaryPrivateIpAddressCount"], check.DeepEquals, []string{"2"})
+	c.Assert(req.Form["AllowReassignment"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestUnassignPrivateIpAddresses(c *check.C) {
+	testServer.Response(200, nil, UnassignPrivateIpAddressesExample)
+
+	resp, err := s.ec2.UnassignPrivateIpAddresses("eni-197d9972", []string{"10.0.0.82"})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"UnassignPrivateIpAddresses"})
+	c.Assert(req.Form["NetworkInterfaceId"], check.DeepEquals, []string{"eni-197d9972"})
+	c.Assert(req.Form["PrivateIpAddress.1"], check.DeepEquals, []string{"10.0.0.82"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestCopyImage(c *check.C) {
+	testServer.Response(200, nil, CopyImageExample)
+
+	resp, err := s.ec2.CopyImage(aws.USWest2, "ami-1a2b3c4d", "My AMI", "copy")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CopyImage"})
+	c.Assert(req.Form["SourceRegion"], check.DeepEquals, []string{"us-west-2"})
+	c.Assert(req.Form["SourceImageId"], check.DeepEquals, []string{"ami-1a2b3c4d"})
+	c.Assert(req.Form["Name"], check.DeepEquals, []string{"My AMI"})
+	c.Assert(req.Form["Description"], check.DeepEquals, []string{"copy"})
+	c.Assert(req.Form["ClientToken"][0], check.Matches, "[0-9a-f]{64}")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.ImageId, check.Equals, "ami-4d3c2b1a")
+}
+
+func (s *S) TestCopyImageWithOptions(c *check.C) {
+	testServer.Response(200, nil, CopyImageExample)
+
+	_, err := s.ec2.CopyImageWithOptions(&ec2.CopyImageOptions{
+		SourceRegion:  aws.USWest2,
+		SourceImageId: "ami-1a2b3c4d",
+		Name:          "My AMI",
+		ClientToken:   "my-token",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["ClientToken"], check.DeepEquals, []string{"my-token"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestCreateNatGateway(c *check.C) {
+	testServer.Response(200, nil, CreateNatGatewayExample)
+
+	resp, err := s.ec2.CreateNatGateway("subnet-1a2b3c4d", "eipalloc-37fc1a52")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateNatGateway"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["SubnetId"], check.DeepEquals, []string{"subnet-1a2b3c4d"})
+	c.Assert(req.Form["AllocationId"], check.DeepEquals, []string{"eipalloc-37fc1a52"})
+	c.Assert(req.Form["ClientToken"][0], check.Matches, "[0-9a-f]{64}")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "1b74dc5c-bcda-403f-867d-example")
+	c.Assert(resp.ClientToken, check.Equals, "c70b1e2e-5a8f-4c31-8c4e-example")
+	g := resp.NatGateway
+	c.Assert(g.NatGatewayId, check.Equals, "nat-04e77a5e9c34432f9")
+	c.Assert(g.SubnetId, check.Equals, "subnet-1a2b3c4d")
+	c.Assert(g.VpcId, check.Equals, "vpc-4e20d42b")
+	c.Assert(g.State, check.Equals, "pending")
+	c.Assert(g.CreateTime, check.Equals, "2015-11-25T14:00:55.416Z")
+	c.Assert(g.NatGatewayAddresses, check.DeepEquals, []ec2.NatGatewayAddress{{AllocationId: "eipalloc-37fc1a52"}})
+}
+
+func (s *S) TestCreateNatGatewayWithOptions(c *check.C) {
+	testServer.Response(200, nil, CreateNatGatewayExample)
+
+	_, err := s.ec2.CreateNatGatewayWithOptions(&ec2.CreateNatGatewayOptions{
+		SubnetId:     "subnet-1a2b3c4d",
+		AllocationId: "eipalloc-37fc1a52",
+		ClientToken:  "c70b1e2e-5a8f-4c31-8c4e-example",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["SubnetId"], check.DeepEquals, []string{"subnet-1a2b3c4d"})
+	c.Assert(req.Form["AllocationId"], check.DeepEquals, []string{"eipalloc-37fc1a52"})
+	c.Assert(req.Form["ClientToken"], check.DeepEquals, []string{"c70b1e2e-5a8f-4c31-8c4e-example"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestDeleteNatGateway(c *check.C) {
+	testServer.Response(200, nil, DeleteNatGatewayExample)
+
+	resp, err := s.ec2.DeleteNatGateway("nat-04ae55e711cec5680")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteNatGateway"})
+	c.Assert(req.Form["NatGatewayId"], check.DeepEquals, []string{"nat-04ae55e711cec5680"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "741fc8ab-6ebe-452b-b92b-example")
+	c.Assert(resp.NatGatewayId, check.Equals, "nat-04ae55e711cec5680")
+}
+
+func (s *S) TestNatGateways(c *check.C) {
+	testServer.Response(200, nil, DescribeNatGatewaysExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("vpc-id", "vpc-1a2b3c4d")
+	resp, err := s.ec2.NatGateways([]string{"nat-04e77a5e9c34432f9", "nat-0a1b2c3d4e5f60718"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeNatGateways"})
+	c.Assert(req.Form["NatGatewayId.1"], check.DeepEquals, []string{"nat-04e77a5e9c34432f9"})
+	c.Assert(req.Form["NatGatewayId.2"], check.DeepEquals, []string{"nat-0a1b2c3d4e5f60718"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"vpc-id"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"vpc-1a2b3c4d"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "bfed02c6-dae9-47c0-86a2-example")
+	c.Assert(resp.NatGateways, check.HasLen, 2)
+
+	g0 := resp.NatGateways[0]
+	c.Assert(g0.NatGatewayId, check.Equals, "nat-04e77a5e9c34432f9")
+	c.Assert(g0.State, check.Equals, "available")
+	c.Assert(g0.SubnetId, check.Equals, "subnet-5d164032")
+	c.Assert(g0.VpcId, check.Equals, "vpc-1a2b3c4d")
+	c.Assert(g0.NatGatewayAddresses, check.DeepEquals, []ec2.NatGatewayAddress{{
+		AllocationId:       "eipalloc-ec4c9a88",
+		NetworkInterfaceId: "eni-b9e30bd7",
+		PrivateIp:          "10.0.0.190",
+		PublicIp:           "52.0.2.47",
+	}})
+	c.Assert(g0.Tags, check.DeepEquals, []ec2.Tag{{Key: "Name", Value: "private-nat"}})
+
+	g1 := resp.NatGateways[1]
+	c.Assert(g1.State, check.Equals, "failed")
+	c.Assert(g1.DeleteTime, check.Equals, "2015-11-26T09:14:02.000Z")
+	c.Assert(g1.FailureCode, check.Equals, "InvalidAllocationID.NotFound")
+	c.Assert(g1.FailureMessage, check.Matches, "Elastic IP address .* could not be associated.*")
+}
+
+func (s *S) TestCreateDhcpOptions(c *check.C) {
+	testServer.Response(200, nil, CreateDhcpOptionsExample)
+
+	resp, err := s.ec2.CreateDhcpOptions([]ec2.DhcpConfig{
+		{Key: "domain-name", Values: []string{"example.com"}},
+		{Key: "domain-name-servers", Values: []string{"10.2.5.1", "10.2.5.2"}},
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateDhcpOptions"})
+	c.Assert(req.Form["DhcpConfiguration.1.Key"], check.DeepEquals, []string{"domain-name"})
+	c.Assert(req.Form["DhcpConfiguration.1.Value.1"], check.DeepEquals, []string{"example.com"})
+	c.Assert(req.Form["DhcpConfiguration.2.Key"], check.DeepEquals, []string{"domain-name-servers"})
+	c.Assert(req.Form["DhcpConfiguration.2.Value.1"], check.DeepEquals, []string{"10.2.5.1"})
+	c.Assert(req.Form["DhcpConfiguration.2.Value.2"], check.DeepEquals, []string{"10.2.5.2"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+	c.Assert(resp.DhcpOptions.DhcpOptionsId, check.Equals, "dopt-7a8b9c2d")
+	c.Assert(resp.DhcpOptions.Configurations, check.DeepEquals, []ec2.DhcpConfig{
+		{Key: "domain-name", Values: []string{"example.com"}},
+		{Key: "domain-name-servers", Values: []string{"10.2.5.1", "10.2.5.2"}},
+	})
+}
+
+func (s *S) TestDeleteDhcpOptions(c *check.C) {
+	testServer.Response(200, nil, DeleteDhcpOptionsExample)
+
+	resp, err := s.ec2.DeleteDhcpOptions("dopt-7a8b9c2d")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteDhcpOptions"})
+	c.Assert(req.Form["DhcpOptionsId"], check.DeepEquals, []string{"dopt-7a8b9c2d"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+}
+
+func (s *S) TestAssociateDhcpOptions(c *check.C) {
+	testServer.Response(200, nil, AssociateDhcpOptionsExample)
+
+	resp, err := s.ec2.AssociateDhcpOptions("dopt-7a8b9c2d", "vpc-1a2b3c4d")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AssociateDhcpOptions"})
+	c.Assert(req.Form["DhcpOptionsId"], check.DeepEquals, []string{"dopt-7a8b9c2d"})
+	c.Assert(req.Form["VpcId"], check.DeepEquals, []string{"vpc-1a2b3c4d"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+}
+
+func (s *S) TestDhcpOptions(c *check.C) {
+	testServer.Response(200, nil, DescribeDhcpOptionsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key", "domain-name")
+	resp, err := s.ec2.DhcpOptions([]string{"dopt-7a8b9c2d"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeDhcpOptions"})
+	c.Assert(req.Form["DhcpOptionsId.1"], check.DeepEquals, []string{"dopt-7a8b9c2d"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"domain-name"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.DhcpOptions, check.HasLen, 1)
+	o := resp.DhcpOptions[0]
+	c.Assert(o.DhcpOptionsId, check.Equals, "dopt-7a8b9c2d")
+	c.Assert(o.OwnerId, check.Equals, "123456789012")
+	c.Assert(o.Configurations, check.HasLen, 2)
+	c.Assert(o.Configurations[1].Key, check.Equals, "domain-name-servers")
+	c.Assert(o.Configurations[1].Values, check.DeepEquals, []string{"10.2.5.1", "10.2.5.2"})
+	c.Assert(o.Tags, check.DeepEquals, []ec2.Tag{{Key: "Name", Value: "internal-dns"}})
+}
+
+func (s *S) TestCreateNetworkAcl(c *check.C) {
+	testServer.Response(200, nil, CreateNetworkAclExample)
+
+	resp, err := s.ec2.CreateNetworkAcl("vpc-11ad4878")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateNetworkAcl"})
+	c.Assert(req.Form["VpcId"], check.DeepEquals, []string{"vpc-11ad4878"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.NetworkAcl.NetworkAclId, check.Equals, "acl-5fb85d36")
+	c.Assert(resp.NetworkAcl.VpcId, check.Equals, "vpc-11ad4878")
+	c.Assert(resp.NetworkAcl.Default, check.Equals, false)
+	c.Assert(resp.NetworkAcl.Entries, check.HasLen, 2)
+	c.Assert(resp.NetworkAcl.Entries[0].Egress, check.Equals, true)
+	c.Assert(resp.NetworkAcl.Entries[0].RuleAction, check.Equals, "deny")
+}
+
+func (s *S) TestDeleteNetworkAcl(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	_, err := s.ec2.DeleteNetworkAcl("acl-2cb85d45")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteNetworkAcl"})
+	c.Assert(req.Form["NetworkAclId"], check.DeepEquals, []string{"acl-2cb85d45"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestNetworkAcls(c *check.C) {
+	testServer.Response(200, nil, DescribeNetworkAclsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("vpc-id", "vpc-5266953b")
+	resp, err := s.ec2.NetworkAcls([]string{"acl-5d659634"}, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeNetworkAcls"})
+	c.Assert(req.Form["NetworkAclId.1"], check.DeepEquals, []string{"acl-5d659634"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"vpc-id"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.NetworkAcls, check.HasLen, 1)
+	acl := resp.NetworkAcls[0]
+	c.Assert(acl.NetworkAclId, check.Equals, "acl-5d659634")
+	c.Assert(acl.Entries, check.DeepEquals, []ec2.NetworkAclEntry{
+		{RuleNumber: 110, Protocol: "6", RuleAction: "allow", Egress: true, CidrBlock: "0.0.0.0/0", FromPort: 49152, ToPort: 65535},
+		{RuleNumber: 120, Protocol: "1", RuleAction: "allow", CidrBlock: "10.0.0.0/16", IcmpType: 8, IcmpCode: -1},
+		{RuleNumber: 32767, Protocol: "-1", RuleAction: "deny", CidrBlock: "0.0.0.0/0"},
+	})
+	c.Assert(acl.Associations, check.DeepEquals, []ec2.NetworkAclAssociation{{
+		NetworkAclAssociationId: "aclassoc-5c659635",
+		NetworkAclId:            "acl-5d659634",
+		SubnetId:                "subnet-ff669596",
+	}})
+}
+
+func (s *S) TestCreateNetworkAclEntry(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	_, err := s.ec2.CreateNetworkAclEntry("acl-2cb85d45", &ec2.NetworkAclEntry{
+		RuleNumber: 100,
+		Protocol:   "6",
+		RuleAction: "allow",
+		CidrBlock:  "0.0.0.0/0",
+		FromPort:   443,
+		ToPort:     443,
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateNetworkAclEntry"})
+	c.Assert(req.Form["NetworkAclId"], check.DeepEquals, []string{"acl-2cb85d45"})
+	c.Assert(req.Form["RuleNumber"], check.DeepEquals, []string{"100"})
+	c.Assert(req.Form["Protocol"], check.DeepEquals, []string{"6"})
+	c.Assert(req.Form["RuleAction"], check.DeepEquals, []string{"allow"})
+	c.Assert(req.Form["Egress"], check.DeepEquals, []string{"false"})
+	c.Assert(req.Form["CidrBlock"], check.DeepEquals, []string{"0.0.0.0/0"})
+	c.Assert(req.Form["PortRange.From"], check.DeepEquals, []string{"443"})
+	c.Assert(req.Form["PortRange.To"], check.DeepEquals, []string{"443"})
+	c.Assert(req.Form["Icmp.Type"], check.IsNil)
+	c.Assert(err, check.IsNil)
+
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	_, err = s.ec2.CreateNetworkAclEntry("acl-2cb85d45", &ec2.NetworkAclEntry{
+		RuleNumber: 110,
+		Protocol:   "1",
+		RuleAction: "deny",
+		Egress:     true,
+		CidrBlock:  "10.0.0.0/8",
+		IcmpType:   -1,
+		IcmpCode:   -1,
+	})
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["Egress"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["Icmp.Type"], check.DeepEquals, []string{"-1"})
+	c.Assert(req.Form["Icmp.Code"], check.DeepEquals, []string{"-1"})
+	c.Assert(req.Form["PortRange.From"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestCreateNetworkAclEntryProtocolName(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	_, err := s.ec2.CreateNetworkAclEntry("acl-2cb85d45", &ec2.NetworkAclEntry{
+		RuleNumber: 100,
+		Protocol:   "TCP",
+		RuleAction: "allow",
+		CidrBlock:  "0.0.0.0/0",
+		FromPort:   443,
+		ToPort:     443,
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Protocol"], check.DeepEquals, []string{"6"})
+	c.Assert(req.Form["PortRange.From"], check.DeepEquals, []string{"443"})
+	c.Assert(req.Form["PortRange.To"], check.DeepEquals, []string{"443"})
+	c.Assert(err, check.IsNil)
+
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	_, err = s.ec2.CreateNetworkAclEntry("acl-2cb85d45", &ec2.NetworkAclEntry{
+		RuleNumber: 110,
+		Protocol:   ec2.ProtocolICMP,
+		RuleAction: "deny",
+		CidrBlock:  "10.0.0.0/8",
+		IcmpType:   8,
+		IcmpCode:   -1,
+	})
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["Protocol"], check.DeepEquals, []string{"1"})
+	c.Assert(req.Form["Icmp.Type"], check.DeepEquals, []string{"8"})
+	c.Assert(req.Form["Icmp.Code"], check.DeepEquals, []string{"-1"})
+	c.Assert(err, check.IsNil)
+
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	_, err = s.ec2.CreateNetworkAclEntry("acl-2cb85d45", &ec2.NetworkAclEntry{
+		RuleNumber: 120,
+		Protocol:   "ICMPv6",
+		RuleAction: "allow",
+		CidrBlock:  "10.0.0.0/8",
+		IcmpType:   128,
+		IcmpCode:   0,
+	})
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["Protocol"], check.DeepEquals, []string{"58"})
+	c.Assert(req.Form["Icmp.Type"], check.DeepEquals, []string{"128"})
+	c.Assert(req.Form["Icmp.Code"], check.DeepEquals, []string{"0"})
+	c.Assert(req.Form["PortRange.From"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestCreateNetworkAclEntryInvalidProtocol(c *check.C) {
+	resp, err := s.ec2.CreateNetworkAclEntry("acl-2cb85d45", &ec2.NetworkAclEntry{
+		RuleNumber: 100,
+		Protocol:   "sctp",
+		RuleAction: "allow",
+		CidrBlock:  "0.0.0.0/0",
+	})
+
+	c.Assert(err, check.ErrorMatches, `invalid IP protocol "sctp"`)
+	c.Assert(resp, check.IsNil)
+}
+
+func (s *S) TestDeleteNetworkAclEntry(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	_, err := s.ec2.DeleteNetworkAclEntry("acl-2cb85d45", 100, true)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteNetworkAclEntry"})
+	c.Assert(req.Form["NetworkAclId"], check.DeepEquals, []string{"acl-2cb85d45"})
+	c.Assert(req.Form["RuleNumber"], check.DeepEquals, []string{"100"})
+	c.Assert(req.Form["Egress"], check.DeepEquals, []string{"true"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestReplaceNetworkAclAssociation(c *check.C) {
+	testServer.Response(200, nil, ReplaceNetworkAclAssociationExample)
+
+	resp, err := s.ec2.ReplaceNetworkAclAssociation("aclassoc-e5b95c8c", "acl-5fb85d36")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ReplaceNetworkAclAssociation"})
+	c.Assert(req.Form["AssociationId"], check.DeepEquals, []string{"aclassoc-e5b95c8c"})
+	c.Assert(req.Form["NetworkAclId"], check.DeepEquals, []string{"acl-5fb85d36"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.NewAssociationId, check.Equals, "aclassoc-17b85d7e")
+}
+
+func (s *S) TestCreateVpcPeeringConnection(c *check.C) {
+	testServer.Response(200, nil, CreateVpcPeeringConnectionExample)
+
+	resp, err := s.ec2.CreateVpcPeeringConnection("vpc-1a2b3c4d", "vpc-a1b2c3d4", "123456789012", "us-west-2")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateVpcPeeringConnection"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["VpcId"], check.DeepEquals, []string{"vpc-1a2b3c4d"})
+	c.Assert(req.Form["PeerVpcId"], check.DeepEquals, []string{"vpc-a1b2c3d4"})
+	c.Assert(req.Form["PeerOwnerId"], check.DeepEquals, []string{"123456789012"})
+	c.Assert(req.Form["PeerRegion"], check.DeepEquals, []string{"us-west-2"})
+
+	c.Assert(err, check.IsNil)
+	pcx := resp.VpcPeeringConnection
+	c.Assert(pcx.VpcPeeringConnectionId, check.Equals, "pcx-73a5401a")
+	c.Assert(pcx.RequesterVpcInfo, check.DeepEquals, ec2.VpcPeeringConnectionVpcInfo{
+		VpcId:     "vpc-1a2b3c4d",
+		OwnerId:   "777788889999",
+		CidrBlock: "10.0.0.0/28",
+		Region:    "us-east-1",
+	})
+	c.Assert(pcx.AccepterVpcInfo.OwnerId, check.Equals, "123456789012")
+	c.Assert(pcx.AccepterVpcInfo.Region, check.Equals, "us-west-2")
+	c.Assert(pcx.StatusCode, check.Equals, "initiating-request")
+	c.Assert(pcx.StatusMessage, check.Equals, "Initiating Request to 123456789012")
+	c.Assert(pcx.ExpirationTime, check.Equals, "2014-02-18T14:37:25.000Z")
+}
+
+func (s *S) TestCreateVpcPeeringConnectionSameAccount(c *check.C) {
+	testServer.Response(200, nil, CreateVpcPeeringConnectionExample)
+
+	_, err := s.ec2.CreateVpcPeeringConnection("vpc-1a2b3c4d", "vpc-a1b2c3d4", "", "")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["PeerOwnerId"], check.IsNil)
+	c.Assert(req.Form["PeerRegion"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestAcceptVpcPeeringConnection(c *check.C) {
+	testServer.Response(200, nil, AcceptVpcPeeringConnectionExample)
+
+	resp, err := s.ec2.AcceptVpcPeeringConnection("pcx-1a2b3c4d")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AcceptVpcPeeringConnection"})
+	c.Assert(req.Form["VpcPeeringConnectionId"], check.DeepEquals, []string{"pcx-1a2b3c4d"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.VpcPeeringConnection.StatusCode, check.Equals, "active")
+	c.Assert(resp.VpcPeeringConnection.AccepterVpcInfo.VpcId, check.Equals, "vpc-111aaa22")
+}
+
+func (s *S) TestDeleteVpcPeeringConnection(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	_, err := s.ec2.DeleteVpcPeeringConnection("pcx-1a2b3c4d")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteVpcPeeringConnection"})
+	c.Assert(req.Form["VpcPeeringConnectionId"], check.DeepEquals, []string{"pcx-1a2b3c4d"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestVpcPeeringConnections(c *check.C) {
+	testServer.Response(200, nil, DescribeVpcPeeringConnectionsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("status-code", "pending-acceptance")
+	resp, err := s.ec2.VpcPeeringConnections(nil, filter)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpcPeeringConnections"})
+	c.Assert(req.Form["VpcPeeringConnectionId.1"], check.IsNil)
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"status-code"})
+	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"pending-acceptance"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.VpcPeeringConnections, check.HasLen, 1)
+	pcx := resp.VpcPeeringConnections[0]
+	c.Assert(pcx.VpcPeeringConnectionId, check.Equals, "pcx-111aaa22")
+	c.Assert(pcx.RequesterVpcInfo.CidrBlock, check.Equals, "172.31.0.0/16")
+	c.Assert(pcx.AccepterVpcInfo.VpcId, check.Equals, "vpc-aa22cc33")
+	c.Assert(pcx.StatusCode, check.Equals, "pending-acceptance")
+	c.Assert(pcx.Tags, check.DeepEquals, []ec2.Tag{{Key: "Name", Value: "shared-services"}})
+}
+
+func (s *S) TestCreateVpcEndpointGateway(c *check.C) {
+	testServer.Response(200, nil, CreateVpcEndpointExample)
+
+	resp, err := s.ec2.CreateVpcEndpoint(&ec2.CreateVpcEndpointOptions{
+		VpcId:         "vpc-1a2b3c4d",
+		ServiceName:   "com.amazonaws.us-east-1.s3",
+		RouteTableIds: []string{"rtb-11aa22bb"},
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateVpcEndpoint"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["VpcId"], check.DeepEquals, []string{"vpc-1a2b3c4d"})
+	c.Assert(req.Form["ServiceName"], check.DeepEquals, []string{"com.amazonaws.us-east-1.s3"})
+	c.Assert(req.Form["RouteTableId.1"], check.DeepEquals, []string{"rtb-11aa22bb"})
+	c.Assert(req.Form["VpcEndpointType"], check.IsNil)
+	c.Assert(req.Form["SubnetId.1"], check.IsNil)
+	c.Assert(req.Form["PrivateDnsEnabled"], check.IsNil)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "4bc7d8f9-1f1c-4fb2-bd53-2d8ba70ad64c")
+	e := resp.VpcEndpoint
+	c.Assert(e.VpcEndpointId, check.Equals, "vpce-abc12345")
+	c.Assert(e.VpcEndpointType, check.Equals, "Gateway")
+	c.Assert(e.State, check.Equals, "available")
+	c.Assert(e.RouteTableIds, check.DeepEquals, []string{"rtb-11aa22bb"})
+	c.Assert(e.PolicyDocument, check.Matches, `\{"Version":"2008-10-17".*`)
+}
+
+func (s *S) TestCreateVpcEndpointInterface(c *check.C) {
+	testServer.Response(200, nil, CreateVpcEndpointExample)
+
+	_, err := s.ec2.CreateVpcEndpoint(&ec2.CreateVpcEndpointOptions{
+		VpcId:             "vpc-1a2b3c4d",
+		ServiceName:       "com.amazonaws.us-east-1.ec2",
+		VpcEndpointType:   "Interface",
+		SubnetIds:         []string{"subnet-d6fcaa8d", "subnet-e7fdbb9e"},
+		SecurityGroupIds:  []string{"sg-54e8bf31"},
+		PrivateDnsEnabled: true,
+		ClientToken:       "token",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["VpcEndpointType"], check.DeepEquals, []string{"Interface"})
+	c.Assert(req.Form["SubnetId.1"], check.DeepEquals, []string{"subnet-d6fcaa8d"})
+	c.Assert(req.Form["SubnetId.2"], check.DeepEquals, []string{"subnet-e7fdbb9e"})
+	c.Assert(req.Form["SecurityGroupId.1"], check.DeepEquals, []string{"sg-54e8bf31"})
+	c.Assert(req.Form["PrivateDnsEnabled"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["ClientToken"], check.DeepEquals, []string{"token"})
+	c.Assert(req.Form["RouteTableId.1"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestCreateVpcEndpointGeneratedClientToken(c *check.C) {
+	testServer.Response(200, nil, CreateVpcEndpointExample)
+
+	resp, err := s.ec2.CreateVpcEndpoint(&ec2.CreateVpcEndpointOptions{
+		VpcId:       "vpc-1a2b3c4d",
+		ServiceName: "com.amazonaws.us-east-1.s3",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(err, check.IsNil)
+	c.Assert(req.Form["ClientToken"][0], check.Matches, "[0-9a-f]{64}")
+	c.Assert(resp.ClientToken, check.Equals, req.Form["ClientToken"][0])
+}
 
-	s0 := resp.Subnets[0]
-	c.Assert(s0.Id, check.Equals, "subnet-3e993755")
-	c.Assert(s0.State, check.Equals, "available")
-	c.Assert(s0.VpcId, check.Equals, "vpc-f84a9b93")
-	c.Assert(s0.CidrBlock, check.Equals, "10.0.12.0/24")
-	c.Assert(s0.AvailableIpAddressCount, check.Equals, 249)
-	c.Assert(s0.AvailabilityZone, check.Equals, "us-west-2c")
-	c.Assert(s0.DefaultForAz, check.Equals, false)
-	c.Assert(s0.MapPublicIpOnLaunch, check.Equals, false)
+func (s *S) TestCreateVpcEndpointFailure(c *check.C) {
+	testServer.Response(500, nil, "")
 
-	c.Assert(s0.Tags, check.HasLen, 2)
-	c.Assert(s0.Tags[0].Key, check.Equals, "visibility")
-	c.Assert(s0.Tags[0].Value, check.Equals, "private")
-	c.Assert(s0.Tags[1].Key, check.Equals, "Name")
-	c.Assert(s0.Tags[1].Value, check.Equals, "application")
+	resp, err := s.ec2.CreateVpcEndpoint(&ec2.CreateVpcEndpointOptions{
+		VpcId:       "vpc-1a2b3c4d",
+		ServiceName: "com.amazonaws.us-east-1.s3",
+	})
+
+	testServer.WaitRequest()
+	c.Assert(err, check.ErrorMatches, "500 Internal Server Error")
+	c.Assert(resp, check.IsNil)
 }
 
-func (s *S) TestCreateSecurityGroupExample(c *check.C) {
-	testServer.Response(200, nil, CreateSecurityGroupExample)
+func (s *S) TestDeleteVpcEndpoints(c *check.C) {
+	testServer.Response(200, nil, DeleteVpcEndpointsExample)
 
-	resp, err := s.ec2.CreateSecurityGroup("websrv", "Web Servers")
+	resp, err := s.ec2.DeleteVpcEndpoints([]string{"vpce-aaa11111", "vpce-bbb22222"})
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateSecurityGroup"})
-	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
-	c.Assert(req.Form["GroupDescription"], check.DeepEquals, []string{"Web Servers"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteVpcEndpoints"})
+	c.Assert(req.Form["VpcEndpointId.1"], check.DeepEquals, []string{"vpce-aaa11111"})
+	c.Assert(req.Form["VpcEndpointId.2"], check.DeepEquals, []string{"vpce-bbb22222"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Name, check.Equals, "websrv")
-	c.Assert(resp.Id, check.Equals, "sg-67ad940e")
+	c.Assert(resp.Unsuccessful, check.DeepEquals, []ec2.UnsuccessfulItem{{
+		ResourceId: "vpce-bbb22222",
+		Code:       "InvalidVpcEndpoint.NotFound",
+		Message:    "The vpcEndpoint ID 'vpce-bbb22222' does not exist",
+	}})
 }
 
-func (s *S) TestDescribeSecurityGroupsExample(c *check.C) {
-	testServer.Response(200, nil, DescribeSecurityGroupsExample)
+func (s *S) TestVpcEndpoints(c *check.C) {
+	testServer.Response(200, nil, DescribeVpcEndpointsExample)
 
-	resp, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{{Name: "WebServers"}, {Name: "RangedPortsBySource"}}, nil)
+	filter := ec2.NewFilter()
+	filter.Add("vpc-id", "vpc-1a2b3c4d")
+	resp, err := s.ec2.VpcEndpoints(nil, filter)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSecurityGroups"})
-	c.Assert(req.Form["GroupName.1"], check.DeepEquals, []string{"WebServers"})
-	c.Assert(req.Form["GroupName.2"], check.DeepEquals, []string{"RangedPortsBySource"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpcEndpoints"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"vpc-id"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Groups, check.HasLen, 2)
+	c.Assert(resp.RequestId, check.Equals, "19a7b6cd-be42-4b08-b7a3-example")
+	c.Assert(resp.VpcEndpoints, check.HasLen, 2)
+	c.Assert(resp.VpcEndpoints[0].RouteTableIds, check.DeepEquals, []string{"rtb-123abc12", "rtb-abc123ab"})
+
+	e := resp.VpcEndpoints[1]
+	c.Assert(e.VpcEndpointId, check.Equals, "vpce-0f89a33420c1931d7")
+	c.Assert(e.VpcEndpointType, check.Equals, "Interface")
+	c.Assert(e.SubnetIds, check.DeepEquals, []string{"subnet-d6fcaa8d"})
+	c.Assert(e.Groups, check.DeepEquals, []ec2.SecurityGroup{{Id: "sg-54e8bf31", Name: "default"}})
+	c.Assert(e.NetworkInterfaceIds, check.DeepEquals, []string{"eni-2ec2b084"})
+	c.Assert(e.DnsEntries, check.DeepEquals, []ec2.VpcEndpointDns{{
+		DnsName:      "vpce-0f89a33420c1931d7-bluzidnv.ec2.us-east-1.vpce.amazonaws.com",
+		HostedZoneId: "Z7HUB22UULQXV",
+	}})
+	c.Assert(e.PrivateDnsEnabled, check.Equals, true)
+}
 
-	g0 := resp.Groups[0]
-	c.Assert(g0.OwnerId, check.Equals, "999988887777")
-	c.Assert(g0.Name, check.Equals, "WebServers")
-	c.Assert(g0.Id, check.Equals, "sg-67ad940e")
-	c.Assert(g0.Description, check.Equals, "Web Servers")
-	c.Assert(g0.IPPerms, check.HasLen, 1)
+func (s *S) TestModifyVpcEndpoint(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
 
-	g0ipp := g0.IPPerms[0]
-	c.Assert(g0ipp.Protocol, check.Equals, "tcp")
-	c.Assert(g0ipp.FromPort, check.Equals, 80)
-	c.Assert(g0ipp.ToPort, check.Equals, 80)
-	c.Assert(g0ipp.SourceIPs, check.DeepEquals, []string{"0.0.0.0/0"})
+	_, err := s.ec2.ModifyVpcEndpoint("vpce-1a2b3c4d", &ec2.ModifyVpcEndpointOptions{
+		AddRouteTableIds:    []string{"rtb-11111111", "rtb-22222222"},
+		RemoveRouteTableIds: []string{"rtb-33333333"},
+		ResetPolicy:         true,
+	})
 
-	g1 := resp.Groups[1]
-	c.Assert(g1.OwnerId, check.Equals, "999988887777")
-	c.Assert(g1.Name, check.Equals, "RangedPortsBySource")
-	c.Assert(g1.Id, check.Equals, "sg-76abc467")
-	c.Assert(g1.Description, check.Equals, "Group A")
-	c.Assert(g1.IPPerms, check.HasLen, 1)
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ModifyVpcEndpoint"})
+	c.Assert(req.Form["VpcEndpointId"], check.DeepEquals, []string{"vpce-1a2b3c4d"})
+	c.Assert(req.Form["AddRouteTableId.1"], check.DeepEquals, []string{"rtb-11111111"})
+	c.Assert(req.Form["AddRouteTableId.2"], check.DeepEquals, []string{"rtb-22222222"})
+	c.Assert(req.Form["RemoveRouteTableId.1"], check.DeepEquals, []string{"rtb-33333333"})
+	c.Assert(req.Form["ResetPolicy"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["PolicyDocument"], check.IsNil)
+	c.Assert(req.Form["PrivateDnsEnabled"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
 
-	g1ipp := g1.IPPerms[0]
-	c.Assert(g1ipp.Protocol, check.Equals, "tcp")
-	c.Assert(g1ipp.FromPort, check.Equals, 6000)
-	c.Assert(g1ipp.ToPort, check.Equals, 7000)
-	c.Assert(g1ipp.SourceIPs, check.IsNil)
+func (s *S) TestModifyInterfaceVpcEndpoint(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
+
+	disabled := false
+	_, err := s.ec2.ModifyVpcEndpoint("vpce-1a2b3c4d", &ec2.ModifyVpcEndpointOptions{
+		AddSubnetIds:           []string{"subnet-11111111"},
+		RemoveSubnetIds:        []string{"subnet-22222222"},
+		AddSecurityGroupIds:    []string{"sg-11111111"},
+		RemoveSecurityGroupIds: []string{"sg-22222222"},
+		PrivateDnsEnabled:      &disabled,
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["AddSubnetId.1"], check.DeepEquals, []string{"subnet-11111111"})
+	c.Assert(req.Form["RemoveSubnetId.1"], check.DeepEquals, []string{"subnet-22222222"})
+	c.Assert(req.Form["AddSecurityGroupId.1"], check.DeepEquals, []string{"sg-11111111"})
+	c.Assert(req.Form["RemoveSecurityGroupId.1"], check.DeepEquals, []string{"sg-22222222"})
+	c.Assert(req.Form["PrivateDnsEnabled"], check.DeepEquals, []string{"false"})
+	c.Assert(err, check.IsNil)
 }
 
-func (s *S) TestDescribeSecurityGroups(c *check.C) {
-	testServer.Response(200, nil, SecurityGroupsVPCExample)
+func (s *S) TestInstanceTypeInfo(c *check.C) {
+	testServer.Response(200, nil, DescribeInstanceTypesExample)
+	testServer.Response(200, nil, DescribeInstanceTypesPage2Example)
 
-	expected := ec2.SecurityGroupsResp{
-		RequestId: "59dbff89-35bd-4eac-99ed-be587EXAMPLE",
-		Groups: []ec2.SecurityGroupInfo{
-			ec2.SecurityGroupInfo{
-				SecurityGroup: ec2.SecurityGroup{
-					Id:   "sg-67ad940e",
-					Name: "WebServers",
-				},
-				OwnerId:     "999988887777",
-				Description: "Web Servers",
-				IPPerms: []ec2.IPPerm{
-					ec2.IPPerm{
-						Protocol:     "tcp",
-						FromPort:     80,
-						ToPort:       80,
-						SourceIPs:    []string{"0.0.0.0/0"},
-						SourceGroups: nil,
-					},
-				},
-				IPPermsEgress: []ec2.IPPerm{
-					ec2.IPPerm{
-						Protocol:     "tcp",
-						FromPort:     22,
-						ToPort:       22,
-						SourceIPs:    []string{"10.0.0.0/8"},
-						SourceGroups: nil,
-					},
-				},
-			},
-			ec2.SecurityGroupInfo{
-				SecurityGroup: ec2.SecurityGroup{
-					Id:   "sg-76abc467",
-					Name: "RangedPortsBySource",
-				},
-				OwnerId:     "999988887777",
-				Description: "Group A",
-				IPPerms: []ec2.IPPerm{
-					ec2.IPPerm{
-						Protocol: "tcp",
-						FromPort: 6000,
-						ToPort:   7000,
-					},
-				},
-				VpcId: "vpc-12345678",
-				Tags: []ec2.Tag{
-					ec2.Tag{
-						Key:   "key",
-						Value: "value",
-					},
-				},
-			},
-		},
-	}
+	resp, err := s.ec2.InstanceTypeInfo([]string{"m5.large", "t4g.micro"})
 
-	resp, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{{Name: "WebServers"}, {Name: "RangedPortsBySource"}}, nil)
-	values := testServer.WaitRequest().URL.Query()
-	c.Assert(values.Get("Action"), check.Equals, "DescribeSecurityGroups")
-	c.Assert(values.Get("GroupName.1"), check.Equals, "WebServers")
-	c.Assert(values.Get("GroupName.2"), check.Equals, "RangedPortsBySource")
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["Action"], check.DeepEquals, []string{"DescribeInstanceTypes"})
+	c.Assert(reqs[0].Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(reqs[0].Form["InstanceType.1"], check.DeepEquals, []string{"m5.large"})
+	c.Assert(reqs[0].Form["InstanceType.2"], check.DeepEquals, []string{"t4g.micro"})
+	c.Assert(reqs[0].Form["NextToken"], check.IsNil)
+	c.Assert(reqs[1].Form["NextToken"], check.DeepEquals, []string{"AAEAAT5WE2EXAMPLE"})
+	c.Assert(reqs[1].Form["InstanceType.1"], check.DeepEquals, []string{"m5.large"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(*resp, check.DeepEquals, expected)
-}
+	c.Assert(resp.RequestId, check.Equals, "7c9c6f2b-9b3f-4a4f-8b22-example")
+	c.Assert(resp.InstanceTypes, check.HasLen, 2)
+
+	m5 := resp.InstanceTypes[0]
+	c.Assert(m5.InstanceType, check.Equals, "m5.large")
+	c.Assert(m5.CurrentGeneration, check.Equals, true)
+	c.Assert(m5.VCpuInfo, check.DeepEquals, ec2.VCpuInfo{DefaultVCpus: 2, DefaultCores: 1, DefaultThreadsPerCore: 2})
+	c.Assert(m5.MemoryInfo.SizeInMiB, check.Equals, int64(8192))
+	c.Assert(m5.NetworkInfo, check.DeepEquals, ec2.NetworkInfo{
+		NetworkPerformance:        "Up to 10 Gigabit",
+		MaximumNetworkInterfaces:  3,
+		Ipv4AddressesPerInterface: 10,
+		Ipv6Supported:             true,
+		EnaSupport:                "required",
+	})
+	c.Assert(m5.SupportedArchitectures, check.DeepEquals, []string{"x86_64"})
 
-func (s *S) TestDescribeSecurityGroupsExampleWithFilter(c *check.C) {
-	testServer.Response(200, nil, DescribeSecurityGroupsExample)
+	t4g := resp.InstanceTypes[1]
+	c.Assert(t4g.InstanceType, check.Equals, "t4g.micro")
+	c.Assert(t4g.MemoryInfo.SizeInMiB, check.Equals, int64(1024))
+	c.Assert(t4g.SupportedArchitectures, check.DeepEquals, []string{"arm64"})
+}
 
-	filter := ec2.NewFilter()
-	filter.Add("ip-permission.protocol", "tcp")
-	filter.Add("ip-permission.from-port", "22")
-	filter.Add("ip-permission.to-port", "22")
-	filter.Add("ip-permission.group-name", "app_server_group", "database_group")
+func (s *S) TestImportVolume(c *check.C) {
+	testServer.Response(200, nil, ImportVolumeExample)
 
-	_, err := s.ec2.SecurityGroups(nil, filter)
+	resp, err := s.ec2.ImportVolume(&ec2.ImportVolumeOptions{
+		AvailabilityZone:  "us-east-1c",
+		ImageFormat:       "VMDK",
+		ImageBytes:        1179593728,
+		ImportManifestUrl: "https://s3.amazonaws.com/myawsbucket/disk.vmdkmanifest.xml",
+		VolumeSize:        12,
+	})
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSecurityGroups"})
-	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"ip-permission.from-port"})
-	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"22"})
-	c.Assert(req.Form["Filter.2.Name"], check.DeepEquals, []string{"ip-permission.group-name"})
-	c.Assert(req.Form["Filter.2.Value.1"], check.DeepEquals, []string{"app_server_group"})
-	c.Assert(req.Form["Filter.2.Value.2"], check.DeepEquals, []string{"database_group"})
-	c.Assert(req.Form["Filter.3.Name"], check.DeepEquals, []string{"ip-permission.protocol"})
-	c.Assert(req.Form["Filter.3.Value.1"], check.DeepEquals, []string{"tcp"})
-	c.Assert(req.Form["Filter.4.Name"], check.DeepEquals, []string{"ip-permission.to-port"})
-	c.Assert(req.Form["Filter.4.Value.1"], check.DeepEquals, []string{"22"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ImportVolume"})
+	c.Assert(req.Form["AvailabilityZone"], check.DeepEquals, []string{"us-east-1c"})
+	c.Assert(req.Form["Image.Format"], check.DeepEquals, []string{"VMDK"})
+	c.Assert(req.Form["Image.Bytes"], check.DeepEquals, []string{"1179593728"})
+	c.Assert(req.Form["Image.ImportManifestUrl"], check.DeepEquals, []string{"https://s3.amazonaws.com/myawsbucket/disk.vmdkmanifest.xml"})
+	c.Assert(req.Form["Volume.Size"], check.DeepEquals, []string{"12"})
+	c.Assert(req.Form["Description"], check.IsNil)
 
 	c.Assert(err, check.IsNil)
+	task := resp.ConversionTask
+	c.Assert(task.ConversionTaskId, check.Equals, "import-i-fh95npoc")
+	c.Assert(task.State, check.Equals, "active")
+	c.Assert(task.ImportVolume.AvailabilityZone, check.Equals, "us-east-1c")
+	c.Assert(task.ImportVolume.ImageSize, check.Equals, int64(1179593728))
+	c.Assert(task.ImportVolume.ImportManifestUrl, check.Matches, "https://s3.amazonaws.com/myawsbucket/.*&Expires=1294855591&.*")
+	c.Assert(task.ImportVolume.VolumeSize, check.Equals, 12)
 }
 
-func (s *S) TestDescribeSecurityGroupsDumpWithGroup(c *check.C) {
-	testServer.Response(200, nil, DescribeSecurityGroupsDump)
+func (s *S) TestDescribeConversionTasks(c *check.C) {
+	testServer.Response(200, nil, DescribeConversionTasksExample)
 
-	resp, err := s.ec2.SecurityGroups(nil, nil)
+	resp, err := s.ec2.DescribeConversionTasks([]string{"import-i-fh95npoc"})
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeSecurityGroups"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeConversionTasks"})
+	c.Assert(req.Form["ConversionTaskId.1"], check.DeepEquals, []string{"import-i-fh95npoc"})
+
 	c.Assert(err, check.IsNil)
-	c.Check(resp.Groups, check.HasLen, 1)
-	c.Check(resp.Groups[0].IPPerms, check.HasLen, 2)
+	c.Assert(resp.ConversionTasks, check.HasLen, 1)
+	task := resp.ConversionTasks[0]
+	c.Assert(task.ConversionTaskId, check.Equals, "import-i-fh95npoc")
+	c.Assert(task.ExpirationTime, check.Equals, "2010-12-22T12:01Z")
+	c.Assert(task.State, check.Equals, "active")
+	c.Assert(task.StatusMessage, check.Equals, "Pending")
+	c.Assert(task.ImportVolume, check.DeepEquals, ec2.ImportVolumeTaskDetails{
+		BytesConverted:    1020253184,
+		AvailabilityZone:  "us-east-1c",
+		ImageFormat:       "VDMK",
+		ImageSize:         1179593728,
+		ImportManifestUrl: "https://s3.amazonaws.com/myawsbucket/a3a5e1b6-590d-43cc-97c1-15c7325d3f41/Win_2008_Server_Data_Center_SP2_32-bit.vmdkmanifest.xml",
+		VolumeId:          "vol-1a2b3c4d",
+		VolumeSize:        12,
+	})
+}
 
-	ipp0 := resp.Groups[0].IPPerms[0]
-	c.Assert(ipp0.SourceIPs, check.IsNil)
-	c.Check(ipp0.Protocol, check.Equals, "icmp")
-	c.Assert(ipp0.SourceGroups, check.HasLen, 1)
-	c.Check(ipp0.SourceGroups[0].OwnerId, check.Equals, "12345")
-	c.Check(ipp0.SourceGroups[0].Name, check.Equals, "default")
-	c.Check(ipp0.SourceGroups[0].Id, check.Equals, "sg-67ad940e")
+func (s *S) TestModifyInstanceType(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
 
-	ipp1 := resp.Groups[0].IPPerms[1]
-	c.Check(ipp1.Protocol, check.Equals, "tcp")
-	c.Assert(ipp0.SourceIPs, check.IsNil)
-	c.Assert(ipp0.SourceGroups, check.HasLen, 1)
-	c.Check(ipp1.SourceGroups[0].Id, check.Equals, "sg-76abc467")
-	c.Check(ipp1.SourceGroups[0].OwnerId, check.Equals, "12345")
-	c.Check(ipp1.SourceGroups[0].Name, check.Equals, "other")
+	_, err := s.ec2.ModifyInstanceType("i-1a2b3c4d", "m3.large")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ModifyInstanceAttribute"})
+	c.Assert(req.Form["InstanceId"], check.DeepEquals, []string{"i-1a2b3c4d"})
+	c.Assert(req.Form["InstanceType.Value"], check.DeepEquals, []string{"m3.large"})
+	c.Assert(err, check.IsNil)
 }
 
-func (s *S) TestDeleteSecurityGroupExample(c *check.C) {
-	testServer.Response(200, nil, DeleteSecurityGroupExample)
+func (s *S) TestResizeInstance(c *check.C) {
+	testServer.Response(200, nil, StopInstancesExample)
+	testServer.Response(200, nil, DescribeInstanceStoppingExample)
+	testServer.Response(200, nil, DescribeInstanceStoppedExample)
+	testServer.Response(200, nil, SimpleResponseExample)
+	testServer.Response(200, nil, StartInstancesExample)
+	testServer.Response(200, nil, DescribeInstanceRunningExample)
 
-	resp, err := s.ec2.DeleteSecurityGroup(ec2.SecurityGroup{Name: "websrv"})
-	req := testServer.WaitRequest()
+	err := s.ec2.ResizeInstance("i-1a2b3c4d", "m3.large", &ec2.ResizeOptions{
+		Restart:  true,
+		Attempts: &aws.AttemptStrategy{Total: time.Second, Delay: time.Millisecond},
+	})
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteSecurityGroup"})
-	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
-	c.Assert(req.Form["GroupId"], check.IsNil)
+	reqs := testServer.WaitRequests(6)
+	var actions []string
+	for _, req := range reqs {
+		actions = append(actions, req.Form.Get("Action"))
+	}
+	c.Assert(actions, check.DeepEquals, []string{
+		"StopInstances",
+		"DescribeInstances",
+		"DescribeInstances",
+		"ModifyInstanceAttribute",
+		"StartInstances",
+		"DescribeInstances",
+	})
+	c.Assert(reqs[3].Form["InstanceType.Value"], check.DeepEquals, []string{"m3.large"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
 }
 
-func (s *S) TestDeleteSecurityGroupExampleWithId(c *check.C) {
-	testServer.Response(200, nil, DeleteSecurityGroupExample)
+func (s *S) TestResizeInstanceWithoutRestart(c *check.C) {
+	testServer.Response(200, nil, StopInstancesExample)
+	testServer.Response(200, nil, DescribeInstanceStoppedExample)
+	testServer.Response(200, nil, SimpleResponseExample)
 
-	// ignore return and error - we're only want to check the parameter handling.
-	s.ec2.DeleteSecurityGroup(ec2.SecurityGroup{Id: "sg-67ad940e", Name: "ignored"})
-	req := testServer.WaitRequest()
+	err := s.ec2.ResizeInstance("i-1a2b3c4d", "m3.large", nil)
 
-	c.Assert(req.Form["GroupName"], check.IsNil)
-	c.Assert(req.Form["GroupId"], check.DeepEquals, []string{"sg-67ad940e"})
+	reqs := testServer.WaitRequests(3)
+	c.Assert(reqs[2].Form["Action"], check.DeepEquals, []string{"ModifyInstanceAttribute"})
+	c.Assert(err, check.IsNil)
 }
 
-func (s *S) TestAuthorizeSecurityGroupExample1(c *check.C) {
-	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
+func (s *S) TestResizeInstanceTimeout(c *check.C) {
+	testServer.Response(200, nil, StopInstancesExample)
+	testServer.Responses(2, 200, nil, DescribeInstanceStoppingExample)
 
-	perms := []ec2.IPPerm{{
-		Protocol:  "tcp",
-		FromPort:  80,
-		ToPort:    80,
-		SourceIPs: []string{"205.192.0.0/16", "205.159.0.0/16"},
-	}}
-	resp, err := s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Name: "websrv"}, perms)
+	err := s.ec2.ResizeInstance("i-1a2b3c4d", "m3.large", &ec2.ResizeOptions{
+		Attempts: &aws.AttemptStrategy{Min: 2},
+	})
 
-	req := testServer.WaitRequest()
+	testServer.WaitRequests(3)
+	c.Assert(err, check.ErrorMatches, "timed out waiting for instance i-1a2b3c4d, which is stopping")
+}
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AuthorizeSecurityGroupIngress"})
-	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
-	c.Assert(req.Form["IpPermissions.1.IpProtocol"], check.DeepEquals, []string{"tcp"})
-	c.Assert(req.Form["IpPermissions.1.FromPort"], check.DeepEquals, []string{"80"})
-	c.Assert(req.Form["IpPermissions.1.ToPort"], check.DeepEquals, []string{"80"})
-	c.Assert(req.Form["IpPermissions.1.IpRanges.1.CidrIp"], check.DeepEquals, []string{"205.192.0.0/16"})
-	c.Assert(req.Form["IpPermissions.1.IpRanges.2.CidrIp"], check.DeepEquals, []string{"205.159.0.0/16"})
+func (s *S) TestWaitUntilSnapshotCompleted(c *check.C) {
+	testServer.Response(200, nil, DescribeSnapshotsExample)
+	testServer.Response(200, nil, DescribeSnapshotCompletedExample)
+
+	var progress []int
+	err := s.ec2.WaitUntilSnapshotCompleted("snap-1a2b3c4d", &ec2.WaitOptions{
+		Attempts: &aws.AttemptStrategy{Total: time.Second, Delay: time.Millisecond},
+		Progress: func(percent int) { progress = append(progress, percent) },
+	})
 
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["Action"], check.DeepEquals, []string{"DescribeSnapshots"})
+	c.Assert(reqs[0].Form["SnapshotId.1"], check.DeepEquals, []string{"snap-1a2b3c4d"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(progress, check.DeepEquals, []int{30, 100})
 }
 
-func (s *S) TestAuthorizeSecurityGroupExample1WithId(c *check.C) {
-	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
-
-	perms := []ec2.IPPerm{{
-		Protocol:  "tcp",
-		FromPort:  80,
-		ToPort:    80,
-		SourceIPs: []string{"205.192.0.0/16", "205.159.0.0/16"},
-	}}
-	// ignore return and error - we're only want to check the parameter handling.
-	s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Id: "sg-67ad940e", Name: "ignored"}, perms)
+func (s *S) TestWaitUntilSnapshotCompletedError(c *check.C) {
+	testServer.Response(200, nil, DescribeSnapshotErrorExample)
 
-	req := testServer.WaitRequest()
+	err := s.ec2.WaitUntilSnapshotCompleted("snap-1a2b3c4d", nil)
 
-	c.Assert(req.Form["GroupName"], check.IsNil)
-	c.Assert(req.Form["GroupId"], check.DeepEquals, []string{"sg-67ad940e"})
+	testServer.WaitRequest()
+	c.Assert(err, check.ErrorMatches, "snapshot snap-1a2b3c4d failed: Volume is unavailable")
 }
 
-func (s *S) TestAuthorizeSecurityGroupExample2(c *check.C) {
-	testServer.Response(200, nil, AuthorizeSecurityGroupIngressExample)
+func (s *S) TestWaitUntilSnapshotCompletedTimeout(c *check.C) {
+	testServer.Responses(2, 200, nil, DescribeSnapshotsExample)
 
-	perms := []ec2.IPPerm{{
-		Protocol: "tcp",
-		FromPort: 80,
-		ToPort:   81,
-		SourceGroups: []ec2.UserSecurityGroup{
-			{OwnerId: "999988887777", Name: "OtherAccountGroup"},
-			{Id: "sg-67ad940e"},
-		},
-	}}
-	resp, err := s.ec2.AuthorizeSecurityGroup(ec2.SecurityGroup{Name: "websrv"}, perms)
+	err := s.ec2.WaitUntilSnapshotCompleted("snap-1a2b3c4d", &ec2.WaitOptions{
+		Attempts: &aws.AttemptStrategy{Min: 2},
+	})
+
+	testServer.WaitRequests(2)
+	c.Assert(err, check.ErrorMatches, "timed out waiting for snapshot snap-1a2b3c4d, which is pending")
+}
 
-	req := testServer.WaitRequest()
+func (s *S) TestWatchInstances(c *check.C) {
+	testServer.Response(200, nil, DescribeInstanceRunningExample)
+	testServer.Response(200, nil, DescribeInstanceStoppingExample)
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AuthorizeSecurityGroupIngress"})
-	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
-	c.Assert(req.Form["IpPermissions.1.IpProtocol"], check.DeepEquals, []string{"tcp"})
-	c.Assert(req.Form["IpPermissions.1.FromPort"], check.DeepEquals, []string{"80"})
-	c.Assert(req.Form["IpPermissions.1.ToPort"], check.DeepEquals, []string{"81"})
-	c.Assert(req.Form["IpPermissions.1.Groups.1.UserId"], check.DeepEquals, []string{"999988887777"})
-	c.Assert(req.Form["IpPermissions.1.Groups.1.GroupName"], check.DeepEquals, []string{"OtherAccountGroup"})
-	c.Assert(req.Form["IpPermissions.1.Groups.2.UserId"], check.IsNil)
-	c.Assert(req.Form["IpPermissions.1.Groups.2.GroupName"], check.IsNil)
-	c.Assert(req.Form["IpPermissions.1.Groups.2.GroupId"], check.DeepEquals, []string{"sg-67ad940e"})
+	changes, stop := s.ec2.WatchInstances([]string{"i-1a2b3c4d"}, 50*time.Millisecond)
+	change := <-changes
+	stop()
+	for range changes {
+	}
 
-	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Form["Action"], check.DeepEquals, []string{"DescribeInstances"})
+	c.Assert(reqs[0].Form["InstanceId.1"], check.IsNil)
+	c.Assert(reqs[0].Form["Filter.1.Name"], check.DeepEquals, []string{"instance-id"})
+	c.Assert(reqs[0].Form["Filter.1.Value.1"], check.DeepEquals, []string{"i-1a2b3c4d"})
+	c.Assert(reqs[1].Form["Filter.1.Value.1"], check.DeepEquals, []string{"i-1a2b3c4d"})
+	c.Assert(change.InstanceId, check.Equals, "i-1a2b3c4d")
+	c.Assert(change.PreviousState.Name, check.Equals, "running")
+	c.Assert(change.CurrentState.Name, check.Equals, "stopping")
+	stop()
 }
 
-func (s *S) TestRevokeSecurityGroupExample(c *check.C) {
-	// RevokeSecurityGroup is implemented by the same code as AuthorizeSecurityGroup
-	// so there's no need to duplicate all the tests.
-	testServer.Response(200, nil, RevokeSecurityGroupIngressExample)
+func (s *S) TestCreateInstanceExportTask(c *check.C) {
+	testServer.Response(200, nil, CreateInstanceExportTaskExample)
 
-	resp, err := s.ec2.RevokeSecurityGroup(ec2.SecurityGroup{Name: "websrv"}, nil)
+	resp, err := s.ec2.CreateInstanceExportTask("i-12345678", &ec2.ExportTaskOptions{
+		Description:       "Example for docs",
+		TargetEnvironment: "vmware",
+		DiskImageFormat:   "VMDK",
+		ContainerFormat:   "ova",
+		S3Bucket:          "my-bucket",
+		S3Prefix:          "exports/",
+	})
 
 	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateInstanceExportTask"})
+	c.Assert(req.Form["InstanceId"], check.DeepEquals, []string{"i-12345678"})
+	c.Assert(req.Form["Description"], check.DeepEquals, []string{"Example for docs"})
+	c.Assert(req.Form["TargetEnvironment"], check.DeepEquals, []string{"vmware"})
+	c.Assert(req.Form["ExportToS3.DiskImageFormat"], check.DeepEquals, []string{"VMDK"})
+	c.Assert(req.Form["ExportToS3.ContainerFormat"], check.DeepEquals, []string{"ova"})
+	c.Assert(req.Form["ExportToS3.S3Bucket"], check.DeepEquals, []string{"my-bucket"})
+	c.Assert(req.Form["ExportToS3.S3Prefix"], check.DeepEquals, []string{"exports/"})
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"RevokeSecurityGroupIngress"})
-	c.Assert(req.Form["GroupName"], check.DeepEquals, []string{"websrv"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.ExportTask, check.DeepEquals, ec2.ExportTask{
+		ExportTaskId:      "export-i-1234wxyz",
+		Description:       "Example for docs",
+		State:             "active",
+		StatusMessage:     "Running",
+		InstanceId:        "i-12345678",
+		TargetEnvironment: "vmware",
+		DiskImageFormat:   "vmdk",
+		ContainerFormat:   "ova",
+		S3Bucket:          "my-bucket",
+		S3Key:             "exports/export-i-1234wxyz.ova",
+	})
 }
 
-func (s *S) TestCreateTags(c *check.C) {
-	testServer.Response(200, nil, CreateTagsExample)
+func (s *S) TestDescribeExportTasks(c *check.C) {
+	testServer.Response(200, nil, DescribeExportTasksExample)
 
-	resp, err := s.ec2.CreateTags([]string{"ami-1a2b3c4d", "i-7f4d3a2b"}, []ec2.Tag{{"webserver", ""}, {"stack", "Production"}})
+	resp, err := s.ec2.DescribeExportTasks([]string{"export-i-1234wxyz"})
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["ResourceId.1"], check.DeepEquals, []string{"ami-1a2b3c4d"})
-	c.Assert(req.Form["ResourceId.2"], check.DeepEquals, []string{"i-7f4d3a2b"})
-	c.Assert(req.Form["Tag.1.Key"], check.DeepEquals, []string{"webserver"})
-	c.Assert(req.Form["Tag.1.Value"], check.DeepEquals, []string{""})
-	c.Assert(req.Form["Tag.2.Key"], check.DeepEquals, []string{"stack"})
-	c.Assert(req.Form["Tag.2.Value"], check.DeepEquals, []string{"Production"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeExportTasks"})
+	c.Assert(req.Form["ExportTaskId.1"], check.DeepEquals, []string{"export-i-1234wxyz"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.ExportTasks, check.HasLen, 1)
+	c.Assert(resp.ExportTasks[0].ExportTaskId, check.Equals, "export-i-1234wxyz")
+	c.Assert(resp.ExportTasks[0].S3Key, check.Equals, "exports/export-i-1234wxyz.ova")
 }
 
-func (s *S) TestDeleteTags(c *check.C) {
-	testServer.Response(200, nil, DeleteTagsExample)
+func (s *S) TestCancelExportTask(c *check.C) {
+	testServer.Response(200, nil, SimpleResponseExample)
 
-	resp, err := s.ec2.DeleteTags([]string{"ami-1a2b3c4d", "i-7f4d3a2b"}, []ec2.Tag{{"webserver", ""}, {"stack", ""}})
+	_, err := s.ec2.CancelExportTask("export-i-1234wxyz")
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["ResourceId.1"], check.DeepEquals, []string{"ami-1a2b3c4d"})
-	c.Assert(req.Form["ResourceId.2"], check.DeepEquals, []string{"i-7f4d3a2b"})
-	c.Assert(req.Form["Tag.1.Key"], check.DeepEquals, []string{"webserver"})
-	c.Assert(req.Form["Tag.2.Key"], check.DeepEquals, []string{"stack"})
-
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CancelExportTask"})
+	c.Assert(req.Form["ExportTaskId"], check.DeepEquals, []string{"export-i-1234wxyz"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
 }
 
-func (s *S) TestDescribeTags(c *check.C) {
-	testServer.Response(200, nil, DescribeTagsExample)
-
-	filter := ec2.NewFilter()
-	filter.Add("key1", "value1")
+func (s *S) TestCreateFlowLogs(c *check.C) {
+	testServer.Response(200, nil, CreateFlowLogsExample)
 
-	resp, err := s.ec2.DescribeTags(filter)
+	resp, err := s.ec2.CreateFlowLogs(&ec2.CreateFlowLogsOptions{
+		ResourceIds:              []string{"vpc-1a2b3c4d", "vpc-2f09a348"},
+		ResourceType:             "VPC",
+		TrafficType:              "ALL",
+		LogGroupName:             "my-flow-logs",
+		DeliverLogsPermissionArn: "arn:aws:iam::123456789101:role/flowlogsrole",
+		ClientToken:              "myclienttoken",
+	})
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeTags"})
-	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"key1"})
-	c.Assert(req.Form["Filter.1.Value.1"], check.DeepEquals, []string{"value1"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateFlowLogs"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["ResourceId.1"], check.DeepEquals, []string{"vpc-1a2b3c4d"})
+	c.Assert(req.Form["ResourceId.2"], check.DeepEquals, []string{"vpc-2f09a348"})
+	c.Assert(req.Form["ResourceType"], check.DeepEquals, []string{"VPC"})
+	c.Assert(req.Form["TrafficType"], check.DeepEquals, []string{"ALL"})
+	c.Assert(req.Form["LogGroupName"], check.DeepEquals, []string{"my-flow-logs"})
+	c.Assert(req.Form["LogDestinationType"], check.IsNil)
+	c.Assert(req.Form["LogDestination"], check.IsNil)
+	c.Assert(req.Form["DeliverLogsPermissionArn"], check.DeepEquals, []string{"arn:aws:iam::123456789101:role/flowlogsrole"})
+	c.Assert(req.Form["ClientToken"], check.DeepEquals, []string{"myclienttoken"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
-	c.Assert(resp.Tags, check.HasLen, 6)
+	c.Assert(resp.ClientToken, check.Equals, "myclienttoken")
+	c.Assert(resp.FlowLogIds, check.DeepEquals, []string{"fl-1a2b3c4d"})
+	c.Assert(resp.Unsuccessful, check.DeepEquals, []ec2.UnsuccessfulItem{{
+		ResourceId: "vpc-2f09a348",
+		Code:       "InvalidVpcID.NotFound",
+		Message:    "The vpc ID 'vpc-2f09a348' does not exist",
+	}})
+}
 
-	r0 := resp.Tags[0]
-	c.Assert(r0.Key, check.Equals, "webserver")
-	c.Assert(r0.Value, check.Equals, "")
-	c.Assert(r0.ResourceId, check.Equals, "ami-1a2b3c4d")
-	c.Assert(r0.ResourceType, check.Equals, "image")
+func (s *S) TestCreateFlowLogsToS3(c *check.C) {
+	testServer.Response(200, nil, CreateFlowLogsExample)
 
-	r1 := resp.Tags[1]
-	c.Assert(r1.Key, check.Equals, "stack")
-	c.Assert(r1.Value, check.Equals, "Production")
-	c.Assert(r1.ResourceId, check.Equals, "ami-1a2b3c4d")
-	c.Assert(r1.ResourceType, check.Equals, "image")
+	_, err := s.ec2.CreateFlowLogs(&ec2.CreateFlowLogsOptions{
+		ResourceIds:        []string{"subnet-1a2b3c4d"},
+		ResourceType:       "Subnet",
+		TrafficType:        "REJECT",
+		LogDestinationType: "s3",
+		LogDestination:     "arn:aws:s3:::my-flow-logs",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["LogGroupName"], check.IsNil)
+	c.Assert(req.Form["LogDestinationType"], check.DeepEquals, []string{"s3"})
+	c.Assert(req.Form["LogDestination"], check.DeepEquals, []string{"arn:aws:s3:::my-flow-logs"})
+	c.Assert(req.Form["DeliverLogsPermissionArn"], check.IsNil)
+	c.Assert(req.Form["ClientToken"], check.HasLen, 1)
+	c.Assert(err, check.IsNil)
 }
 
-func (s *S) TestStartInstances(c *check.C) {
-	testServer.Response(200, nil, StartInstancesExample)
+func (s *S) TestDeleteFlowLogs(c *check.C) {
+	testServer.Response(200, nil, DeleteFlowLogsExample)
 
-	resp, err := s.ec2.StartInstances("i-10a64379")
-	req := testServer.WaitRequest()
+	resp, err := s.ec2.DeleteFlowLogs([]string{"fl-1a2b3c4d", "fl-2b3c4d5e"})
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"StartInstances"})
-	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-10a64379"})
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteFlowLogs"})
+	c.Assert(req.Form["FlowLogId.1"], check.DeepEquals, []string{"fl-1a2b3c4d"})
+	c.Assert(req.Form["FlowLogId.2"], check.DeepEquals, []string{"fl-2b3c4d5e"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-
-	s0 := resp.StateChanges[0]
-	c.Assert(s0.InstanceId, check.Equals, "i-10a64379")
-	c.Assert(s0.CurrentState.Code, check.Equals, 0)
-	c.Assert(s0.CurrentState.Name, check.Equals, "pending")
-	c.Assert(s0.PreviousState.Code, check.Equals, 80)
-	c.Assert(s0.PreviousState.Name, check.Equals, "stopped")
+	c.Assert(resp.Unsuccessful, check.HasLen, 0)
 }
 
-func (s *S) TestStopInstances(c *check.C) {
-	testServer.Response(200, nil, StopInstancesExample)
+func (s *S) TestFlowLogs(c *check.C) {
+	testServer.Response(200, nil, DescribeFlowLogsExample)
 
-	resp, err := s.ec2.StopInstances("i-10a64379")
-	req := testServer.WaitRequest()
+	filter := ec2.NewFilter()
+	filter.Add("resource-id", "vpc-1a2b3c4d")
+	resp, err := s.ec2.FlowLogs(nil, filter)
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"StopInstances"})
-	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-10a64379"})
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeFlowLogs"})
+	c.Assert(req.Form["FlowLogId.1"], check.IsNil)
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"resource-id"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-
-	s0 := resp.StateChanges[0]
-	c.Assert(s0.InstanceId, check.Equals, "i-10a64379")
-	c.Assert(s0.CurrentState.Code, check.Equals, 64)
-	c.Assert(s0.CurrentState.Name, check.Equals, "stopping")
-	c.Assert(s0.PreviousState.Code, check.Equals, 16)
-	c.Assert(s0.PreviousState.Name, check.Equals, "running")
+	c.Assert(resp.FlowLogs, check.DeepEquals, []ec2.FlowLog{{
+		FlowLogId:                "fl-ab12cd34",
+		FlowLogStatus:            "ACTIVE",
+		CreationTime:             "2015-05-19T08:48:59Z",
+		ResourceId:               "vpc-1a2b3c4d",
+		TrafficType:              "ALL",
+		LogGroupName:             "FlowLogsForSubnetA",
+		LogDestinationType:       "cloud-watch-logs",
+		DeliverLogsPermissionArn: "arn:aws:iam::123456789101:role/flowlogsrole",
+		DeliverLogsStatus:        "FAILED",
+		DeliverLogsErrorMessage:  "Access error",
+	}})
 }
 
-func (s *S) TestRebootInstances(c *check.C) {
-	testServer.Response(200, nil, RebootInstancesExample)
+func (s *S) TestBundleInstance(c *check.C) {
+	testServer.Response(200, nil, BundleInstanceExample)
 
-	resp, err := s.ec2.RebootInstances("i-10a64379")
-	req := testServer.WaitRequest()
+	resp, err := s.ec2.BundleInstance("i-1234567890abcdef0", &ec2.BundleOptions{
+		Bucket:       "myawsbucket",
+		Prefix:       "winami",
+		UploadPolicy: `{"expiration":"2030-01-01T00:00:00Z"}`,
+	})
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"RebootInstances"})
-	c.Assert(req.Form["InstanceId.1"], check.DeepEquals, []string{"i-10a64379"})
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"BundleInstance"})
+	c.Assert(req.Form["InstanceId"], check.DeepEquals, []string{"i-1234567890abcdef0"})
+	c.Assert(req.Form["Storage.S3.Bucket"], check.DeepEquals, []string{"myawsbucket"})
+	c.Assert(req.Form["Storage.S3.Prefix"], check.DeepEquals, []string{"winami"})
+	c.Assert(req.Form["Storage.S3.AWSAccessKeyId"], check.DeepEquals, []string{"abc"})
+	c.Assert(req.Form["Storage.S3.UploadPolicy"], check.DeepEquals, []string{"eyJleHBpcmF0aW9uIjoiMjAzMC0wMS0wMVQwMDowMDowMFoifQ=="})
+	c.Assert(req.Form["Storage.S3.UploadPolicySignature"], check.DeepEquals, []string{"6OtvgtPrqW1tMlSsiQB0cFczlrI="})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.RequestId, check.Equals, "bun-c1a540a8")
+	task := resp.BundleTask
+	c.Assert(task.BundleId, check.Equals, "bun-c1a540a8")
+	c.Assert(task.InstanceId, check.Equals, "i-1234567890abcdef0")
+	c.Assert(task.State, check.Equals, "bundling")
+	c.Assert(task.Progress, check.Equals, "70%")
+	c.Assert(task.Bucket, check.Equals, "myawsbucket")
+	c.Assert(task.Prefix, check.Equals, "winami")
 }
 
-func (s *S) TestSignatureWithEndpointPath(c *check.C) {
-	ec2.FakeTime(true)
-	defer ec2.FakeTime(false)
-
-	testServer.Response(200, nil, RebootInstancesExample)
+func (s *S) TestBundleInstanceBucketOwnerCredentials(c *check.C) {
+	testServer.Response(200, nil, BundleInstanceExample)
 
-	region := aws.Region{EC2Endpoint: aws.ServiceInfo{Endpoint: testServer.URL + "/services/Cloud", Signer: aws.V2Signature}}
-	ec2 := ec2.New(s.ec2.Auth, region)
+	_, err := s.ec2.BundleInstance("i-1234567890abcdef0", &ec2.BundleOptions{
+		Bucket:         "myawsbucket",
+		Prefix:         "winami",
+		AWSAccessKeyId: "owner",
+		AWSSecretKey:   "xyz",
+		UploadPolicy:   `{"expiration":"2030-01-01T00:00:00Z"}`,
+	})
 
-	_, err := ec2.RebootInstances("i-10a64379")
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Storage.S3.AWSAccessKeyId"], check.DeepEquals, []string{"owner"})
+	c.Assert(req.Form["Storage.S3.UploadPolicySignature"], check.DeepEquals, []string{"3rwOmmM4ukYzTGH+p0y+jV/Npps="})
 	c.Assert(err, check.IsNil)
+}
 
-	req := testServer.WaitRequest()
-	c.Assert(req.Form["Signature"], check.DeepEquals, []string{"VVoC6Y6xfES+KvZo+789thP8+tye4F6fOKBiKmXk4S4="})
+func (s *S) TestBundleInstanceAccessKeyWithoutSecretKey(c *check.C) {
+	resp, err := s.ec2.BundleInstance("i-1234567890abcdef0", &ec2.BundleOptions{
+		Bucket:         "myawsbucket",
+		AWSAccessKeyId: "owner",
+	})
+
+	c.Assert(err, check.ErrorMatches, "AWSAccessKeyId and AWSSecretKey must be set together")
+	c.Assert(resp, check.IsNil)
 }
 
-func (s *S) TestDescribeReservedInstancesiExample(c *check.C) {
-	testServer.Response(200, nil, DescribeReservedInstancesExample)
+func (s *S) TestDescribeBundleTasks(c *check.C) {
+	testServer.Response(200, nil, DescribeBundleTasksExample)
 
-	resp, err := s.ec2.DescribeReservedInstances([]string{"i-1", "i-2"}, nil)
+	resp, err := s.ec2.DescribeBundleTasks([]string{"bun-c1a540a8"}, nil)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeReservedInstances"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeBundleTasks"})
+	c.Assert(req.Form["BundleId.1"], check.DeepEquals, []string{"bun-c1a540a8"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.ReservedInstances, check.HasLen, 1)
-
-	r0 := resp.ReservedInstances[0]
-	c.Assert(r0.ReservedInstanceId, check.Equals, "e5a2ff3b-7d14-494f-90af-0b5d0EXAMPLE")
-
+	c.Assert(resp.BundleTasks, check.HasLen, 1)
+	task := resp.BundleTasks[0]
+	c.Assert(task.State, check.Equals, "failed")
+	c.Assert(task.Progress, check.Equals, "20%")
+	c.Assert(task.StartTime, check.Equals, "2008-10-07T11:41:50.000Z")
+	c.Assert(task.UpdateTime, check.Equals, "2008-10-07T11:51:50.000Z")
+	c.Assert(task.ErrorCode, check.Equals, "Client.InvalidParameterValue")
+	c.Assert(task.ErrorMessage, check.Equals, "The upload policy has expired.")
 }
 
-func (s *S) TestDeregisterImage(c *check.C) {
-	testServer.Response(200, nil, DeregisterImageExample)
+func (s *S) TestCancelBundleTask(c *check.C) {
+	testServer.Response(200, nil, CancelBundleTaskExample)
 
-	resp, err := s.ec2.DeregisterImage("i-1")
+	resp, err := s.ec2.CancelBundleTask("bun-cla322b9")
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeregisterImage"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CancelBundleTask"})
+	c.Assert(req.Form["BundleId"], check.DeepEquals, []string{"bun-cla322b9"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Response, check.Equals, true)
-
+	c.Assert(resp.BundleTask.State, check.Equals, "cancelling")
+	c.Assert(resp.BundleTask.Prefix, check.Equals, "my-new-image")
 }
 
-func (s *S) TestDescribeInstanceStatus(c *check.C) {
-	testServer.Response(200, nil, DescribeInstanceStatusExample)
+func (s *S) TestCreateFleet(c *check.C) {
+	testServer.Response(200, nil, CreateFleetExample)
 
-	resp, err := s.ec2.DescribeInstanceStatus([]string{"i-1a2b3c4d", "i-2a2b3c4d"}, nil)
+	resp, err := s.ec2.CreateFleet(&ec2.FleetConfig{
+		LaunchTemplateConfigs: []ec2.FleetLaunchTemplateConfig{{
+			LaunchTemplate: ec2.LaunchTemplateSpec{LaunchTemplateId: "lt-0e8c754449bEXAMPLE", Version: "1"},
+			Overrides: []ec2.FleetLaunchTemplateOverrides{
+				{InstanceType: "m5.large", SubnetId: "subnet-11111111"},
+				{InstanceType: "c5.large", WeightedCapacity: 1.5, Priority: 2},
+			},
+		}, {
+			LaunchTemplate: ec2.LaunchTemplateSpec{LaunchTemplateName: "web", Version: "$Latest"},
+		}},
+		TotalTargetCapacity:       3,
+		OnDemandTargetCapacity:    1,
+		DefaultTargetCapacityType: "spot",
+		Type:                      "instant",
+		SpotAllocationStrategy:    "capacity-optimized",
+		ClientToken:               "client-token",
+	})
 
 	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateFleet"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["LaunchTemplateConfigs.1.LaunchTemplateSpecification.LaunchTemplateId"], check.DeepEquals, []string{"lt-0e8c754449bEXAMPLE"})
+	c.Assert(req.Form["LaunchTemplateConfigs.1.LaunchTemplateSpecification.LaunchTemplateName"], check.IsNil)
+	c.Assert(req.Form["LaunchTemplateConfigs.1.LaunchTemplateSpecification.Version"], check.DeepEquals, []string{"1"})
+	c.Assert(req.Form["LaunchTemplateConfigs.1.Overrides.1.InstanceType"], check.DeepEquals, []string{"m5.large"})
+	c.Assert(req.Form["LaunchTemplateConfigs.1.Overrides.1.SubnetId"], check.DeepEquals, []string{"subnet-11111111"})
+	c.Assert(req.Form["LaunchTemplateConfigs.1.Overrides.1.WeightedCapacity"], check.IsNil)
+	c.Assert(req.Form["LaunchTemplateConfigs.1.Overrides.2.InstanceType"], check.DeepEquals, []string{"c5.large"})
+	c.Assert(req.Form["LaunchTemplateConfigs.1.Overrides.2.WeightedCapacity"], check.DeepEquals, []string{"1.5"})
+	c.Assert(req.Form["LaunchTemplateConfigs.1.Overrides.2.Priority"], check.DeepEquals, []string{"2"})
+	c.Assert(req.Form["LaunchTemplateConfigs.2.LaunchTemplateSpecification.LaunchTemplateName"], check.DeepEquals, []string{"web"})
+	c.Assert(req.Form["LaunchTemplateConfigs.2.LaunchTemplateSpecification.Version"], check.DeepEquals, []string{"$Latest"})
+	c.Assert(req.Form["TargetCapacitySpecification.TotalTargetCapacity"], check.DeepEquals, []string{"3"})
+	c.Assert(req.Form["TargetCapacitySpecification.OnDemandTargetCapacity"], check.DeepEquals, []string{"1"})
+	c.Assert(req.Form["TargetCapacitySpecification.SpotTargetCapacity"], check.IsNil)
+	c.Assert(req.Form["TargetCapacitySpecification.DefaultTargetCapacityType"], check.DeepEquals, []string{"spot"})
+	c.Assert(req.Form["Type"], check.DeepEquals, []string{"instant"})
+	c.Assert(req.Form["SpotOptions.AllocationStrategy"], check.DeepEquals, []string{"capacity-optimized"})
+	c.Assert(req.Form["OnDemandOptions.AllocationStrategy"], check.IsNil)
+	c.Assert(req.Form["ClientToken"], check.DeepEquals, []string{"client-token"})
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInstanceStatus"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "3be1508e-c444-4fef-89cc-0b1223c4f02fEXAMPLE")
-	c.Assert(resp.InstanceStatuses, check.HasLen, 4)
-	r0 := resp.InstanceStatuses[0]
-	c.Assert(r0.InstanceId, check.Equals, "i-1a2b3c4d")
-	c.Assert(r0.InstanceState, check.Equals, "running")
-	c.Assert(r0.SystemStatus.StatusName, check.Equals, "impaired")
-	c.Assert(r0.SystemStatus.Status, check.Equals, "failed")
-	c.Assert(r0.InstanceStatus.StatusName, check.Equals, "impaired")
+	c.Assert(resp.RequestId, check.Equals, "b9f0bd5f-ee8a-4a18-8a43-bd52EXAMPLE")
+	c.Assert(resp.FleetId, check.Equals, "fleet-73fdd2ce-7b8a-4a3e-8c5c-1af4eEXAMPLE")
+	c.Assert(resp.ClientToken, check.Equals, "client-token")
+	c.Assert(resp.InstanceIds, check.DeepEquals, []string{"i-1234567890abcdef0", "i-0598c7d356eba48d7", "i-0a1b2c3d4e5f67890"})
+	c.Assert(resp.Errors, check.DeepEquals, []ec2.FleetError{{
+		LaunchTemplateId: "lt-0e8c754449bEXAMPLE",
+		InstanceType:     "c5.large",
+		Lifecycle:        "spot",
+		Code:             "InsufficientInstanceCapacity",
+		Message:          "There is no Spot capacity available that matches your request.",
+	}})
 }
 
-func (s *S) TestDescribeVolumes(c *check.C) {
-	testServer.Response(200, nil, DescribeVolumesExample)
+func (s *S) TestDescribeFleets(c *check.C) {
+	testServer.Response(200, nil, DescribeFleetsExample)
 
-	resp, err := s.ec2.DescribeVolumes([]string{"vol-1a2b3c4d"}, nil)
+	resp, err := s.ec2.DescribeFleets([]string{"fleet-73fdd2ce-7b8a-4a3e-8c5c-1af4eEXAMPLE"})
 
 	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeFleets"})
+	c.Assert(req.Form["FleetId.1"], check.DeepEquals, []string{"fleet-73fdd2ce-7b8a-4a3e-8c5c-1af4eEXAMPLE"})
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVolumes"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.Volumes, check.HasLen, 1)
-	v0 := resp.Volumes[0]
-	c.Assert(v0.AvailabilityZone, check.Equals, "us-east-1a")
-	c.Assert(v0.Size, check.Equals, 80)
-	c.Assert(v0.Status, check.Equals, "in-use")
-	c.Assert(v0.AttachmentSet.VolumeId, check.Equals, "vol-1a2b3c4d")
-	c.Assert(v0.AttachmentSet.InstanceId, check.Equals, "i-1a2b3c4d")
-	c.Assert(v0.AttachmentSet.Device, check.Equals, "/dev/sdh")
-	c.Assert(v0.AttachmentSet.Status, check.Equals, "attached")
+	c.Assert(resp.Fleets, check.HasLen, 1)
+	f := resp.Fleets[0]
+	c.Assert(f.FleetId, check.Equals, "fleet-73fdd2ce-7b8a-4a3e-8c5c-1af4eEXAMPLE")
+	c.Assert(f.State, check.Equals, "active")
+	c.Assert(f.ActivityStatus, check.Equals, "fulfilled")
+	c.Assert(f.Type, check.Equals, "maintain")
+	c.Assert(f.TotalTargetCapacity, check.Equals, 2)
+	c.Assert(f.OnDemandTargetCapacity, check.Equals, 1)
+	c.Assert(f.SpotTargetCapacity, check.Equals, 1)
+	c.Assert(f.DefaultTargetCapacityType, check.Equals, "spot")
+	c.Assert(f.FulfilledCapacity, check.Equals, 2.0)
+	c.Assert(f.FulfilledOnDemandCapacity, check.Equals, 1.0)
+	c.Assert(f.Tags, check.DeepEquals, []ec2.Tag{{Key: "Name", Value: "web"}})
 }
 
-func (s *S) TestAttachVolume(c *check.C) {
-	testServer.Response(200, nil, AttachVolumeExample)
+func (s *S) TestDeleteFleets(c *check.C) {
+	testServer.Response(200, nil, DeleteFleetsExample)
 
-	resp, err := s.ec2.AttachVolume("v-1", "i-1", "/dev/sdz")
+	resp, err := s.ec2.DeleteFleets([]string{"fleet-73fdd2ce-7b8a-4a3e-8c5c-1af4eEXAMPLE", "fleet-11111111-2222-3333-4444-55555EXAMPLE"}, true)
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"AttachVolume"})
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteFleets"})
+	c.Assert(req.Form["FleetId.2"], check.DeepEquals, []string{"fleet-11111111-2222-3333-4444-55555EXAMPLE"})
+	c.Assert(req.Form["TerminateInstances"], check.DeepEquals, []string{"true"})
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Successful, check.DeepEquals, []ec2.DeletedFleet{{
+		FleetId:       "fleet-73fdd2ce-7b8a-4a3e-8c5c-1af4eEXAMPLE",
+		CurrentState:  "deleted_terminating",
+		PreviousState: "active",
+	}})
+	c.Assert(resp.Unsuccessful, check.DeepEquals, []ec2.UndeletedFleet{{
+		FleetId: "fleet-11111111-2222-3333-4444-55555EXAMPLE",
+		Code:    "fleetIdDoesNotExist",
+		Message: "The fleet ID does not exist.",
+	}})
 }
 
-func (s *S) TestCreateVolume(c *check.C) {
-	testServer.Response(200, nil, CreateVolumeExample)
-
-	resp, err := s.ec2.CreateVolume(ec2.CreateVolumeOptions{
-		Size:             "1",
-		AvailabilityZone: "us-east-1a",
+func (s *S) TestCreateLaunchTemplate(c *check.C) {
+	testServer.Response(200, nil, CreateLaunchTemplateExample)
+
+	resp, err := s.ec2.CreateLaunchTemplate("MyLaunchTemplate", &ec2.LaunchTemplateData{
+		ImageId:        "ami-8c1be5f6",
+		InstanceType:   "t2.micro",
+		KeyName:        "my-key",
+		SecurityGroups: []ec2.SecurityGroup{{Id: "sg-1a2b3c4d"}, {Name: "web"}},
+		UserData:       []byte("#!/bin/sh"),
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{
+			{DeviceName: "/dev/xvda", VolumeType: "gp2", VolumeSize: 20},
+		},
+		NetworkInterfaces: []ec2.NetworkInterface{
+			{SubnetId: "subnet-7b16de0c", AssociatePublicIpAddress: true},
+		},
+		InstanceMarketOptions: &ec2.MarketOptions{MaxPrice: "0.05"},
+		TagSpecifications: []ec2.TagSpecification{
+			{ResourceType: "instance", Tags: []ec2.Tag{{Key: "purpose", Value: "webservers"}}},
+		},
 	})
 
 	req := testServer.WaitRequest()
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateVolume"})
+	c.Assert(req.Method, check.Equals, "POST")
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateLaunchTemplate"})
+	c.Assert(req.Form["Version"], check.DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["LaunchTemplateName"], check.DeepEquals, []string{"MyLaunchTemplate"})
+	c.Assert(req.Form["LaunchTemplateData.ImageId"], check.DeepEquals, []string{"ami-8c1be5f6"})
+	c.Assert(req.Form["LaunchTemplateData.InstanceType"], check.DeepEquals, []string{"t2.micro"})
+	c.Assert(req.Form["LaunchTemplateData.KeyName"], check.DeepEquals, []string{"my-key"})
+	c.Assert(req.Form["LaunchTemplateData.SecurityGroupId.1"], check.DeepEquals, []string{"sg-1a2b3c4d"})
+	c.Assert(req.Form["LaunchTemplateData.SecurityGroup.1"], check.DeepEquals, []string{"web"})
+	c.Assert(req.Form["LaunchTemplateData.UserData"], check.DeepEquals, []string{"IyEvYmluL3No"})
+	c.Assert(req.Form["LaunchTemplateData.BlockDeviceMapping.1.DeviceName"], check.DeepEquals, []string{"/dev/xvda"})
+	c.Assert(req.Form["LaunchTemplateData.BlockDeviceMapping.1.Ebs.VolumeSize"], check.DeepEquals, []string{"20"})
+	c.Assert(req.Form["LaunchTemplateData.NetworkInterface.1.DeviceIndex"], check.DeepEquals, []string{"0"})
+	c.Assert(req.Form["LaunchTemplateData.NetworkInterface.1.SubnetId"], check.DeepEquals, []string{"subnet-7b16de0c"})
+	c.Assert(req.Form["LaunchTemplateData.NetworkInterface.1.AssociatePublicIpAddress"], check.DeepEquals, []string{"true"})
+	c.Assert(req.Form["LaunchTemplateData.InstanceMarketOptions.MarketType"], check.DeepEquals, []string{"spot"})
+	c.Assert(req.Form["LaunchTemplateData.InstanceMarketOptions.SpotOptions.MaxPrice"], check.DeepEquals, []string{"0.05"})
+	c.Assert(req.Form["LaunchTemplateData.TagSpecification.1.ResourceType"], check.DeepEquals, []string{"instance"})
+	c.Assert(req.Form["LaunchTemplateData.TagSpecification.1.Tag.1.Key"], check.DeepEquals, []string{"purpose"})
+	c.Assert(req.Form["LaunchTemplateData.Monitoring.Enabled"], check.IsNil)
 
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "0c67a4c9-d7ec-45ef-8016-bf666EXAMPLE")
-	c.Assert(resp.Size, check.Equals, "1")
-	c.Assert(resp.VolumeId, check.Equals, "vol-2a21e543")
-	c.Assert(resp.AvailabilityZone, check.Equals, "us-east-1a")
-	c.Assert(resp.SnapshotId, check.Equals, "")
-	c.Assert(resp.Status, check.Equals, "creating")
-	c.Assert(resp.CreateTime, check.Equals, "2009-12-28T05:42:53.000Z")
-	c.Assert(resp.VolumeType, check.Equals, "standard")
-	c.Assert(resp.IOPS, check.Equals, 0)
-	c.Assert(resp.Encrypted, check.Equals, false)
+	c.Assert(resp.RequestId, check.Equals, "39f77e0a-3ea7-4fbd-8b16-c8ebEXAMPLE")
+	c.Assert(resp.LaunchTemplate, check.DeepEquals, ec2.LaunchTemplate{
+		LaunchTemplateId:     "lt-0a20c965061f64abc",
+		LaunchTemplateName:   "MyLaunchTemplate",
+		CreateTime:           "2017-10-31T11:38:52.000Z",
+		CreatedBy:            "arn:aws:iam::123456789012:root",
+		DefaultVersionNumber: 1,
+		LatestVersionNumber:  1,
+	})
 }
 
-func (s *S) TestDescribeVpcs(c *check.C) {
-	testServer.Response(200, nil, DescribeVpcsExample)
+func (s *S) TestCreateLaunchTemplateNilData(c *check.C) {
+	testServer.Response(200, nil, CreateLaunchTemplateExample)
 
-	resp, err := s.ec2.DescribeVpcs([]string{"vpc-1a2b3c4d"}, nil)
+	resp, err := s.ec2.CreateLaunchTemplate("MyLaunchTemplate", nil)
 
 	req := testServer.WaitRequest()
+	c.Assert(req.Form["LaunchTemplateName"], check.DeepEquals, []string{"MyLaunchTemplate"})
+	c.Assert(req.Form["LaunchTemplateData.ImageId"], check.IsNil)
+	c.Assert(req.Form["ClientToken"][0], check.Matches, "[0-9a-f]{64}")
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.ClientToken, check.Equals, req.Form["ClientToken"][0])
+}
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpcs"})
+func (s *S) TestCreateLaunchTemplateWithOptions(c *check.C) {
+	testServer.Response(200, nil, CreateLaunchTemplateExample)
+
+	resp, err := s.ec2.CreateLaunchTemplateWithOptions(&ec2.CreateLaunchTemplateOptions{
+		LaunchTemplateName: "MyLaunchTemplate",
+		Data:               &ec2.LaunchTemplateData{ImageId: "ami-8c1be5f6"},
+		ClientToken:        "my-token",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateLaunchTemplate"})
+	c.Assert(req.Form["LaunchTemplateName"], check.DeepEquals, []string{"MyLaunchTemplate"})
+	c.Assert(req.Form["LaunchTemplateData.ImageId"], check.DeepEquals, []string{"ami-8c1be5f6"})
+	c.Assert(req.Form["ClientToken"], check.DeepEquals, []string{"my-token"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
-	c.Assert(resp.Vpcs, check.HasLen, 1)
-	v0 := resp.Vpcs[0]
-	c.Assert(v0.VpcId, check.Equals, "vpc-1a2b3c4d")
-	c.Assert(v0.State, check.Equals, "available")
-	c.Assert(v0.CidrBlock, check.Equals, "10.0.0.0/23")
-	c.Assert(v0.DhcpOptionsId, check.Equals, "dopt-7a8b9c2d")
-	c.Assert(v0.InstanceTenancy, check.Equals, "default")
-	c.Assert(v0.IsDefault, check.Equals, false)
+	c.Assert(resp.ClientToken, check.Equals, "my-token")
+	c.Assert(resp.LaunchTemplate.LaunchTemplateId, check.Equals, "lt-0a20c965061f64abc")
 }
 
-func (s *S) TestDescribeVpnConnections(c *check.C) {
-	testServer.Response(200, nil, DescribeVpnConnectionsExample)
+func (s *S) TestCreateLaunchTemplateInvalidVolumeType(c *check.C) {
+	_, err := s.ec2.CreateLaunchTemplate("MyLaunchTemplate", &ec2.LaunchTemplateData{
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{DeviceName: "/dev/xvda", VolumeType: "gp9"}},
+	})
+	c.Assert(err, check.ErrorMatches, `block device /dev/xvda: invalid volume type "gp9"`)
+}
 
-	resp, err := s.ec2.DescribeVpnConnections([]string{"vpn-44a8938f"}, nil)
+func (s *S) TestCreateLaunchTemplateVersion(c *check.C) {
+	testServer.Response(200, nil, CreateLaunchTemplateVersionExample)
+
+	resp, err := s.ec2.CreateLaunchTemplateVersion(&ec2.CreateLaunchTemplateVersionOptions{
+		LaunchTemplateId:   "lt-0a20c965061f64abc",
+		SourceVersion:      "1",
+		VersionDescription: "LaunchTemplateVersion2",
+		Data:               &ec2.LaunchTemplateData{ImageId: "ami-8c1be5f6"},
+	})
 
 	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"CreateLaunchTemplateVersion"})
+	c.Assert(req.Form["LaunchTemplateId"], check.DeepEquals, []string{"lt-0a20c965061f64abc"})
+	c.Assert(req.Form["LaunchTemplateName"], check.IsNil)
+	c.Assert(req.Form["SourceVersion"], check.DeepEquals, []string{"1"})
+	c.Assert(req.Form["VersionDescription"], check.DeepEquals, []string{"LaunchTemplateVersion2"})
+	c.Assert(req.Form["LaunchTemplateData.ImageId"], check.DeepEquals, []string{"ami-8c1be5f6"})
+	c.Assert(req.Form["LaunchTemplateData.InstanceType"], check.IsNil)
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpnConnections"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
-	c.Assert(resp.VpnConnections, check.HasLen, 1)
-	v0 := resp.VpnConnections[0]
-	c.Assert(v0.VpnConnectionId, check.Equals, "vpn-44a8938f")
-	c.Assert(v0.State, check.Equals, "available")
-	c.Assert(v0.Type, check.Equals, "ipsec.1")
-	c.Assert(v0.CustomerGatewayId, check.Equals, "cgw-b4dc3961")
-	c.Assert(v0.VpnGatewayId, check.Equals, "vgw-8db04f81")
+	v := resp.LaunchTemplateVersion
+	c.Assert(v.LaunchTemplateId, check.Equals, "lt-0a20c965061f64abc")
+	c.Assert(v.VersionNumber, check.Equals, int64(2))
+	c.Assert(v.VersionDescription, check.Equals, "LaunchTemplateVersion2")
+	c.Assert(v.DefaultVersion, check.Equals, false)
 }
 
-func (s *S) TestDescribeVpnGateways(c *check.C) {
-	testServer.Response(200, nil, DescribeVpnGatewaysExample)
+func (s *S) TestDescribeLaunchTemplates(c *check.C) {
+	testServer.Response(200, nil, DescribeLaunchTemplatesExample)
 
-	resp, err := s.ec2.DescribeVpnGateways([]string{"vgw-8db04f81"}, nil)
+	filter := ec2.NewFilter()
+	filter.Add("tag:purpose", "webservers")
+	resp, err := s.ec2.DescribeLaunchTemplates([]string{"lt-0a20c965061f64abc"}, []string{"MyLaunchTemplate"}, filter)
 
 	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeLaunchTemplates"})
+	c.Assert(req.Form["LaunchTemplateId.1"], check.DeepEquals, []string{"lt-0a20c965061f64abc"})
+	c.Assert(req.Form["LaunchTemplateName.1"], check.DeepEquals, []string{"MyLaunchTemplate"})
+	c.Assert(req.Form["Filter.1.Name"], check.DeepEquals, []string{"tag:purpose"})
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeVpnGateways"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
-	c.Assert(resp.VpnGateway, check.HasLen, 1)
-	g0 := resp.VpnGateway[0]
-	c.Assert(g0.VpnGatewayId, check.Equals, "vgw-8db04f81")
-	c.Assert(g0.State, check.Equals, "available")
-	c.Assert(g0.Type, check.Equals, "ipsec.1")
-	c.Assert(g0.AvailabilityZone, check.Equals, "us-east-1a")
-	c.Assert(g0.AttachedVpcId, check.Equals, "vpc-1a2b3c4d")
-	c.Assert(g0.AttachState, check.Equals, "attached")
+	c.Assert(resp.LaunchTemplates, check.HasLen, 1)
+	lt := resp.LaunchTemplates[0]
+	c.Assert(lt.LaunchTemplateName, check.Equals, "MyLaunchTemplate")
+	c.Assert(lt.DefaultVersionNumber, check.Equals, int64(1))
+	c.Assert(lt.LatestVersionNumber, check.Equals, int64(2))
+	c.Assert(lt.Tags, check.DeepEquals, []ec2.Tag{{Key: "purpose", Value: "webservers"}})
 }
 
-func (s *S) TestDescribeInternetGateways(c *check.C) {
-	testServer.Response(200, nil, DescribeInternetGatewaysExample)
+func (s *S) TestDeleteLaunchTemplate(c *check.C) {
+	testServer.Response(200, nil, DeleteLaunchTemplateExample)
 
-	resp, err := s.ec2.DescribeInternetGateways([]string{"igw-eaad4883EXAMPLE"}, nil)
+	resp, err := s.ec2.DeleteLaunchTemplate("lt-0a20c965061f64abc")
 
 	req := testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DeleteLaunchTemplate"})
+	c.Assert(req.Form["LaunchTemplateId"], check.DeepEquals, []string{"lt-0a20c965061f64abc"})
 
-	c.Assert(req.Form["Action"], check.DeepEquals, []string{"DescribeInternetGateways"})
 	c.Assert(err, check.IsNil)
-	c.Assert(resp.RequestId, check.Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
-	c.Assert(resp.InternetGateway, check.HasLen, 1)
-	g0 := resp.InternetGateway[0]
-	c.Assert(g0.InternetGatewayId, check.Equals, "igw-eaad4883EXAMPLE")
-	c.Assert(g0.AttachedVpcId, check.Equals, "vpc-11ad4878")
-	c.Assert(g0.AttachState, check.Equals, "available")
+	c.Assert(resp.LaunchTemplate.LaunchTemplateName, check.Equals, "MyLaunchTemplate")
 }
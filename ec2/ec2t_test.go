@@ -79,6 +79,20 @@ func (s *LocalServerSuite) TestUserData(c *check.C) {
 	c.Assert(tinst.UserData, check.DeepEquals, data)
 }
 
+// TestSecurityGroupVPC is not defined on ServerTests because it
+// requires an existing VPC.
+func (s *LocalServerSuite) TestSecurityGroupVPC(c *check.C) {
+	resp, err := s.ec2.CreateSecurityGroupVPC(sessionName("vpcgroup"), "vpc group", "vpc-1a2b3c4d")
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Id, check.Not(check.Equals), "")
+	defer s.ec2.DeleteSecurityGroup(resp.SecurityGroup)
+
+	groups, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{{Id: resp.Id}}, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(groups.Groups, check.HasLen, 1)
+	c.Assert(groups.Groups[0].VpcId, check.Equals, "vpc-1a2b3c4d")
+}
+
 // AmazonServerSuite runs the ec2test server tests against a live EC2 server.
 // It will only be activated if the -all flag is specified.
 type AmazonServerSuite struct {
@@ -242,6 +256,43 @@ func (s *ServerTests) TestIPPerms(c *check.C) {
 	c.Assert(resp.Groups, check.HasLen, 0)
 }
 
+func (s *ServerTests) TestIPPermsRoundTrip(c *check.C) {
+	g0 := s.makeTestGroup(c, "goamz-test0", "ec2test group 0")
+	defer s.ec2.DeleteSecurityGroup(g0)
+
+	g1 := s.makeTestGroup(c, "goamz-test1", "ec2test group 1")
+	defer s.ec2.DeleteSecurityGroup(g1)
+
+	_, err := s.ec2.AuthorizeSecurityGroup(g0, []ec2.IPPerm{{
+		Protocol:     "tcp",
+		FromPort:     2000,
+		ToPort:       2001,
+		SourceIPs:    []string{"127.0.0.0/24", "200.1.1.34/32"},
+		SourceGroups: []ec2.UserSecurityGroup{{Name: g0.Name}},
+	}})
+	c.Assert(err, check.IsNil)
+
+	resp, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{g0}, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Groups, check.HasLen, 1)
+	perms := resp.Groups[0].IPPerms
+
+	// Replicate the rules read from g0 onto g1 as they are.
+	_, err = s.ec2.AuthorizeSecurityGroup(g1, perms)
+	c.Assert(err, check.IsNil)
+	defer s.ec2.RevokeSecurityGroup(g1, perms)
+
+	resp, err = s.ec2.SecurityGroups([]ec2.SecurityGroup{g1}, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Groups, check.HasLen, 1)
+	c.Assert(resp.Groups[0].IPPerms, check.HasLen, 1)
+	perm := resp.Groups[0].IPPerms[0]
+	sort.Strings(perm.SourceIPs)
+	c.Check(perm.SourceIPs, check.DeepEquals, []string{"127.0.0.0/24", "200.1.1.34/32"})
+	c.Assert(perm.SourceGroups, check.HasLen, 1)
+	c.Check(perm.SourceGroups[0].Id, check.Equals, g0.Id)
+}
+
 func (s *ServerTests) TestDuplicateIPPerm(c *check.C) {
 	name := "goamz-test"
 	descr := "goamz security group for tests"
@@ -97,6 +97,7 @@ type securityGroup struct {
 	id          string
 	name        string
 	description string
+	vpcId       string
 
 	perms map[permKey]bool
 }
@@ -181,7 +182,7 @@ func (g *securityGroup) ec2Perms() (perms []ec2.IPPerm) {
 					OwnerId: ownerId,
 				})
 		} else {
-			ec2p.SourceIPs = append(ec2p.SourceIPs, k.ipAddr)
+			ec2p.IPRanges = append(ec2p.IPRanges, ec2.IPRange{CIDR: k.ipAddr})
 		}
 	}
 	for _, ec2p := range result {
@@ -608,6 +609,7 @@ func (srv *Server) createSecurityGroup(w http.ResponseWriter, req *http.Request,
 	g := &securityGroup{
 		name:        name,
 		description: req.Form.Get("GroupDescription"),
+		vpcId:       req.Form.Get("VpcId"),
 		id:          fmt.Sprintf("sg-%d", srv.groupId.next()),
 		perms:       make(map[permKey]bool),
 	}
@@ -720,6 +722,7 @@ func (srv *Server) describeSecurityGroups(w http.ResponseWriter, req *http.Reque
 				SecurityGroup: group.ec2SecurityGroup(),
 				Description:   group.description,
 				IPPerms:       group.ec2Perms(),
+				VpcId:         group.vpcId,
 			})
 		} else if err != nil {
 			fatalf(400, "InvalidParameterValue", "describe security groups: %v", err)
@@ -740,10 +743,12 @@ func (srv *Server) authorizeSecurityGroupIngress(w http.ResponseWriter, req *htt
 	}
 	perms := srv.parsePerms(req)
 
+	seen := make(map[permKey]bool)
 	for _, p := range perms {
-		if g.perms[p] {
+		if g.perms[p] || seen[p] {
 			fatalf(400, "InvalidPermission.Duplicate", "Permission has already been authorized on the specified group")
 		}
+		seen[p] = true
 	}
 	for _, p := range perms {
 		g.perms[p] = true
@@ -867,6 +872,8 @@ func (srv *Server) parsePerms(req *http.Request) []permKey {
 					fatalf(400, "InvalidPermission.Malformed", "Invalid IP range: %q", val)
 				}
 				ec2p.SourceIPs = append(ec2p.SourceIPs, val)
+			case "Description":
+				// Descriptions are accepted but not recorded.
 			default:
 				fatalf(400, "UnknownParameter", "unknown parameter %q", name)
 			}
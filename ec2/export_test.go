@@ -15,3 +15,13 @@ func FakeTime(fakeIt bool) {
 		timeNow = time.Now
 	}
 }
+
+// SetSleep replaces the function used to wait between retries, or
+// restores time.Sleep if f is nil.
+func SetSleep(f func(time.Duration)) {
+	if f == nil {
+		sleep = time.Sleep
+	} else {
+		sleep = f
+	}
+}